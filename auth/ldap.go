@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+
+	"aide-devoir-forum/config"
+	"aide-devoir-forum/models"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPConn est le sous-ensemble de *ldap.Conn utilisé par le fournisseur LDAP.
+// Un annuaire de test (stand-in en mémoire) peut le remplacer via LDAPProvider.Dial.
+type LDAPConn interface {
+	Bind(username, password string) error
+	Search(request *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// LDAPProvider authentifie les élèves et professeurs auprès de l'annuaire de l'établissement :
+// recherche de l'entrée avec le compte de service, puis bind avec le mot de passe saisi.
+type LDAPProvider struct {
+	config config.LDAPConfig
+	Dial   func() (LDAPConn, error)
+}
+
+func NewLDAPProvider(cfg config.LDAPConfig) *LDAPProvider {
+	p := &LDAPProvider{config: cfg}
+	p.Dial = p.dial
+	return p
+}
+
+// Name implémente Provider
+func (p *LDAPProvider) Name() string {
+	return models.AuthProviderLDAP
+}
+
+// Authenticate implémente Provider
+func (p *LDAPProvider) Authenticate(username, password string) (*Identity, error) {
+	// Un bind sans mot de passe est un bind anonyme qui "réussit" (RFC 4513 §5.1.2)
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := p.Dial()
+	if err != nil {
+		return nil, fmt.Errorf("connexion à l'annuaire: %w", err)
+	}
+	defer conn.Close()
+
+	if err := p.serviceBind(conn); err != nil {
+		return nil, err
+	}
+
+	entry, err := p.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("bind utilisateur: %w", err)
+	}
+
+	groups := entry.GetAttributeValues("memberOf")
+	if p.config.GroupBaseDN != "" {
+		// L'utilisateur n'a pas forcément le droit de lire les groupes
+		if err := p.serviceBind(conn); err != nil {
+			return nil, err
+		}
+		if groups, err = p.findGroups(conn, entry.DN); err != nil {
+			return nil, err
+		}
+	}
+
+	identity := &Identity{
+		Provider:   models.AuthProviderLDAP,
+		ExternalID: entry.GetAttributeValue(p.config.IDAttribute),
+		Username:   entry.GetAttributeValue(p.config.UsernameAttribute),
+		Email:      entry.GetAttributeValue(p.config.EmailAttribute),
+		Groups:     groups,
+	}
+	if identity.ExternalID == "" {
+		identity.ExternalID = entry.DN
+	}
+	if identity.Username == "" {
+		identity.Username = username
+	}
+
+	return identity, nil
+}
+
+// dial ouvre une connexion à l'annuaire (ldap://, ldaps://, StartTLS optionnel)
+func (p *LDAPProvider) dial() (LDAPConn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.config.InsecureSkipVerify}
+	if u, err := url.Parse(p.config.URL); err == nil {
+		tlsConfig.ServerName = u.Hostname()
+	}
+
+	conn, err := ldap.DialURL(p.config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: p.config.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(p.config.Timeout)
+
+	if p.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// serviceBind s'authentifie avec le compte de service (recherche anonyme sinon)
+func (p *LDAPProvider) serviceBind(conn LDAPConn) error {
+	if p.config.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(p.config.BindDN, p.config.BindPassword); err != nil {
+		return fmt.Errorf("bind du compte de service: %w", err)
+	}
+	return nil
+}
+
+// findUser recherche l'entrée unique correspondant à l'identifiant saisi
+func (p *LDAPProvider) findUser(conn LDAPConn, username string) (*ldap.Entry, error) {
+	request := ldap.NewSearchRequest(
+		p.config.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(p.config.Timeout.Seconds()), false,
+		fmt.Sprintf(p.config.UserFilter, ldap.EscapeFilter(username)),
+		[]string{p.config.UsernameAttribute, p.config.EmailAttribute, p.config.IDAttribute, "memberOf"},
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) || ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("recherche de l'utilisateur: %w", err)
+	}
+
+	// Aucune entrée ou entrée ambiguë : on refuse
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return result.Entries[0], nil
+}
+
+// findGroups recherche les groupes dont l'utilisateur est membre
+func (p *LDAPProvider) findGroups(conn LDAPConn, userDN string) ([]string, error) {
+	request := ldap.NewSearchRequest(
+		p.config.GroupBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(p.config.Timeout.Seconds()), false,
+		fmt.Sprintf(p.config.GroupFilter, ldap.EscapeFilter(userDN)),
+		[]string{"cn"},
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		return nil, fmt.Errorf("recherche des groupes: %w", err)
+	}
+
+	groups := make([]string, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		groups = append(groups, entry.DN)
+	}
+	return groups, nil
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"aide-devoir-forum/config"
+	"aide-devoir-forum/models"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	testServiceDN = "cn=forum,ou=services,dc=lycee,dc=fr"
+	testUserDN    = "uid=sarah,ou=people,dc=lycee,dc=fr"
+	testGroupsDN  = "ou=groups,dc=lycee,dc=fr"
+)
+
+// fakeDirectory est un annuaire en mémoire substitué à LDAPProvider.Dial.
+// Les recherches ne sont autorisées qu'au compte de service, comme sur un annuaire
+// où les élèves ne peuvent pas lire les groupes.
+type fakeDirectory struct {
+	passwords map[string]string        // DN -> mot de passe
+	entries   map[string][]*ldap.Entry // Filtre de recherche -> entrées retournées
+	filters   []string                 // Filtres reçus, dans l'ordre
+	bound     string
+	dials     int
+	closed    int
+}
+
+func newFakeDirectory() *fakeDirectory {
+	return &fakeDirectory{
+		passwords: map[string]string{
+			testServiceDN: "service-secret",
+			testUserDN:    "bonjour123",
+		},
+		entries: map[string][]*ldap.Entry{
+			"(uid=sarah)": {ldap.NewEntry(testUserDN, map[string][]string{
+				"uid":      {"sarah"},
+				"mail":     {"sarah@lycee.fr"},
+				"memberOf": {"cn=eleves,ou=groups,dc=lycee,dc=fr"},
+			})},
+			"(member=" + testUserDN + ")": {
+				ldap.NewEntry("cn=eleves,ou=groups,dc=lycee,dc=fr", nil),
+				ldap.NewEntry("cn=Profs,ou=groups,dc=lycee,dc=fr", nil),
+			},
+		},
+	}
+}
+
+func (d *fakeDirectory) dial() (LDAPConn, error) {
+	d.dials++
+	d.bound = ""
+	return d, nil
+}
+
+func (d *fakeDirectory) Bind(username, password string) error {
+	if expected, ok := d.passwords[username]; !ok || password == "" || expected != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+	d.bound = username
+	return nil
+}
+
+func (d *fakeDirectory) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	d.filters = append(d.filters, request.Filter)
+	if d.bound != testServiceDN {
+		return nil, ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("insufficient access"))
+	}
+	return &ldap.SearchResult{Entries: d.entries[request.Filter]}, nil
+}
+
+func (d *fakeDirectory) Close() error {
+	d.closed++
+	return nil
+}
+
+func testLDAPConfig() config.LDAPConfig {
+	return config.LDAPConfig{
+		BindDN:            testServiceDN,
+		BindPassword:      "service-secret",
+		BaseDN:            "ou=people,dc=lycee,dc=fr",
+		UserFilter:        "(uid=%s)",
+		UsernameAttribute: "uid",
+		EmailAttribute:    "mail",
+		IDAttribute:       "uid",
+		GroupFilter:       "(member=%s)",
+		GroupRoles: map[string]int{
+			"profs": models.RoleProfessor,
+			"cn=vie-scolaire,ou=groups,dc=lycee,dc=fr": models.RoleModerator,
+		},
+	}
+}
+
+func newTestLDAPProvider(cfg config.LDAPConfig, directory *fakeDirectory) *LDAPProvider {
+	provider := NewLDAPProvider(cfg)
+	provider.Dial = directory.dial
+	return provider
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	directory := newFakeDirectory()
+	provider := newTestLDAPProvider(testLDAPConfig(), directory)
+
+	identity, err := provider.Authenticate("sarah", "bonjour123")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	expected := &Identity{
+		Provider:   models.AuthProviderLDAP,
+		ExternalID: "sarah",
+		Username:   "sarah",
+		Email:      "sarah@lycee.fr",
+		Groups:     []string{"cn=eleves,ou=groups,dc=lycee,dc=fr"},
+	}
+	if !reflect.DeepEqual(identity, expected) {
+		t.Errorf("identité = %+v, attendu %+v", identity, expected)
+	}
+	if directory.closed != directory.dials {
+		t.Errorf("%d connexion(s) ouverte(s), %d fermée(s)", directory.dials, directory.closed)
+	}
+}
+
+func TestLDAPAuthenticateBindFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+	}{
+		{"mauvais mot de passe", "sarah", "faux"},
+		{"mot de passe vide (bind anonyme)", "sarah", ""},
+		{"utilisateur inconnu", "inconnu", "bonjour123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestLDAPProvider(testLDAPConfig(), newFakeDirectory())
+			if _, err := provider.Authenticate(tt.username, tt.password); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("erreur = %v, attendu ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticateServiceBindFailure(t *testing.T) {
+	cfg := testLDAPConfig()
+	cfg.BindPassword = "expiré"
+	provider := newTestLDAPProvider(cfg, newFakeDirectory())
+
+	// Une panne du compte de service n'est pas un refus d'identifiants : la chaîne doit le signaler
+	_, err := provider.Authenticate("sarah", "bonjour123")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("erreur = %v, attendu une erreur de bind du compte de service", err)
+	}
+}
+
+func TestLDAPUserSearchEscapesFilter(t *testing.T) {
+	directory := newFakeDirectory()
+	provider := newTestLDAPProvider(testLDAPConfig(), directory)
+
+	// Sans échappement, "*)(uid=*" transformerait la recherche en (uid=*)(uid=*)
+	if _, err := provider.Authenticate("*)(uid=*", "bonjour123"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("erreur = %v, attendu ErrInvalidCredentials", err)
+	}
+
+	expected := []string{`(uid=\2a\29\28uid=\2a)`}
+	if !reflect.DeepEqual(directory.filters, expected) {
+		t.Errorf("filtres = %q, attendu %q", directory.filters, expected)
+	}
+}
+
+func TestLDAPUserSearchAmbiguous(t *testing.T) {
+	directory := newFakeDirectory()
+	directory.entries["(uid=sarah)"] = append(directory.entries["(uid=sarah)"],
+		ldap.NewEntry("uid=sarah,ou=anciens,dc=lycee,dc=fr", nil))
+	provider := newTestLDAPProvider(testLDAPConfig(), directory)
+
+	if _, err := provider.Authenticate("sarah", "bonjour123"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("erreur = %v, attendu ErrInvalidCredentials", err)
+	}
+}
+
+func TestLDAPGroupLookup(t *testing.T) {
+	directory := newFakeDirectory()
+	cfg := testLDAPConfig()
+	cfg.GroupBaseDN = testGroupsDN
+	provider := newTestLDAPProvider(cfg, directory)
+
+	identity, err := provider.Authenticate("sarah", "bonjour123")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	// La recherche des groupes se fait avec le compte de service, DN de l'utilisateur échappé
+	expectedFilters := []string{"(uid=sarah)", `(member=uid=sarah,ou=people,dc=lycee,dc=fr)`}
+	if !reflect.DeepEqual(directory.filters, expectedFilters) {
+		t.Errorf("filtres = %q, attendu %q", directory.filters, expectedFilters)
+	}
+	expectedGroups := []string{"cn=eleves,ou=groups,dc=lycee,dc=fr", "cn=Profs,ou=groups,dc=lycee,dc=fr"}
+	if !reflect.DeepEqual(identity.Groups, expectedGroups) {
+		t.Errorf("groupes = %q, attendu %q", identity.Groups, expectedGroups)
+	}
+
+	provisioner := NewProvisioner(nil, cfg)
+	if role := provisioner.RoleForGroups(identity.Groups); role != models.RoleProfessor {
+		t.Errorf("rôle = %d, attendu %d (professeur)", role, models.RoleProfessor)
+	}
+}
+
+func TestRoleForGroups(t *testing.T) {
+	provisioner := NewProvisioner(nil, testLDAPConfig())
+
+	tests := []struct {
+		name   string
+		groups []string
+		role   int
+	}{
+		{"aucun groupe", nil, models.RoleUser},
+		{"groupe non configuré", []string{"cn=eleves,ou=groups,dc=lycee,dc=fr"}, models.RoleUser},
+		{"CN, insensible à la casse", []string{"cn=PROFS,ou=groups,dc=lycee,dc=fr"}, models.RoleProfessor},
+		{"DN complet", []string{"CN=Vie-Scolaire,OU=groups,DC=lycee,DC=fr"}, models.RoleModerator},
+		{"rôle le plus élevé", []string{"cn=vie-scolaire,ou=groups,dc=lycee,dc=fr", "cn=profs,ou=groups,dc=lycee,dc=fr"}, models.RoleModerator},
+		{"DN invalide reconnu tel quel", []string{"profs"}, models.RoleProfessor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if role := provisioner.RoleForGroups(tt.groups); role != tt.role {
+				t.Errorf("rôle = %d, attendu %d", role, tt.role)
+			}
+		})
+	}
+}
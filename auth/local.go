@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// LocalUserStore est l'accès aux comptes nécessaire au fournisseur local
+type LocalUserStore interface {
+	GetUserByUsername(username string) (*models.User, error)
+}
+
+// LocalProvider vérifie le mot de passe (bcrypt) des comptes créés sur le forum
+type LocalProvider struct {
+	store LocalUserStore
+}
+
+func NewLocalProvider(store LocalUserStore) *LocalProvider {
+	return &LocalProvider{store: store}
+}
+
+// Name implémente Provider
+func (p *LocalProvider) Name() string {
+	return models.AuthProviderLocal
+}
+
+// Authenticate implémente Provider
+func (p *LocalProvider) Authenticate(username, password string) (*Identity, error) {
+	user, err := p.store.GetUserByUsername(username)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Les comptes externes n'ont pas de mot de passe local
+	if user.AuthProvider != models.AuthProviderLocal || !utils.CheckPasswordHash(password, user.Password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Identity{
+		Provider:   models.AuthProviderLocal,
+		ExternalID: user.Username,
+		Username:   user.Username,
+		Email:      user.Email,
+		User:       user,
+	}, nil
+}
@@ -0,0 +1,85 @@
+// Package auth regroupe les fournisseurs d'identité utilisés à la connexion
+// (comptes locaux, annuaire LDAP) et la création automatique des comptes externes.
+package auth
+
+import (
+	"errors"
+	"log"
+
+	"aide-devoir-forum/config"
+	"aide-devoir-forum/models"
+)
+
+// Erreurs retournées par les fournisseurs d'identité
+var (
+	// ErrInvalidCredentials : identifiants refusés, le fournisseur suivant peut être essayé
+	ErrInvalidCredentials = errors.New("identifiants invalides")
+	// ErrAccountConflict : l'identité externe correspond à un compte local non rattaché
+	ErrAccountConflict = errors.New("un compte du forum utilise déjà cet email")
+)
+
+// Identity représente une identité vérifiée par un fournisseur
+type Identity struct {
+	Provider   string   // models.AuthProviderLocal, models.AuthProviderLDAP...
+	ExternalID string   // Identifiant stable chez le fournisseur
+	Username   string   // Identifiant de connexion chez le fournisseur
+	Email      string   // Peut être vide
+	Groups     []string // DN des groupes (fournisseurs externes)
+	User       *models.User
+}
+
+// Provider vérifie des identifiants. Un fournisseur local renseigne Identity.User ;
+// un fournisseur externe laisse le rattachement au compte du forum au Provisioner.
+type Provider interface {
+	Name() string
+	Authenticate(username, password string) (*Identity, error)
+}
+
+// Chain essaie les fournisseurs dans l'ordre et retourne la première identité acceptée.
+// Un fournisseur indisponible (annuaire injoignable...) n'empêche pas d'essayer les suivants,
+// ce qui garde les comptes locaux (administrateurs) utilisables en cas de panne.
+type Chain []Provider
+
+// Authenticate implémente Provider
+func (c Chain) Authenticate(username, password string) (*Identity, error) {
+	for _, provider := range c {
+		identity, err := provider.Authenticate(username, password)
+		if err == nil {
+			return identity, nil
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			log.Printf("⚠️  Fournisseur d'identité %s: %v", provider.Name(), err)
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// Name implémente Provider
+func (c Chain) Name() string {
+	return "chain"
+}
+
+// NewProvider construit la chaîne de fournisseurs configurée (AUTH_PROVIDERS)
+func NewProvider(cfg config.AuthConfig, store LocalUserStore) Provider {
+	var chain Chain
+	for _, name := range cfg.Providers {
+		switch name {
+		case models.AuthProviderLocal:
+			chain = append(chain, NewLocalProvider(store))
+		case models.AuthProviderLDAP:
+			if cfg.LDAP.URL == "" || cfg.LDAP.BaseDN == "" {
+				log.Printf("⚠️  Fournisseur LDAP ignoré: LDAP_URL et LDAP_BASE_DN sont requis")
+				continue
+			}
+			chain = append(chain, NewLDAPProvider(cfg.LDAP))
+		case "":
+		default:
+			log.Printf("⚠️  Fournisseur d'identité inconnu ignoré: %s", name)
+		}
+	}
+
+	if len(chain) == 0 {
+		chain = append(chain, NewLocalProvider(store))
+	}
+	return chain
+}
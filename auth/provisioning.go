@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"aide-devoir-forum/config"
+	"aide-devoir-forum/models"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ProvisioningStore est l'accès aux comptes nécessaire au rattachement des identités externes
+type ProvisioningStore interface {
+	GetUserByUsername(username string) (*models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
+	GetUserByExternalID(provider, externalID string) (*models.User, error)
+	CreateExternalUser(username, email, provider, externalID string, roleID int) (int64, error)
+	LinkExternalIdentity(userID int, provider, externalID string) error
+	PromoteUser(userID, newRoleID int) error
+}
+
+// Provisioner rattache une identité externe à un compte du forum,
+// en créant le compte au premier login et en appliquant la correspondance groupes -> rôles.
+type Provisioner struct {
+	store  ProvisioningStore
+	config config.LDAPConfig
+}
+
+func NewProvisioner(store ProvisioningStore, cfg config.LDAPConfig) *Provisioner {
+	return &Provisioner{store: store, config: cfg}
+}
+
+// ResolveUser retourne le compte du forum correspondant à une identité vérifiée
+func (p *Provisioner) ResolveUser(identity *Identity) (*models.User, error) {
+	// Compte local : déjà résolu par le fournisseur
+	if identity.User != nil {
+		return identity.User, nil
+	}
+
+	role := p.RoleForGroups(identity.Groups)
+
+	user, err := p.store.GetUserByExternalID(identity.Provider, identity.ExternalID)
+	if err == nil {
+		return p.syncRole(user, identity, role)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	email := identity.Email
+	if email == "" {
+		email = normalizeUsername(identity.ExternalID) + "@" + p.config.EmailDomain
+	}
+
+	// Un compte local avec le même email n'est rattaché que si la configuration l'autorise :
+	// sinon, une entrée d'annuaire pourrait prendre le contrôle d'un compte existant.
+	existing, err := p.store.GetUserByEmail(email)
+	if err == nil {
+		if !p.config.LinkExisting || existing.AuthProvider != models.AuthProviderLocal {
+			return nil, ErrAccountConflict
+		}
+		if err := p.store.LinkExternalIdentity(existing.ID, identity.Provider, identity.ExternalID); err != nil {
+			return nil, err
+		}
+		log.Printf("🔗 Compte %s rattaché à l'identité %s %s", existing.Username, identity.Provider, identity.ExternalID)
+		return p.syncRole(existing, identity, role)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	username, err := p.availableUsername(identity.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.store.CreateExternalUser(username, email, identity.Provider, identity.ExternalID, role); err != nil {
+		return nil, err
+	}
+	log.Printf("👤 Compte %s créé depuis l'identité %s %s", username, identity.Provider, identity.ExternalID)
+
+	return p.store.GetUserByExternalID(identity.Provider, identity.ExternalID)
+}
+
+// RoleForGroups retourne le rôle le plus élevé accordé par les groupes de l'utilisateur.
+// Un groupe est reconnu par son DN complet ou par son CN.
+func (p *Provisioner) RoleForGroups(groups []string) int {
+	role := models.RoleUser
+	for _, group := range groups {
+		for _, name := range groupNames(group) {
+			if mapped, ok := p.config.GroupRoles[name]; ok && mapped > role {
+				role = mapped
+			}
+		}
+	}
+	return role
+}
+
+// syncRole applique la correspondance groupes -> rôles à un compte existant. Par défaut le
+// rôle n'est que relevé : une promotion locale (modérateur...) n'est pas annulée à la connexion.
+// Avec SyncRoles, le rôle de l'annuaire s'impose, y compris à la baisse.
+func (p *Provisioner) syncRole(user *models.User, identity *Identity, role int) (*models.User, error) {
+	if user.RoleID == role || (!p.config.SyncRoles && role < user.RoleID) {
+		return user, nil
+	}
+
+	if err := p.store.PromoteUser(user.ID, role); err != nil {
+		return nil, err
+	}
+	log.Printf("🎓 Rôle de %s synchronisé depuis l'annuaire: %d -> %d", user.Username, user.RoleID, role)
+
+	return p.store.GetUserByExternalID(identity.Provider, identity.ExternalID)
+}
+
+// availableUsername choisit un nom d'utilisateur libre à partir de l'identifiant externe
+func (p *Provisioner) availableUsername(externalUsername string) (string, error) {
+	base := normalizeUsername(externalUsername)
+
+	candidate := base
+	for i := 2; i <= 100; i++ {
+		if _, err := p.store.GetUserByUsername(candidate); errors.Is(err, sql.ErrNoRows) {
+			return candidate, nil
+		} else if err != nil {
+			return "", err
+		}
+		candidate = base + "_" + strconv.Itoa(i)
+	}
+	return "", errors.New("aucun nom d'utilisateur disponible pour " + externalUsername)
+}
+
+// normalizeUsername adapte un identifiant externe aux règles des noms d'utilisateur
+// du forum (3-50 caractères : lettres, chiffres, _ et -)
+func normalizeUsername(value string) string {
+	var b strings.Builder
+	for _, char := range value {
+		if (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
+			(char >= '0' && char <= '9') || char == '_' || char == '-' {
+			b.WriteRune(char)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+
+	username := b.String()
+	if len(username) > 46 { // Place pour un suffixe "_100"
+		username = username[:46]
+	}
+	for len(username) < 3 {
+		username += "_"
+	}
+	return username
+}
+
+// groupNames retourne les noms sous lesquels un groupe peut être configuré (DN et CN, en minuscules)
+func groupNames(group string) []string {
+	names := []string{strings.ToLower(group)}
+
+	dn, err := ldap.ParseDN(group)
+	if err != nil || len(dn.RDNs) == 0 {
+		return names
+	}
+	for _, attr := range dn.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, "cn") {
+			names = append(names, strings.ToLower(attr.Value))
+		}
+	}
+	return names
+}
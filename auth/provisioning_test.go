@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"database/sql"
+	"testing"
+
+	"aide-devoir-forum/models"
+)
+
+// fakeStore est un ProvisioningStore en mémoire
+type fakeStore struct {
+	users     map[int]*models.User
+	externals map[string]int // Fournisseur + "/" + identifiant externe -> ID du compte
+}
+
+func (s *fakeStore) GetUserByUsername(username string) (*models.User, error) {
+	for _, user := range s.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *fakeStore) GetUserByEmail(email string) (*models.User, error) {
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *fakeStore) GetUserByExternalID(provider, externalID string) (*models.User, error) {
+	userID, ok := s.externals[provider+"/"+externalID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	user := *s.users[userID]
+	return &user, nil
+}
+
+func (s *fakeStore) CreateExternalUser(username, email, provider, externalID string, roleID int) (int64, error) {
+	id := len(s.users) + 1
+	s.users[id] = &models.User{ID: id, Username: username, Email: email, AuthProvider: provider, RoleID: roleID}
+	s.externals[provider+"/"+externalID] = id
+	return int64(id), nil
+}
+
+func (s *fakeStore) LinkExternalIdentity(userID int, provider, externalID string) error {
+	s.users[userID].AuthProvider = provider
+	s.externals[provider+"/"+externalID] = userID
+	return nil
+}
+
+func (s *fakeStore) PromoteUser(userID, newRoleID int) error {
+	s.users[userID].RoleID = newRoleID
+	return nil
+}
+
+func TestResolveUserRoleSync(t *testing.T) {
+	profs := []string{"cn=profs,ou=groups,dc=lycee,dc=fr"}
+
+	tests := []struct {
+		name      string
+		syncRoles bool
+		current   int
+		groups    []string
+		expected  int
+	}{
+		{"rôle relevé par l'annuaire", false, models.RoleUser, profs, models.RoleProfessor},
+		{"promotion locale conservée", false, models.RoleModerator, profs, models.RoleModerator},
+		{"promotion locale conservée sans groupe", false, models.RoleModerator, nil, models.RoleModerator},
+		{"rôle de l'annuaire imposé (LDAP_SYNC_ROLES)", true, models.RoleModerator, profs, models.RoleProfessor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{
+				users:     map[int]*models.User{1: {ID: 1, Username: "sarah", AuthProvider: models.AuthProviderLDAP, RoleID: tt.current}},
+				externals: map[string]int{models.AuthProviderLDAP + "/sarah": 1},
+			}
+			cfg := testLDAPConfig()
+			cfg.SyncRoles = tt.syncRoles
+
+			user, err := NewProvisioner(store, cfg).ResolveUser(&Identity{
+				Provider:   models.AuthProviderLDAP,
+				ExternalID: "sarah",
+				Username:   "sarah",
+				Groups:     tt.groups,
+			})
+			if err != nil {
+				t.Fatalf("ResolveUser: %v", err)
+			}
+			if user.RoleID != tt.expected {
+				t.Errorf("rôle = %d, attendu %d", user.RoleID, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveUserFirstLogin(t *testing.T) {
+	store := &fakeStore{
+		users:     map[int]*models.User{1: {ID: 1, Username: "sarah", Email: "autre@lycee.fr", AuthProvider: models.AuthProviderLocal, RoleID: models.RoleUser}},
+		externals: map[string]int{},
+	}
+
+	user, err := NewProvisioner(store, testLDAPConfig()).ResolveUser(&Identity{
+		Provider:   models.AuthProviderLDAP,
+		ExternalID: "sarah",
+		Username:   "sarah",
+		Email:      "sarah@lycee.fr",
+		Groups:     []string{"cn=profs,ou=groups,dc=lycee,dc=fr"},
+	})
+	if err != nil {
+		t.Fatalf("ResolveUser: %v", err)
+	}
+	if user.Username != "sarah_2" || user.RoleID != models.RoleProfessor {
+		t.Errorf("compte = %s (rôle %d), attendu sarah_2 (rôle %d)", user.Username, user.RoleID, models.RoleProfessor)
+	}
+}
@@ -4,17 +4,26 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"aide-devoir-forum/models"
+
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Security SecurityConfig
-	Uploads  UploadsConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	JWT          JWTConfig
+	Security     SecurityConfig
+	Uploads      UploadsConfig
+	OAuth        OAuthConfig
+	Auth         AuthConfig
+	Registration RegistrationConfig
+	Reputation   models.ReputationRules
+	Bounty       models.BountyRules
+	Mail         MailConfig
 }
 
 type ServerConfig struct {
@@ -22,6 +31,8 @@ type ServerConfig struct {
 	Host         string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	TLSCertFile  string
+	TLSKeyFile   string
 }
 
 type DatabaseConfig struct {
@@ -41,6 +52,79 @@ type JWTConfig struct {
 type SecurityConfig struct {
 	BCryptCost int
 	RateLimit  int
+	Headers    HeadersConfig
+}
+
+// HeadersConfig regroupe les en-têtes de sécurité HTTP envoyés par le serveur.
+// Dans les politiques CSP, le marqueur {nonce} est remplacé à chaque requête
+// par 'nonce-<valeur>' (voir middleware.SecurityHeaders).
+type HeadersConfig struct {
+	Enabled        bool
+	CSP            string            // Politique appliquée
+	CSPReportOnly  string            // Politique en mode rapport uniquement
+	CSPRoutes      map[string]string // Politique appliquée par préfixe de chemin
+	ReferrerPolicy string
+	HSTSMaxAge     int // En secondes, envoyé uniquement en HTTPS
+	HSTSSubdomains bool
+}
+
+// OAuthConfig configure le fournisseur OAuth2 / OpenID Connect intégré
+type OAuthConfig struct {
+	Issuer         string // URL publique du forum, utilisée comme "iss" des tokens
+	SigningKeyFile string // Clé privée RSA (PEM) signant les tokens, générée si absente
+	CodeTTL        time.Duration
+	AccessTokenTTL time.Duration
+}
+
+// AuthConfig configure les fournisseurs d'identité utilisés à la connexion
+type AuthConfig struct {
+	Providers []string // Essayés dans l'ordre : "ldap", "local"
+	LDAP      LDAPConfig
+}
+
+// LDAPConfig configure l'authentification par l'annuaire LDAP de l'établissement.
+// Les filtres contiennent un %s remplacé par la valeur échappée (identifiant ou DN).
+type LDAPConfig struct {
+	URL                string // ldap://annuaire:389 ou ldaps://annuaire:636
+	StartTLS           bool
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+	BindDN             string // Compte de service pour la recherche (vide = anonyme)
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string
+	UsernameAttribute  string
+	EmailAttribute     string
+	IDAttribute        string // Attribut stable identifiant la personne
+	GroupBaseDN        string // Vide = groupes lus dans l'attribut memberOf
+	GroupFilter        string
+	GroupRoles         map[string]int // Groupe (DN ou CN) -> rôle du forum
+	SyncRoles          bool           // Imposer le rôle de l'annuaire à chaque connexion, même à la baisse
+	LinkExisting       bool           // Rattacher un compte local de même email au premier login
+	EmailDomain        string         // Domaine des emails générés si l'annuaire n'en fournit pas
+}
+
+// Modes d'inscription
+const (
+	RegistrationOpen       = "open"        // Inscription libre, code d'invitation facultatif
+	RegistrationInviteOnly = "invite-only" // Code d'invitation obligatoire
+	RegistrationClosed     = "closed"      // Aucune inscription (ex: comptes gérés par l'annuaire LDAP)
+)
+
+// RegistrationConfig configure l'inscription de nouveaux comptes
+type RegistrationConfig struct {
+	Mode string
+}
+
+// MailConfig configure l'envoi des emails (résumé des abonnements) par SMTP.
+// Sans serveur SMTP, les abonnements ne sont notifiés que sur le site.
+type MailConfig struct {
+	SMTPHost       string
+	SMTPPort       string
+	Username       string // Vide = pas d'authentification
+	Password       string
+	From           string
+	DigestInterval time.Duration // Délai minimum entre deux résumés envoyés à un utilisateur
 }
 
 type UploadsConfig struct {
@@ -55,12 +139,14 @@ func Load() *Config {
 		log.Printf("Aucun fichier .env trouvé, utilisation des variables d'environnement système")
 	}
 
-	return &Config{
+	cfg := &Config{
 		Server: ServerConfig{
 			Port:         getEnv("SERVER_PORT", "8080"),
 			Host:         getEnv("SERVER_HOST", "localhost"),
 			ReadTimeout:  15 * time.Second,
 			WriteTimeout: 15 * time.Second,
+			TLSCertFile:  getEnv("TLS_CERT_FILE", ""),
+			TLSKeyFile:   getEnv("TLS_KEY_FILE", ""),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -77,13 +163,124 @@ func Load() *Config {
 		Security: SecurityConfig{
 			BCryptCost: getEnvAsInt("BCRYPT_COST", 12),
 			RateLimit:  getEnvAsInt("RATE_LIMIT", 100),
+			Headers: HeadersConfig{
+				Enabled:       getEnvAsBool("SECURITY_HEADERS", true),
+				CSP:           getEnv("SECURITY_CSP", defaultCSP),
+				CSPReportOnly: getEnv("SECURITY_CSP_REPORT_ONLY", defaultCSPReportOnly),
+				CSPRoutes: map[string]string{
+					"/uploads/": getEnv("SECURITY_CSP_UPLOADS", uploadsCSP),
+				},
+				ReferrerPolicy: getEnv("SECURITY_REFERRER_POLICY", "strict-origin-when-cross-origin"),
+				HSTSMaxAge:     getEnvAsInt("SECURITY_HSTS_MAX_AGE", 31536000),
+				HSTSSubdomains: getEnvAsBool("SECURITY_HSTS_SUBDOMAINS", false),
+			},
 		},
 		Uploads: UploadsConfig{
 			MaxFileSize: getEnvAsInt64("MAX_FILE_SIZE", 10485760), // 10MB par défaut
 			PostsDir:    getEnv("UPLOADS_POSTS_DIR", "uploads/posts"),
 			AvatarsDir:  getEnv("UPLOADS_AVATARS_DIR", "uploads/avatars"),
 		},
+		OAuth: OAuthConfig{
+			Issuer:         getEnv("OAUTH_ISSUER", ""),
+			SigningKeyFile: getEnv("OAUTH_SIGNING_KEY_FILE", "oauth-signing-key.pem"),
+			CodeTTL:        time.Duration(getEnvAsInt("OAUTH_CODE_TTL", 60)) * time.Second,
+			AccessTokenTTL: time.Duration(getEnvAsInt("OAUTH_ACCESS_TOKEN_TTL", 3600)) * time.Second,
+		},
+	}
+
+	cfg.Auth = AuthConfig{
+		Providers: strings.Split(strings.ReplaceAll(getEnv("AUTH_PROVIDERS", "local"), " ", ""), ","),
+		LDAP: LDAPConfig{
+			URL:                getEnv("LDAP_URL", ""),
+			StartTLS:           getEnvAsBool("LDAP_STARTTLS", false),
+			InsecureSkipVerify: getEnvAsBool("LDAP_INSECURE_SKIP_VERIFY", false),
+			Timeout:            time.Duration(getEnvAsInt("LDAP_TIMEOUT", 5)) * time.Second,
+			BindDN:             getEnv("LDAP_BIND_DN", ""),
+			BindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+			BaseDN:             getEnv("LDAP_BASE_DN", ""),
+			UserFilter:         getEnv("LDAP_USER_FILTER", "(uid=%s)"),
+			UsernameAttribute:  getEnv("LDAP_USERNAME_ATTRIBUTE", "uid"),
+			EmailAttribute:     getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+			IDAttribute:        getEnv("LDAP_ID_ATTRIBUTE", "uid"),
+			GroupBaseDN:        getEnv("LDAP_GROUP_BASE_DN", ""),
+			GroupFilter:        getEnv("LDAP_GROUP_FILTER", "(member=%s)"),
+			GroupRoles:         parseGroupRoles(getEnv("LDAP_GROUP_ROLES", "")),
+			SyncRoles:          getEnvAsBool("LDAP_SYNC_ROLES", false),
+			LinkExisting:       getEnvAsBool("LDAP_LINK_EXISTING", false),
+			EmailDomain:        getEnv("LDAP_EMAIL_DOMAIN", "ldap.invalid"),
+		},
+	}
+
+	cfg.Registration = RegistrationConfig{
+		Mode: getEnv("REGISTRATION_MODE", RegistrationOpen),
 	}
+	switch cfg.Registration.Mode {
+	case RegistrationOpen, RegistrationInviteOnly, RegistrationClosed:
+	default:
+		log.Printf("⚠️  REGISTRATION_MODE inconnu %q, inscription sur invitation uniquement", cfg.Registration.Mode)
+		cfg.Registration.Mode = RegistrationInviteOnly
+	}
+
+	cfg.Reputation = models.ReputationRules{
+		PostLike:          getEnvAsInt("REPUTATION_POST_LIKE", 5),
+		CommentLike:       getEnvAsInt("REPUTATION_COMMENT_LIKE", 10),
+		DislikeReceived:   getEnvAsInt("REPUTATION_DISLIKE", -2),
+		SolutionAccepted:  getEnvAsInt("REPUTATION_SOLUTION", 15),
+		AnswerVerified:    getEnvAsInt("REPUTATION_VERIFIED", 20),
+		ModerationPenalty: getEnvAsInt("REPUTATION_MODERATION_PENALTY", -50),
+		DailyCap:          getEnvAsInt("REPUTATION_DAILY_CAP", 200),
+	}
+
+	cfg.Bounty = models.BountyRules{
+		MinAmount:    getEnvAsInt("BOUNTY_MIN_AMOUNT", 50),
+		MaxAmount:    getEnvAsInt("BOUNTY_MAX_AMOUNT", 500),
+		DurationDays: getEnvAsInt("BOUNTY_DURATION_DAYS", 7),
+	}
+
+	cfg.Mail = MailConfig{
+		SMTPHost:       getEnv("SMTP_HOST", ""),
+		SMTPPort:       getEnv("SMTP_PORT", "587"),
+		Username:       getEnv("SMTP_USERNAME", ""),
+		Password:       getEnv("SMTP_PASSWORD", ""),
+		From:           getEnv("SMTP_FROM", "forum@localhost"),
+		DigestInterval: time.Duration(getEnvAsInt("DIGEST_INTERVAL_HOURS", 24)) * time.Hour,
+	}
+
+	// Par défaut, l'émetteur OAuth est l'adresse locale du serveur
+	if cfg.OAuth.Issuer == "" {
+		scheme := "http"
+		if cfg.TLSEnabled() {
+			scheme = "https"
+		}
+		cfg.OAuth.Issuer = scheme + "://" + cfg.Server.Host + ":" + cfg.Server.Port
+	}
+	cfg.OAuth.Issuer = strings.TrimRight(cfg.OAuth.Issuer, "/")
+
+	return cfg
+}
+
+// Politiques CSP par défaut. Seuls les scripts portant le nonce de la requête
+// s'exécutent : les templates n'utilisent aucun gestionnaire onclick="...".
+const (
+	defaultCSP = "default-src 'self'; " +
+		"script-src 'self' {nonce}; " +
+		"img-src 'self' data:; " +
+		"style-src 'self' 'unsafe-inline' https://cdnjs.cloudflare.com; " +
+		"font-src 'self' https://cdnjs.cloudflare.com; " +
+		"connect-src 'self'; " +
+		"object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+	defaultCSPReportOnly = ""
+	uploadsCSP           = "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'; sandbox; frame-ancestors 'self'"
+)
+
+// MailEnabled indique si un serveur SMTP est configuré pour l'envoi des emails
+func (c *Config) MailEnabled() bool {
+	return c.Mail.SMTPHost != ""
+}
+
+// TLSEnabled indique si le serveur doit écouter en HTTPS
+func (c *Config) TLSEnabled() bool {
+	return c.Server.TLSCertFile != "" && c.Server.TLSKeyFile != ""
 }
 
 func (c *Config) GetDSN() string {
@@ -119,3 +316,52 @@ func getEnvAsInt64(key string, defaultValue int64) int64 {
 	}
 	return defaultValue
 }
+
+// parseGroupRoles lit la correspondance groupes -> rôles, au format
+// "groupe=rôle;groupe=rôle" (ex: "profs=professor;cn=admins,ou=groups,dc=lycee,dc=fr=administrator").
+// Le rôle est le dernier segment après "=", sous forme de nom ou de numéro.
+func parseGroupRoles(value string) map[string]int {
+	roles := map[string]int{}
+	for _, entry := range strings.Split(value, ";") {
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			continue
+		}
+		group := strings.ToLower(strings.TrimSpace(entry[:i]))
+		roleName := strings.ToLower(strings.TrimSpace(entry[i+1:]))
+
+		role, ok := roleNames[roleName]
+		if !ok {
+			if n, err := strconv.Atoi(roleName); err == nil && n >= models.RoleUser && n <= models.RoleAdministrator {
+				role, ok = n, true
+			}
+		}
+		if !ok {
+			log.Printf("⚠️  LDAP_GROUP_ROLES: rôle inconnu %q ignoré", roleName)
+			continue
+		}
+		roles[group] = role
+	}
+	return roles
+}
+
+// roleNames associe les noms de rôles acceptés dans la configuration
+var roleNames = map[string]int{
+	"user":          models.RoleUser,
+	"student":       models.RoleUser,
+	"professor":     models.RoleProfessor,
+	"teacher":       models.RoleProfessor,
+	"moderator":     models.RoleModerator,
+	"administrator": models.RoleAdministrator,
+	"admin":         models.RoleAdministrator,
+}
+
+// getEnvAsBool récupère une variable d'environnement en tant que booléen
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"database/sql"
+
+	"aide-devoir-forum/models"
+)
+
+// === PUBLICATION ANONYME ===
+
+// Colonnes de l'auteur d'un post (alias "p", "u" et "r") pour les listes : un post publié
+// anonymement n'y expose ni l'identifiant, ni le nom, ni le rôle, ni l'avatar de son auteur
+const (
+	postAuthorIDSQL   = "IF(p.is_anonymous, 0, p.user_id)"
+	postAuthorNameSQL = "IF(p.is_anonymous, '" + models.AnonymousName + "', u.username)"
+	postAuthorSQL     = postAuthorIDSQL + ", " + postAuthorNameSQL + ", IF(p.is_anonymous, '', r.name) as role_name, IF(p.is_anonymous, FALSE, u.is_banned)"
+	postAvatarSQL     = "IF(p.is_anonymous, NULL, u.avatar_filename)"
+)
+
+// signedContentSQL exclut (contenus sous l'alias donné) les posts et réponses anonymes
+// des pages d'un utilisateur, sauf quand il les consulte lui-même
+func signedContentSQL(alias string, userID int, viewer *models.User) string {
+	if viewer != nil && viewer.ID == userID {
+		return "1=1"
+	}
+	return "NOT " + alias + ".is_anonymous"
+}
+
+// GetAnonymousAuthor retourne le nom de l'auteur réel d'un post ou d'une réponse
+// ("post" ou "comment") publié anonymement, et le post concerné.
+// Retourne sql.ErrNoRows si le contenu n'existe pas ou n'est pas anonyme.
+func (r *Repository) GetAnonymousAuthor(targetType string, targetID int) (postID int, username string, err error) {
+	var query string
+	switch targetType {
+	case "post":
+		query = `
+			SELECT p.id, u.username FROM posts p
+			JOIN users u ON p.user_id = u.id
+			WHERE p.id = ? AND p.is_anonymous`
+	case "comment":
+		query = `
+			SELECT c.post_id, u.username FROM comments c
+			JOIN users u ON c.user_id = u.id
+			WHERE c.id = ? AND c.is_anonymous`
+	default:
+		return 0, "", sql.ErrNoRows
+	}
+	err = r.db.QueryRow(query, targetID).Scan(&postID, &username)
+	return postID, username, err
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"aide-devoir-forum/models"
+)
+
+// === VALIDATION DES RÉPONSES PAR LES PROFESSEURS ===
+
+// RoleHasPermission vérifie qu'un rôle dispose d'une permission (colonne JSON roles.permissions)
+func (r *Repository) RoleHasPermission(roleID int, permission string) bool {
+	var raw sql.NullString
+	if err := r.db.QueryRow("SELECT permissions FROM roles WHERE id = ?", roleID).Scan(&raw); err != nil || !raw.Valid {
+		return false
+	}
+
+	var permissions []string
+	if err := json.Unmarshal([]byte(raw.String), &permissions); err != nil {
+		return false
+	}
+	for _, p := range permissions {
+		if p == permission || p == models.PermissionAll {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCommentVerified valide (ou retire la validation d')une réponse et met à jour la réputation
+// de son auteur. Retourne sql.ErrNoRows si la réponse était déjà dans cet état.
+func (r *Repository) SetCommentVerified(commentID, verifierID int, verified bool) error {
+	query := "UPDATE comments SET verified_by = ?, verified_at = NOW() WHERE id = ? AND verified_by IS NULL"
+	args := []interface{}{verifierID, commentID}
+	if !verified {
+		query = "UPDATE comments SET verified_by = NULL, verified_at = NULL WHERE id = ? AND verified_by IS NOT NULL"
+		args = []interface{}{commentID}
+	}
+
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if !verified {
+		return r.reverseReputation(models.ReputationAnswerVerified, "comment", commentID, 0)
+	}
+	var authorID int
+	if err := r.db.QueryRow("SELECT user_id FROM comments WHERE id = ?", commentID).Scan(&authorID); err != nil {
+		return err
+	}
+	if err := r.awardReputation(authorID, verifierID, models.ReputationAnswerVerified, "comment", commentID,
+		r.reputation.AnswerVerified); err != nil {
+		return err
+	}
+	return r.EvaluateBadges(authorID, models.BadgeMetricCategoriesHelped)
+}
+
+// GetPostsNeedingReview récupère les questions ouvertes, non résolues et sans réponse validée
+// par un professeur, dans les catégories visibles par l'utilisateur (les plus anciennes d'abord)
+func (r *Repository) GetPostsNeedingReview(limit int, viewer *models.User) ([]models.Post, error) {
+	visibility, args := visibleCategorySQL(viewer)
+	rows, err := r.db.Query(`
+		SELECT p.id, p.title, LEFT(p.content, 200) as content, `+postAuthorSQL+`, `+postAvatarSQL+`, p.is_anonymous,
+		       p.category_id, c.name as category_name, p.status, p.is_solved, p.is_pinned, p.is_locked,
+		       p.views_count, p.likes_count, p.dislikes_count, p.created_at
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		JOIN roles r ON u.role_id = r.id
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.status = 'open' AND p.is_solved = FALSE AND `+listedPostSQL+` AND `+visibility+`
+		  AND NOT EXISTS (SELECT 1 FROM comments cm WHERE cm.post_id = p.id AND cm.verified_by IS NOT NULL)
+		ORDER BY p.created_at ASC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		var post models.Post
+		var avatarFilename sql.NullString
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.UserRole, &post.UserBanned, &avatarFilename, &post.IsAnonymous,
+			&post.CategoryID, &post.CategoryName, &post.Status, &post.IsSolved, &post.IsPinned, &post.IsLocked,
+			&post.ViewsCount, &post.LikesCount, &post.DislikesCount, &post.CreatedAt)
+		if err != nil {
+			continue
+		}
+
+		if avatarFilename.Valid {
+			post.UserAvatarURL = "/uploads/avatars/" + avatarFilename.String
+		}
+
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// scanCommentVerification convertit les colonnes de validation d'un commentaire
+func scanCommentVerification(comment *models.Comment, verifiedBy sql.NullInt64, verifierName sql.NullString, verifiedAt sql.NullTime) {
+	comment.VerifiedByID = nullIntPtr(verifiedBy)
+	if comment.VerifiedByID == nil {
+		return
+	}
+	comment.VerifiedByName = verifierName.String
+	if verifiedAt.Valid {
+		at := verifiedAt.Time
+		comment.VerifiedAt = &at
+	}
+}
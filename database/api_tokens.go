@@ -0,0 +1,127 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"aide-devoir-forum/models"
+)
+
+// === TOKENS API ===
+
+// CreateAPIToken enregistre un nouveau token d'API (seul le hash est stocké)
+func (r *Repository) CreateAPIToken(userID int, name, prefix, hash string, scopes []string, expiresAt *time.Time) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO api_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, name, prefix, hash, strings.Join(scopes, ","), expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetAPITokenByHash récupère un token d'API à partir de son hash
+func (r *Repository) GetAPITokenByHash(hash string) (*models.APIToken, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, name, token_prefix, token_hash, scopes, expires_at,
+		       last_used_at, COALESCE(last_used_ip, ''), revoked_at, created_at
+		FROM api_tokens
+		WHERE token_hash = ?
+	`, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := scanAPITokens(rows)
+	if len(tokens) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &tokens[0], nil
+}
+
+// GetUserAPITokens récupère les tokens d'API d'un utilisateur (révoqués inclus)
+func (r *Repository) GetUserAPITokens(userID int) ([]models.APIToken, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, name, token_prefix, token_hash, scopes, expires_at,
+		       last_used_at, COALESCE(last_used_ip, ''), revoked_at, created_at
+		FROM api_tokens
+		WHERE user_id = ?
+		ORDER BY revoked_at IS NOT NULL, created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAPITokens(rows), nil
+}
+
+// CountActiveAPITokens compte les tokens d'API utilisables d'un utilisateur
+func (r *Repository) CountActiveAPITokens(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM api_tokens
+		WHERE user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`, userID).Scan(&count)
+	return count, err
+}
+
+// RevokeAPIToken révoque un token d'API appartenant à un utilisateur
+func (r *Repository) RevokeAPIToken(tokenID, userID int) error {
+	result, err := r.db.Exec(`
+		UPDATE api_tokens SET revoked_at = NOW()
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+	`, tokenID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// TouchAPIToken enregistre la dernière utilisation d'un token d'API
+func (r *Repository) TouchAPIToken(tokenID int, ip string) error {
+	_, err := r.db.Exec("UPDATE api_tokens SET last_used_at = NOW(), last_used_ip = ? WHERE id = ?", ip, tokenID)
+	return err
+}
+
+// scanAPITokens lit les lignes d'une requête sur api_tokens
+func scanAPITokens(rows *sql.Rows) []models.APIToken {
+	var tokens []models.APIToken
+	for rows.Next() {
+		var token models.APIToken
+		var scopes string
+		var expiresAt, lastUsedAt, revokedAt sql.NullTime
+		err := rows.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenPrefix, &token.TokenHash,
+			&scopes, &expiresAt, &lastUsedAt, &token.LastUsedIP, &revokedAt, &token.CreatedAt)
+		if err != nil {
+			continue
+		}
+
+		token.Scopes = []string{}
+		for _, scope := range strings.Split(scopes, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				token.Scopes = append(token.Scopes, scope)
+			}
+		}
+		if expiresAt.Valid {
+			token.ExpiresAt = &expiresAt.Time
+		}
+		if lastUsedAt.Valid {
+			token.LastUsedAt = &lastUsedAt.Time
+		}
+		if revokedAt.Valid {
+			token.RevokedAt = &revokedAt.Time
+		}
+
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
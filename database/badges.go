@@ -0,0 +1,207 @@
+package database
+
+import (
+	"fmt"
+
+	"aide-devoir-forum/models"
+)
+
+// === BADGES ===
+
+// recordActivity met à jour la série de jours d'activité consécutifs d'un utilisateur.
+// MySQL applique les affectations du SET dans l'ordre : longest_streak voit la nouvelle série.
+func (r *Repository) recordActivity(userID int) error {
+	if _, err := r.db.Exec("INSERT IGNORE INTO user_stats (user_id) VALUES (?)", userID); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`
+		UPDATE user_stats SET
+			current_streak = CASE
+				WHEN last_active_date = CURDATE() THEN current_streak
+				WHEN last_active_date = CURDATE() - INTERVAL 1 DAY THEN current_streak + 1
+				ELSE 1
+			END,
+			longest_streak = GREATEST(longest_streak, current_streak),
+			last_active_date = CURDATE()
+		WHERE user_id = ?
+	`, userID)
+	return err
+}
+
+// recordContribution enregistre l'activité du jour puis évalue les badges concernés
+func (r *Repository) recordContribution(userID int, metrics ...string) {
+	r.recordActivity(userID)
+	r.EvaluateBadges(userID, append(metrics, models.BadgeMetricStreak)...)
+}
+
+// EvaluateBadges attribue les badges dont la métrique vient d'évoluer. Seules les règles
+// des métriques touchées et pas encore obtenues sont évaluées ; l'utilisateur est notifié
+// de chaque nouveau badge.
+func (r *Repository) EvaluateBadges(userID int, metrics ...string) error {
+	earned, err := r.earnedBadgeCodes(userID)
+	if err != nil {
+		return err
+	}
+
+	var pending []models.BadgeDefinition
+	for _, badge := range models.BadgeDefinitions {
+		if !earned[badge.Code] && containsString(metrics, badge.Metric) {
+			pending = append(pending, badge)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	values := make(map[string]int)
+	for _, badge := range pending {
+		value, ok := values[badge.Metric]
+		if !ok {
+			if value, err = r.badgeMetricValue(userID, badge.Metric); err != nil {
+				return err
+			}
+			values[badge.Metric] = value
+		}
+		if value < badge.Threshold {
+			continue
+		}
+
+		result, err := r.db.Exec("INSERT IGNORE INTO user_badges (user_id, badge_code) VALUES (?, ?)", userID, badge.Code)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			r.CreateNotification(userID, models.NotificationBadgeAwarded,
+				fmt.Sprintf("Nouveau badge obtenu : %s", badge.Name), "/badges")
+		}
+	}
+	return nil
+}
+
+// badgeMetricValue calcule la valeur actuelle d'une métrique pour un utilisateur
+func (r *Repository) badgeMetricValue(userID int, metric string) (int, error) {
+	var value int
+	var err error
+	switch metric {
+	case models.BadgeMetricAnswers:
+		err = r.db.QueryRow("SELECT COUNT(*) FROM comments WHERE user_id = ?", userID).Scan(&value)
+	case models.BadgeMetricSolutions:
+		err = r.db.QueryRow("SELECT COUNT(*) FROM comments WHERE user_id = ? AND is_solution = TRUE", userID).Scan(&value)
+	case models.BadgeMetricLikes:
+		err = r.db.QueryRow(`
+			SELECT (SELECT COALESCE(SUM(likes_count), 0) FROM posts WHERE user_id = ?)
+			     + (SELECT COALESCE(SUM(likes_count), 0) FROM comments WHERE user_id = ?)
+		`, userID, userID).Scan(&value)
+	case models.BadgeMetricCategoriesHelped:
+		err = r.db.QueryRow(`
+			SELECT COUNT(DISTINCT p.category_id)
+			FROM comments cm
+			JOIN posts p ON cm.post_id = p.id
+			WHERE cm.user_id = ? AND p.user_id != cm.user_id
+			  AND (cm.is_solution = TRUE OR cm.verified_by IS NOT NULL)
+		`, userID).Scan(&value)
+	case models.BadgeMetricStreak:
+		err = r.db.QueryRow("SELECT COALESCE(MAX(longest_streak), 0) FROM user_stats WHERE user_id = ?", userID).Scan(&value)
+	default:
+		err = fmt.Errorf("métrique de badge inconnue: %s", metric)
+	}
+	return value, err
+}
+
+// earnedBadgeCodes retourne les codes des badges déjà obtenus par un utilisateur
+func (r *Repository) earnedBadgeCodes(userID int) (map[string]bool, error) {
+	rows, err := r.db.Query("SELECT badge_code FROM user_badges WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	earned := make(map[string]bool)
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			continue
+		}
+		earned[code] = true
+	}
+	return earned, nil
+}
+
+// GetUserBadges récupère les badges obtenus par un utilisateur, les plus récents d'abord
+func (r *Repository) GetUserBadges(userID int) ([]models.UserBadge, error) {
+	rows, err := r.db.Query(`
+		SELECT badge_code, awarded_at FROM user_badges
+		WHERE user_id = ?
+		ORDER BY awarded_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var badges []models.UserBadge
+	for rows.Next() {
+		var code string
+		badge := models.UserBadge{UserID: userID}
+		if err := rows.Scan(&code, &badge.AwardedAt); err != nil {
+			continue
+		}
+		// Ignorer les badges retirés des définitions
+		definition, ok := models.GetBadgeDefinition(code)
+		if !ok {
+			continue
+		}
+		badge.BadgeDefinition = definition
+		badges = append(badges, badge)
+	}
+	return badges, nil
+}
+
+// GetBadgeCatalog liste tous les badges avec leur nombre de détenteurs et, pour un
+// utilisateur connecté (viewerID > 0), la date à laquelle il les a obtenus
+func (r *Repository) GetBadgeCatalog(viewerID int) ([]models.BadgeCatalogEntry, error) {
+	rows, err := r.db.Query("SELECT badge_code, COUNT(*) FROM user_badges GROUP BY badge_code")
+	if err != nil {
+		return nil, err
+	}
+	holders := make(map[string]int)
+	for rows.Next() {
+		var code string
+		var count int
+		if err := rows.Scan(&code, &count); err != nil {
+			continue
+		}
+		holders[code] = count
+	}
+	rows.Close()
+
+	var owned []models.UserBadge
+	if viewerID > 0 {
+		if owned, err = r.GetUserBadges(viewerID); err != nil {
+			return nil, err
+		}
+	}
+
+	catalog := make([]models.BadgeCatalogEntry, 0, len(models.BadgeDefinitions))
+	for _, badge := range models.BadgeDefinitions {
+		entry := models.BadgeCatalogEntry{BadgeDefinition: badge, HoldersCount: holders[badge.Code]}
+		for _, userBadge := range owned {
+			if userBadge.Code == badge.Code {
+				awardedAt := userBadge.AwardedAt
+				entry.AwardedAt = &awardedAt
+				break
+			}
+		}
+		catalog = append(catalog, entry)
+	}
+	return catalog, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"aide-devoir-forum/models"
+)
+
+// === FAVORIS ===
+
+// AddBookmark enregistre une question (commentID 0) ou une réponse dans les favoris d'un
+// utilisateur (sans effet si elle y est déjà)
+func (r *Repository) AddBookmark(userID, postID, commentID int) error {
+	_, err := r.db.Exec(`
+		INSERT IGNORE INTO bookmarks (user_id, post_id, comment_id) VALUES (?, ?, ?)
+	`, userID, postID, commentID)
+	return err
+}
+
+// RemoveBookmark retire une question (commentID 0) ou une réponse des favoris d'un utilisateur
+func (r *Repository) RemoveBookmark(userID, postID, commentID int) error {
+	_, err := r.db.Exec(`
+		DELETE FROM bookmarks WHERE user_id = ? AND post_id = ? AND comment_id = ?
+	`, userID, postID, commentID)
+	return err
+}
+
+// IsBookmarked vérifie qu'un utilisateur a enregistré une question (commentID 0) ou une réponse
+func (r *Repository) IsBookmarked(userID, postID, commentID int) bool {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM bookmarks WHERE user_id = ? AND post_id = ? AND comment_id = ?
+	`, userID, postID, commentID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// GetBookmarkedComments retourne les réponses d'une question enregistrées par un utilisateur
+func (r *Repository) GetBookmarkedComments(userID, postID int) map[int]bool {
+	bookmarked := make(map[int]bool)
+	rows, err := r.db.Query(`
+		SELECT comment_id FROM bookmarks WHERE user_id = ? AND post_id = ? AND comment_id <> 0
+	`, userID, postID)
+	if err != nil {
+		return bookmarked
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var commentID int
+		if rows.Scan(&commentID) == nil {
+			bookmarked[commentID] = true
+		}
+	}
+	return bookmarked
+}
+
+// UpdateBookmark modifie la note et la collection (nil = aucune) d'un favori de l'utilisateur.
+// Retourne sql.ErrNoRows si le favori ou la collection n'appartient pas à l'utilisateur.
+func (r *Repository) UpdateBookmark(bookmarkID, userID int, note string, collectionID *int) error {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM bookmarks WHERE id = ? AND user_id = ?", bookmarkID, userID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+	if collectionID != nil {
+		if _, err := r.GetBookmarkCollection(*collectionID, userID); err != nil {
+			return err
+		}
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE bookmarks SET note = ?, collection_id = ? WHERE id = ? AND user_id = ?
+	`, note, collectionID, bookmarkID, userID)
+	return err
+}
+
+// GetBookmarks retourne les favoris d'un utilisateur, les plus récents d'abord. Les contenus
+// devenus inaccessibles (catégorie privée quittée, question dépubliée) sont ignorés ; l'auteur
+// d'un contenu anonyme n'est visible que s'il s'agit de l'utilisateur lui-même.
+func (r *Repository) GetBookmarks(user *models.User, filter models.BookmarkFilter) ([]models.Bookmark, error) {
+	conditions := []string{"b.user_id = ?", "(b.comment_id = 0 OR cm.id IS NOT NULL)", publishedPostSQL}
+	args := []interface{}{user.ID}
+
+	if filter.Query != "" {
+		conditions = append(conditions, "(p.title LIKE ? OR IF(b.comment_id = 0, p.content, cm.content) LIKE ? OR b.note LIKE ?)")
+		searchTerm := "%" + filter.Query + "%"
+		args = append(args, searchTerm, searchTerm, searchTerm)
+	}
+	if filter.CollectionID > 0 {
+		conditions = append(conditions, "b.collection_id = ?")
+		args = append(args, filter.CollectionID)
+	} else if filter.Unsorted {
+		conditions = append(conditions, "b.collection_id IS NULL")
+	}
+	switch filter.Kind {
+	case models.BookmarkKindPost:
+		conditions = append(conditions, "b.comment_id = 0")
+	case models.BookmarkKindComment:
+		conditions = append(conditions, "b.comment_id <> 0")
+	}
+	if filter.CategoryID > 0 {
+		conditions = append(conditions, "p.category_id = ?")
+		args = append(args, filter.CategoryID)
+	}
+
+	visibility, visibilityArgs := visibleCategorySQL(user)
+	conditions = append(conditions, visibility)
+	args = append(args, visibilityArgs...)
+	args = append(args, models.MaxBookmarksListed)
+
+	rows, err := r.db.Query(`
+		SELECT b.id, b.user_id, b.post_id, b.comment_id, b.collection_id, COALESCE(bc.name, ''),
+		       COALESCE(b.note, ''), p.title, p.category_id, c.name,
+		       IF(b.comment_id = 0, p.content, cm.content),
+		       IF(b.comment_id = 0,
+		          IF(p.is_anonymous AND p.user_id <> b.user_id, '`+models.AnonymousName+`', pu.username),
+		          IF(cm.is_anonymous AND cm.user_id <> b.user_id, '`+models.AnonymousName+`', cu.username)),
+		       COALESCE(cm.is_solution, FALSE), b.created_at
+		FROM bookmarks b
+		JOIN posts p ON b.post_id = p.id
+		JOIN categories c ON p.category_id = c.id
+		JOIN users pu ON p.user_id = pu.id
+		LEFT JOIN comments cm ON b.comment_id <> 0 AND cm.id = b.comment_id
+		LEFT JOIN users cu ON cm.user_id = cu.id
+		LEFT JOIN bookmark_collections bc ON b.collection_id = bc.id
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookmarks := []models.Bookmark{}
+	for rows.Next() {
+		var bookmark models.Bookmark
+		var commentID int
+		var collectionID sql.NullInt64
+		if err := rows.Scan(&bookmark.ID, &bookmark.UserID, &bookmark.PostID, &commentID, &collectionID,
+			&bookmark.CollectionName, &bookmark.Note, &bookmark.PostTitle, &bookmark.CategoryID, &bookmark.CategoryName,
+			&bookmark.Content, &bookmark.AuthorName, &bookmark.IsSolution, &bookmark.CreatedAt); err != nil {
+			continue
+		}
+		if commentID != 0 {
+			bookmark.CommentID = &commentID
+		}
+		if collectionID.Valid {
+			id := int(collectionID.Int64)
+			bookmark.CollectionID = &id
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+	return bookmarks, nil
+}
+
+// === COLLECTIONS DE FAVORIS ===
+
+// GetBookmarkCollections retourne les collections d'un utilisateur par ordre alphabétique,
+// avec leur nombre de favoris
+func (r *Repository) GetBookmarkCollections(userID int) ([]models.BookmarkCollection, error) {
+	rows, err := r.db.Query(`
+		SELECT bc.id, bc.user_id, bc.name, COUNT(b.id), bc.created_at
+		FROM bookmark_collections bc
+		LEFT JOIN bookmarks b ON b.collection_id = bc.id
+		WHERE bc.user_id = ?
+		GROUP BY bc.id, bc.user_id, bc.name, bc.created_at
+		ORDER BY bc.name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []models.BookmarkCollection{}
+	for rows.Next() {
+		var collection models.BookmarkCollection
+		if err := rows.Scan(&collection.ID, &collection.UserID, &collection.Name,
+			&collection.BookmarksCount, &collection.CreatedAt); err != nil {
+			continue
+		}
+		collections = append(collections, collection)
+	}
+	return collections, nil
+}
+
+// GetBookmarkCollection récupère une collection de l'utilisateur
+func (r *Repository) GetBookmarkCollection(collectionID, userID int) (*models.BookmarkCollection, error) {
+	var collection models.BookmarkCollection
+	err := r.db.QueryRow(`
+		SELECT id, user_id, name, created_at FROM bookmark_collections WHERE id = ? AND user_id = ?
+	`, collectionID, userID).Scan(&collection.ID, &collection.UserID, &collection.Name, &collection.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// bookmarkCollectionNameTaken vérifie qu'un utilisateur a déjà une autre collection de ce nom
+func (r *Repository) bookmarkCollectionNameTaken(userID int, name string, exceptID int) bool {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM bookmark_collections WHERE user_id = ? AND name = ? AND id <> ?
+	`, userID, name, exceptID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// CreateBookmarkCollection crée une collection de favoris, dans la limite de
+// models.MaxBookmarkCollections par utilisateur
+func (r *Repository) CreateBookmarkCollection(userID int, name string) (int64, error) {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM bookmark_collections WHERE user_id = ?", userID).Scan(&count); err != nil {
+		return 0, err
+	}
+	if count >= models.MaxBookmarkCollections {
+		return 0, fmt.Errorf("vous ne pouvez pas créer plus de %d collections", models.MaxBookmarkCollections)
+	}
+	if r.bookmarkCollectionNameTaken(userID, name, 0) {
+		return 0, fmt.Errorf("une collection porte déjà ce nom")
+	}
+
+	result, err := r.db.Exec("INSERT INTO bookmark_collections (user_id, name) VALUES (?, ?)", userID, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// RenameBookmarkCollection renomme une collection de l'utilisateur.
+// Retourne sql.ErrNoRows si la collection n'appartient pas à l'utilisateur.
+func (r *Repository) RenameBookmarkCollection(collectionID, userID int, name string) error {
+	if _, err := r.GetBookmarkCollection(collectionID, userID); err != nil {
+		return err
+	}
+	if r.bookmarkCollectionNameTaken(userID, name, collectionID) {
+		return fmt.Errorf("une collection porte déjà ce nom")
+	}
+	_, err := r.db.Exec("UPDATE bookmark_collections SET name = ? WHERE id = ? AND user_id = ?", name, collectionID, userID)
+	return err
+}
+
+// DeleteBookmarkCollection supprime une collection de l'utilisateur ; ses favoris sont
+// conservés, sans collection. Retourne sql.ErrNoRows si la collection n'appartient pas à l'utilisateur.
+func (r *Repository) DeleteBookmarkCollection(collectionID, userID int) error {
+	result, err := r.db.Exec("DELETE FROM bookmark_collections WHERE id = ? AND user_id = ?", collectionID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = r.db.Exec("UPDATE bookmarks SET collection_id = NULL WHERE collection_id = ?", collectionID)
+	return err
+}
@@ -0,0 +1,271 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"aide-devoir-forum/models"
+)
+
+// === PRIMES ===
+
+// Règles d'attribution : la prime revient à la première solution acceptée (hors réponse de
+// l'auteur de la prime). À l'expiration, elle revient à la réponse au meilleur score positif ;
+// sans aucune réponse elle est remboursée, et perdue si aucune réponse n'a de vote positif.
+// Une prime attribuée n'est pas reprise si la solution est retirée ensuite, ni si la question
+// ou la réponse est supprimée. Une prime en cours est remboursée si la modération supprime ou
+// fusionne la question, et perdue si son auteur la supprime.
+
+// OfferBounty ouvre une prime sur un post et débite son montant de la réputation de
+// l'utilisateur. Retourne sql.ErrNoRows si une prime est déjà en cours sur ce post.
+func (r *Repository) OfferBounty(postID, userID, amount, durationDays int) error {
+	result, err := r.db.Exec(`
+		INSERT INTO post_bounties (post_id, user_id, amount, expires_at)
+		SELECT ?, ?, ?, NOW() + INTERVAL ? DAY FROM DUAL
+		WHERE NOT EXISTS (SELECT 1 FROM post_bounties WHERE post_id = ? AND status = ?)
+	`, postID, userID, amount, durationDays, postID, models.BountyOpen)
+	if err != nil {
+		return err
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return r.insertReputationEvent(userID, userID, models.ReputationBountyOffered, "post", postID, -amount)
+}
+
+// GetOpenBounty récupère la prime en cours d'un post (sql.ErrNoRows s'il n'y en a pas)
+func (r *Repository) GetOpenBounty(postID int) (*models.Bounty, error) {
+	bounty := &models.Bounty{}
+	err := r.db.QueryRow(`
+		SELECT id, post_id, user_id, amount, status, created_at, expires_at
+		FROM post_bounties
+		WHERE post_id = ? AND status = ?
+	`, postID, models.BountyOpen).Scan(&bounty.ID, &bounty.PostID, &bounty.UserID, &bounty.Amount,
+		&bounty.Status, &bounty.CreatedAt, &bounty.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return bounty, nil
+}
+
+// GetLastAwardedBounty récupère la dernière prime attribuée d'un post (sql.ErrNoRows sinon).
+// Le gagnant d'une prime remportée par une réponse anonyme n'est pas dévoilé.
+func (r *Repository) GetLastAwardedBounty(postID int) (*models.Bounty, error) {
+	bounty := &models.Bounty{}
+	var awardedTo, awardedComment sql.NullInt64
+	var resolvedAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT b.id, b.post_id, b.user_id, b.amount, b.status,
+		       IF(ac.is_anonymous, NULL, b.awarded_to), IF(ac.is_anonymous, '`+models.AnonymousName+`', COALESCE(u.username, '')),
+		       b.awarded_comment_id, b.created_at, b.expires_at, b.resolved_at
+		FROM post_bounties b
+		LEFT JOIN users u ON b.awarded_to = u.id
+		LEFT JOIN comments ac ON b.awarded_comment_id = ac.id
+		WHERE b.post_id = ? AND b.status = ?
+		ORDER BY b.resolved_at DESC
+		LIMIT 1
+	`, postID, models.BountyAwarded).Scan(&bounty.ID, &bounty.PostID, &bounty.UserID, &bounty.Amount, &bounty.Status,
+		&awardedTo, &bounty.AwardedToName, &awardedComment, &bounty.CreatedAt, &bounty.ExpiresAt, &resolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	bounty.AwardedToID = nullIntPtr(awardedTo)
+	bounty.AwardedCommentID = nullIntPtr(awardedComment)
+	if resolvedAt.Valid {
+		at := resolvedAt.Time
+		bounty.ResolvedAt = &at
+	}
+	return bounty, nil
+}
+
+// awardPostBounty attribue la prime en cours d'un post à une réponse. Sans effet s'il n'y a
+// pas de prime ouverte ou si la réponse est celle de l'auteur de la prime.
+func (r *Repository) awardPostBounty(postID, commentID int) error {
+	bounty, err := r.GetOpenBounty(postID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var authorID int
+	if err := r.db.QueryRow("SELECT user_id FROM comments WHERE id = ?", commentID).Scan(&authorID); err != nil {
+		return err
+	}
+	if authorID == bounty.UserID {
+		return nil
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE post_bounties
+		SET status = ?, awarded_to = ?, awarded_comment_id = ?, resolved_at = NOW()
+		WHERE id = ? AND status = ?
+	`, models.BountyAwarded, authorID, commentID, bounty.ID, models.BountyOpen)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil
+	}
+
+	if err := r.insertReputationEvent(authorID, bounty.UserID, models.ReputationBountyAwarded, "comment", commentID, bounty.Amount); err != nil {
+		return err
+	}
+	return r.CreateNotification(authorID, models.NotificationBountyAwarded,
+		fmt.Sprintf("Votre réponse a remporté une prime de %d points", bounty.Amount), fmt.Sprintf("/post/%d", postID))
+}
+
+// ExpireBounties clôt les primes arrivées à échéance selon les règles d'attribution
+func (r *Repository) ExpireBounties() error {
+	rows, err := r.db.Query(`
+		SELECT id, post_id, user_id, amount FROM post_bounties
+		WHERE status = ? AND expires_at <= NOW()
+	`, models.BountyOpen)
+	if err != nil {
+		return err
+	}
+
+	var bounties []models.Bounty
+	for rows.Next() {
+		var bounty models.Bounty
+		if err := rows.Scan(&bounty.ID, &bounty.PostID, &bounty.UserID, &bounty.Amount); err != nil {
+			continue
+		}
+		bounties = append(bounties, bounty)
+	}
+	rows.Close()
+
+	for _, bounty := range bounties {
+		if err := r.expireBounty(bounty); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) expireBounty(bounty models.Bounty) error {
+	// Meilleure réponse d'un autre utilisateur, avec un score positif
+	var commentID int
+	err := r.db.QueryRow(`
+		SELECT id FROM comments
+		WHERE post_id = ? AND user_id != ? AND likes_count - dislikes_count > 0
+		ORDER BY likes_count - dislikes_count DESC, likes_count DESC, created_at ASC
+		LIMIT 1
+	`, bounty.PostID, bounty.UserID).Scan(&commentID)
+	if err == nil {
+		return r.awardPostBounty(bounty.PostID, commentID)
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	var answers int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM comments WHERE post_id = ? AND user_id != ?",
+		bounty.PostID, bounty.UserID).Scan(&answers); err != nil {
+		return err
+	}
+
+	status, message := models.BountyRefunded, fmt.Sprintf("Votre prime de %d points a expiré sans réponse : elle vous est remboursée", bounty.Amount)
+	if answers > 0 {
+		status, message = models.BountyExpired, fmt.Sprintf("Votre prime de %d points a expiré sans réponse appréciée par la communauté", bounty.Amount)
+	}
+
+	result, err := r.db.Exec("UPDATE post_bounties SET status = ?, resolved_at = NOW() WHERE id = ? AND status = ?",
+		status, bounty.ID, models.BountyOpen)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil
+	}
+
+	if status == models.BountyRefunded {
+		if err := r.insertReputationEvent(bounty.UserID, 0, models.ReputationBountyRefunded, "post", bounty.PostID, bounty.Amount); err != nil {
+			return err
+		}
+	}
+	return r.CreateNotification(bounty.UserID, models.NotificationBountyExpired, message, fmt.Sprintf("/post/%d", bounty.PostID))
+}
+
+// RefundOpenBounty rembourse la prime en cours d'un post retiré par la modération
+// (fusionné ou supprimé). Sans effet s'il n'y a pas de prime ouverte.
+func (r *Repository) RefundOpenBounty(postID int) error {
+	bounty, err := r.GetOpenBounty(postID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec("UPDATE post_bounties SET status = ?, resolved_at = NOW() WHERE id = ? AND status = ?",
+		models.BountyRefunded, bounty.ID, models.BountyOpen)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil
+	}
+
+	if err := r.insertReputationEvent(bounty.UserID, 0, models.ReputationBountyRefunded, "post", postID, bounty.Amount); err != nil {
+		return err
+	}
+	return r.CreateNotification(bounty.UserID, models.NotificationBountyExpired,
+		fmt.Sprintf("Votre prime de %d points vous est remboursée : la question a été retirée par la modération", bounty.Amount),
+		fmt.Sprintf("/post/%d", postID))
+}
+
+// GetBountyPosts récupère les questions visibles avec une prime en cours, dans une catégorie
+// (0 = toutes), les primes les plus élevées d'abord
+func (r *Repository) GetBountyPosts(categoryID int, viewer *models.User, limit int) ([]models.Post, error) {
+	visibility, args := visibleCategorySQL(viewer)
+	args = append([]interface{}{models.BountyOpen}, args...)
+	args = append(args, categoryID, categoryID, limit)
+	rows, err := r.db.Query(`
+		SELECT p.id, p.title, LEFT(p.content, 200) as content, `+postAuthorSQL+`, `+postAvatarSQL+`, p.is_anonymous,
+		       p.category_id, c.name as category_name, p.status, p.is_solved, p.is_pinned, p.is_locked,
+		       p.views_count, p.likes_count, p.dislikes_count, p.created_at,
+		       b.id, b.amount, b.expires_at
+		FROM posts p
+		JOIN post_bounties b ON b.post_id = p.id AND b.status = ?
+		JOIN users u ON p.user_id = u.id
+		JOIN roles r ON u.role_id = r.id
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.status = 'open' AND `+listedPostSQL+` AND `+visibility+`
+		  AND (? = 0 OR p.category_id = ?)
+		ORDER BY b.amount DESC, b.expires_at ASC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		var post models.Post
+		var avatarFilename sql.NullString
+		bounty := &models.Bounty{Status: models.BountyOpen}
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.UserRole, &post.UserBanned, &avatarFilename, &post.IsAnonymous,
+			&post.CategoryID, &post.CategoryName, &post.Status, &post.IsSolved, &post.IsPinned, &post.IsLocked,
+			&post.ViewsCount, &post.LikesCount, &post.DislikesCount, &post.CreatedAt,
+			&bounty.ID, &bounty.Amount, &bounty.ExpiresAt)
+		if err != nil {
+			continue
+		}
+
+		if avatarFilename.Valid {
+			post.UserAvatarURL = "/uploads/avatars/" + avatarFilename.String
+		}
+		bounty.PostID = post.ID
+		bounty.UserID = post.UserID
+		post.Bounty = bounty
+
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"database/sql"
+
+	"aide-devoir-forum/models"
+)
+
+// === PERMISSIONS DES CATÉGORIES ===
+
+// GetCategoryPermissions calcule les droits d'un utilisateur (nil = visiteur) dans une catégorie.
+// Les modérateurs globaux, les modérateurs de la catégorie et le professeur de la classe
+// (catégorie privée) ont tous les droits, y compris dans les catégories d'annonces.
+// La validation des réponses dépend de la permission verify_answers du rôle.
+func (r *Repository) GetCategoryPermissions(category *models.Category, user *models.User) models.CategoryPermissions {
+	perms := r.categoryAccess(category, user)
+	perms.CanVerify = perms.CanRead && user != nil && !user.IsBanned &&
+		r.RoleHasPermission(user.RoleID, models.PermissionVerifyAnswers)
+	return perms
+}
+
+// categoryAccess calcule les droits de lecture, publication, réponse et modération
+func (r *Repository) categoryAccess(category *models.Category, user *models.User) models.CategoryPermissions {
+	if user != nil && !user.IsBanned && r.canModerateCategory(category, user) {
+		return models.CategoryPermissions{CanRead: true, CanPost: true, CanComment: true, CanModerate: true}
+	}
+
+	role := models.RoleGuest
+	if user != nil {
+		role = user.RoleID
+	}
+
+	var perms models.CategoryPermissions
+	perms.CanRead = role >= category.ReadMinRole &&
+		(category.ClassGroupID == nil || (user != nil && r.isClassGroupMember(*category.ClassGroupID, user.ID)))
+	if user == nil || user.IsBanned || !perms.CanRead {
+		return perms
+	}
+
+	perms.CanPost = !category.IsAnnouncement && role >= category.PostMinRole
+	perms.CanComment = role >= category.CommentMinRole
+	return perms
+}
+
+// canModerateCategory vérifie qu'un utilisateur modère une catégorie
+func (r *Repository) canModerateCategory(category *models.Category, user *models.User) bool {
+	if user.CanModerate() {
+		return true
+	}
+	if category.ClassGroupID != nil && r.isClassGroupOwner(*category.ClassGroupID, user.ID) {
+		return true
+	}
+	return r.IsCategoryModerator(category.ID, user.ID)
+}
+
+// GetPostPermissions calcule les droits d'un utilisateur dans la catégorie d'un post
+func (r *Repository) GetPostPermissions(postID int, user *models.User) (models.CategoryPermissions, error) {
+	var categoryID int
+	err := r.db.QueryRow("SELECT category_id FROM posts WHERE id = ?", postID).Scan(&categoryID)
+	if err != nil {
+		return models.CategoryPermissions{}, err
+	}
+
+	category, err := r.GetCategory(categoryID)
+	if err != nil {
+		return models.CategoryPermissions{}, err
+	}
+	return r.GetCategoryPermissions(category, user), nil
+}
+
+// IsCategoryModerator vérifie qu'un utilisateur a été nommé modérateur d'une catégorie
+func (r *Repository) IsCategoryModerator(categoryID, userID int) bool {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM category_moderators WHERE category_id = ? AND user_id = ?
+	`, categoryID, userID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// ModeratesAnyCategory vérifie qu'un utilisateur modère au moins une catégorie,
+// par son rôle, une nomination ou une classe dont il est responsable
+func (r *Repository) ModeratesAnyCategory(user *models.User) bool {
+	if user.CanModerate() {
+		return true
+	}
+	var count int
+	err := r.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM category_moderators WHERE user_id = ?)
+		     + (SELECT COUNT(*) FROM class_groups WHERE owner_id = ?)
+	`, user.ID, user.ID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// GetCategoryModerators récupère les modérateurs nommés d'une catégorie (toutes si categoryID vaut 0)
+func (r *Repository) GetCategoryModerators(categoryID int) ([]models.CategoryModerator, error) {
+	rows, err := r.db.Query(`
+		SELECT cm.category_id, cm.user_id, u.username, COALESCE(cm.appointed_by, 0), cm.created_at
+		FROM category_moderators cm
+		JOIN users u ON cm.user_id = u.id
+		WHERE (? = 0 OR cm.category_id = ?)
+		ORDER BY cm.category_id, u.username
+	`, categoryID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var moderators []models.CategoryModerator
+	for rows.Next() {
+		var moderator models.CategoryModerator
+		if err := rows.Scan(&moderator.CategoryID, &moderator.UserID, &moderator.Username,
+			&moderator.AppointedBy, &moderator.CreatedAt); err != nil {
+			continue
+		}
+		moderators = append(moderators, moderator)
+	}
+	return moderators, nil
+}
+
+// AddCategoryModerator nomme un utilisateur modérateur d'une catégorie
+func (r *Repository) AddCategoryModerator(categoryID, userID, appointedBy int) error {
+	_, err := r.db.Exec(`
+		INSERT IGNORE INTO category_moderators (category_id, user_id, appointed_by) VALUES (?, ?, ?)
+	`, categoryID, userID, appointedBy)
+	return err
+}
+
+// RemoveCategoryModerator retire un modérateur d'une catégorie
+func (r *Repository) RemoveCategoryModerator(categoryID, userID int) error {
+	result, err := r.db.Exec("DELETE FROM category_moderators WHERE category_id = ? AND user_id = ?", categoryID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetPostPinned épingle ou désépingle un post en tête de sa catégorie
+func (r *Repository) SetPostPinned(postID int, pinned bool) error {
+	_, err := r.db.Exec("UPDATE posts SET is_pinned = ? WHERE id = ?", pinned, postID)
+	return err
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"database/sql"
+
+	"aide-devoir-forum/models"
+)
+
+// === ARBORESCENCE DES CATÉGORIES ===
+
+// maxCategoryDepth borne la remontée des parents pour se protéger d'un cycle en base
+const maxCategoryDepth = 10
+
+// nullIntPtr convertit une colonne entière nullable en pointeur
+func nullIntPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	id := int(value.Int64)
+	return &id
+}
+
+// sameParent compare deux parents (nil = racine)
+func sameParent(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// buildCategoryTree rattache chaque catégorie à son parent en conservant l'ordre de la requête.
+// Une catégorie dont le parent n'est pas visible est remontée à la racine. Le nombre de posts
+// d'une catégorie inclut celui de ses sous-catégories.
+func buildCategoryTree(flat []models.Category) []models.Category {
+	present := make(map[int]bool, len(flat))
+	for _, category := range flat {
+		present[category.ID] = true
+	}
+
+	children := make(map[int][]models.Category)
+	var roots []models.Category
+	for _, category := range flat {
+		if category.ParentID != nil && present[*category.ParentID] {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+	return attachCategoryChildren(roots, children, 0)
+}
+
+func attachCategoryChildren(nodes []models.Category, children map[int][]models.Category, depth int) []models.Category {
+	for i := range nodes {
+		nodes[i].Depth = depth
+		nodes[i].Children = attachCategoryChildren(children[nodes[i].ID], children, depth+1)
+		for _, child := range nodes[i].Children {
+			nodes[i].PostCount += child.PostCount
+		}
+	}
+	return nodes
+}
+
+// findCategoryNode cherche une catégorie dans une arborescence
+func findCategoryNode(tree []models.Category, id int) *models.Category {
+	for i := range tree {
+		if tree[i].ID == id {
+			return &tree[i]
+		}
+		if node := findCategoryNode(tree[i].Children, id); node != nil {
+			return node
+		}
+	}
+	return nil
+}
+
+// GetSubcategories récupère les sous-catégories directes visibles par l'utilisateur,
+// avec leur propre descendance et leurs compteurs agrégés
+func (r *Repository) GetSubcategories(parentID int, viewer *models.User) ([]models.Category, error) {
+	tree, err := r.GetCategories(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if node := findCategoryNode(tree, parentID); node != nil {
+		return node.Children, nil
+	}
+	return []models.Category{}, nil
+}
+
+// GetCategoryAncestors récupère les catégories parentes visibles par l'utilisateur,
+// de la racine au parent direct. Le fil s'arrête au premier parent masqué, comme
+// buildCategoryTree remonte à la racine une catégorie dont le parent est masqué.
+func (r *Repository) GetCategoryAncestors(categoryID int, viewer *models.User) ([]models.Category, error) {
+	ancestors, err := r.categoryAncestors(categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if !r.CanViewCategory(&ancestors[i], viewer) {
+			return ancestors[i+1:], nil
+		}
+	}
+	return ancestors, nil
+}
+
+// categoryAncestors récupère toutes les catégories parentes, de la racine au parent direct
+func (r *Repository) categoryAncestors(categoryID int) ([]models.Category, error) {
+	var ancestors []models.Category
+	var parentID sql.NullInt64
+	err := r.db.QueryRow("SELECT parent_id FROM categories WHERE id = ?", categoryID).Scan(&parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	for depth := 0; parentID.Valid && depth < maxCategoryDepth; depth++ {
+		var ancestor models.Category
+		var classGroupID sql.NullInt64
+		err := r.db.QueryRow(`
+			SELECT id, name, color, icon, read_min_role, class_group_id, parent_id FROM categories WHERE id = ?
+		`, parentID.Int64).Scan(&ancestor.ID, &ancestor.Name, &ancestor.Color, &ancestor.Icon,
+			&ancestor.ReadMinRole, &classGroupID, &parentID)
+		if err != nil {
+			break
+		}
+		ancestor.ClassGroupID = nullIntPtr(classGroupID)
+		ancestors = append([]models.Category{ancestor}, ancestors...)
+	}
+	return ancestors, nil
+}
+
+// IsCategoryDescendant vérifie si une catégorie se trouve sous une autre (ou est la même),
+// afin d'empêcher un déplacement qui créerait un cycle
+func (r *Repository) IsCategoryDescendant(categoryID, ancestorID int) bool {
+	if categoryID == ancestorID {
+		return true
+	}
+
+	ancestors, err := r.categoryAncestors(categoryID)
+	if err != nil {
+		return false
+	}
+	for _, ancestor := range ancestors {
+		if ancestor.ID == ancestorID {
+			return true
+		}
+	}
+	return false
+}
+
+// nextCategoryPosition retourne la position suivant la dernière catégorie sœur
+func (r *Repository) nextCategoryPosition(parentID *int) (int, error) {
+	var position int
+	err := r.db.QueryRow(`
+		SELECT COALESCE(MAX(position) + 1, 0) FROM categories WHERE parent_id <=> ?
+	`, parentID).Scan(&position)
+	return position, err
+}
+
+// ReorderCategories enregistre l'ordre des catégories sœurs d'un même parent (nil = racine).
+// Les identifiants qui n'appartiennent pas à ce parent sont ignorés.
+func (r *Repository) ReorderCategories(parentID *int, categoryIDs []int) error {
+	for position, id := range categoryIDs {
+		_, err := r.db.Exec(`
+			UPDATE categories SET position = ? WHERE id = ? AND parent_id <=> ?
+		`, position, id, parentID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"aide-devoir-forum/models"
+)
+
+// === CLASSES ===
+
+// visibleCategorySQL retourne la condition limitant une requête (catégories sous l'alias "c")
+// aux catégories lisibles par un utilisateur : rôle suffisant et, pour les catégories privées,
+// appartenance à la classe. Les professeurs de la classe, les modérateurs de la catégorie et
+// les modérateurs globaux voient tout.
+func visibleCategorySQL(viewer *models.User) (string, []interface{}) {
+	if viewer == nil {
+		return "(c.class_group_id IS NULL AND c.read_min_role = 0)", nil
+	}
+	if viewer.CanModerate() {
+		return "1 = 1", nil
+	}
+	return `((c.read_min_role <= ? AND (c.class_group_id IS NULL
+			OR c.class_group_id IN (SELECT class_group_id FROM class_group_members WHERE user_id = ?)))
+		OR c.class_group_id IN (SELECT id FROM class_groups WHERE owner_id = ?)
+		OR c.id IN (SELECT category_id FROM category_moderators WHERE user_id = ?))`,
+		[]interface{}{viewer.RoleID, viewer.ID, viewer.ID, viewer.ID}
+}
+
+// CanViewCategory vérifie qu'un utilisateur (nil = visiteur) peut voir une catégorie
+func (r *Repository) CanViewCategory(category *models.Category, viewer *models.User) bool {
+	return r.GetCategoryPermissions(category, viewer).CanRead
+}
+
+// isClassGroupMember vérifie qu'un utilisateur est inscrit dans une classe
+func (r *Repository) isClassGroupMember(groupID, userID int) bool {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM class_group_members WHERE class_group_id = ? AND user_id = ?
+	`, groupID, userID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// isClassGroupOwner vérifie qu'un utilisateur est le professeur d'une classe
+func (r *Repository) isClassGroupOwner(groupID, userID int) bool {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM class_groups WHERE id = ? AND owner_id = ?", groupID, userID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// CreateClassGroup crée une classe animée par un professeur
+func (r *Repository) CreateClassGroup(name string, ownerID int) (int64, error) {
+	result, err := r.db.Exec("INSERT INTO class_groups (name, owner_id) VALUES (?, ?)", name, ownerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// DeleteClassGroup supprime une classe sans catégorie privée : les posts des élèves
+// ne doivent pas devenir publics ni disparaître avec la classe.
+func (r *Repository) DeleteClassGroup(id int) error {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM categories WHERE class_group_id = ?", id).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return fmt.Errorf("impossible de supprimer une classe possédant des catégories privées")
+	}
+
+	if _, err := r.db.Exec("DELETE FROM class_group_members WHERE class_group_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec("UPDATE invite_codes SET class_group_id = NULL WHERE class_group_id = ?", id); err != nil {
+		return err
+	}
+	_, err = r.db.Exec("DELETE FROM class_groups WHERE id = ?", id)
+	return err
+}
+
+// GetClassGroupsByOwner récupère les classes animées par un professeur (toutes si ownerID vaut 0)
+func (r *Repository) GetClassGroupsByOwner(ownerID int) ([]models.ClassGroup, error) {
+	rows, err := r.db.Query(`
+		SELECT cg.id, cg.name, cg.owner_id, u.username, COUNT(m.user_id), cg.created_at
+		FROM class_groups cg
+		JOIN users u ON cg.owner_id = u.id
+		LEFT JOIN class_group_members m ON m.class_group_id = cg.id
+		WHERE (? = 0 OR cg.owner_id = ?)
+		GROUP BY cg.id, cg.name, cg.owner_id, u.username, cg.created_at
+		ORDER BY cg.name
+	`, ownerID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []models.ClassGroup
+	for rows.Next() {
+		var group models.ClassGroup
+		if err := rows.Scan(&group.ID, &group.Name, &group.OwnerID, &group.OwnerName,
+			&group.MemberCount, &group.CreatedAt); err != nil {
+			continue
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// GetClassGroup récupère une classe
+func (r *Repository) GetClassGroup(id int) (*models.ClassGroup, error) {
+	group := &models.ClassGroup{}
+	err := r.db.QueryRow(`
+		SELECT cg.id, cg.name, cg.owner_id, u.username,
+		       (SELECT COUNT(*) FROM class_group_members m WHERE m.class_group_id = cg.id),
+		       cg.created_at
+		FROM class_groups cg
+		JOIN users u ON cg.owner_id = u.id
+		WHERE cg.id = ?
+	`, id).Scan(&group.ID, &group.Name, &group.OwnerID, &group.OwnerName, &group.MemberCount, &group.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// GetClassGroupMembers récupère les élèves inscrits dans une classe
+func (r *Repository) GetClassGroupMembers(groupID int) ([]models.ClassGroupMember, error) {
+	rows, err := r.db.Query(`
+		SELECT m.user_id, u.username, r.name, m.joined_at
+		FROM class_group_members m
+		JOIN users u ON m.user_id = u.id
+		JOIN roles r ON u.role_id = r.id
+		WHERE m.class_group_id = ?
+		ORDER BY u.username
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.ClassGroupMember
+	for rows.Next() {
+		var member models.ClassGroupMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.RoleName, &member.JoinedAt); err != nil {
+			continue
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// AddClassGroupMember inscrit un utilisateur dans une classe
+func (r *Repository) AddClassGroupMember(groupID, userID int) error {
+	_, err := r.db.Exec(`
+		INSERT IGNORE INTO class_group_members (class_group_id, user_id) VALUES (?, ?)
+	`, groupID, userID)
+	return err
+}
+
+// RemoveClassGroupMember désinscrit un utilisateur d'une classe
+func (r *Repository) RemoveClassGroupMember(groupID, userID int) error {
+	result, err := r.db.Exec("DELETE FROM class_group_members WHERE class_group_id = ? AND user_id = ?", groupID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateClassCategory crée une catégorie privée réservée aux membres d'une classe
+func (r *Repository) CreateClassCategory(name, description, color, icon string, groupID int) error {
+	_, err := r.db.Exec(`
+		INSERT INTO categories (name, description, color, icon, class_group_id, created_at)
+		VALUES (?, ?, ?, ?, ?, NOW())
+	`, name, description, color, icon, groupID)
+	return err
+}
+
+// GetClassGroupCategories récupère les catégories privées d'une classe
+func (r *Repository) GetClassGroupCategories(groupID int) ([]models.Category, error) {
+	rows, err := r.db.Query(`
+		SELECT c.id, c.name, c.description, c.color, c.icon, COUNT(p.id) as post_count
+		FROM categories c
+		LEFT JOIN posts p ON c.id = p.category_id AND `+publishedPostSQL+`
+		WHERE c.class_group_id = ?
+		GROUP BY c.id, c.name, c.description, c.color, c.icon
+		ORDER BY c.name
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		var category models.Category
+		if err := rows.Scan(&category.ID, &category.Name, &category.Description,
+			&category.Color, &category.Icon, &category.PostCount); err != nil {
+			continue
+		}
+		category.ClassGroupID = &groupID
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
@@ -0,0 +1,248 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+
+	"aide-devoir-forum/models"
+)
+
+// === BROUILLONS ===
+
+// Les images ajoutées à un brouillon sont enregistrées dans la table images avec draft_id
+// renseigné (post_id et comment_id vides) : elles sont rattachées au post ou au commentaire
+// à la publication, et supprimées avec le brouillon sinon.
+
+// SaveDraft crée ou met à jour un brouillon. Un brouillon de réponse est unique par question
+// et commentaire visés : un nouvel enregistrement reprend le brouillon existant.
+// Retourne sql.ErrNoRows si draft.ID ne désigne pas un brouillon de l'utilisateur.
+func (r *Repository) SaveDraft(draft *models.Draft) error {
+	if draft.ID == 0 && draft.Kind == models.DraftKindComment {
+		err := r.db.QueryRow(`
+			SELECT id FROM drafts
+			WHERE user_id = ? AND kind = ? AND post_id <=> ? AND parent_id <=> ?
+			ORDER BY updated_at DESC
+			LIMIT 1
+		`, draft.UserID, models.DraftKindComment, draft.PostID, draft.ParentID).Scan(&draft.ID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	if draft.ID > 0 {
+		var count int
+		if err := r.db.QueryRow("SELECT COUNT(*) FROM drafts WHERE id = ? AND user_id = ?",
+			draft.ID, draft.UserID).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return sql.ErrNoRows
+		}
+		_, err := r.db.Exec(`
+			UPDATE drafts
+			SET title = ?, content = ?, category_id = ?, tags = ?, level = ?, due_at = ?, publish_at = ?, updated_at = NOW()
+			WHERE id = ? AND user_id = ?
+		`, draft.Title, draft.Content, draft.CategoryID, draft.Tags, draft.Level, draft.DueAt, draft.PublishAt,
+			draft.ID, draft.UserID)
+		return err
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO drafts (user_id, kind, post_id, parent_id, title, content, category_id, tags, level, due_at, publish_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, draft.UserID, draft.Kind, draft.PostID, draft.ParentID, draft.Title, draft.Content,
+		draft.CategoryID, draft.Tags, draft.Level, draft.DueAt, draft.PublishAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	draft.ID = int(id)
+	return nil
+}
+
+// draftSelectSQL sélectionne un brouillon avec le titre de la question visée et le nom de la catégorie
+const draftSelectSQL = `
+	SELECT d.id, d.user_id, d.kind, d.post_id, d.parent_id, COALESCE(p.title, ''), d.title, COALESCE(d.content, ''),
+	       d.category_id, COALESCE(c.name, ''), d.tags, d.level, d.due_at, d.publish_at, d.created_at, d.updated_at
+	FROM drafts d
+	LEFT JOIN posts p ON d.post_id = p.id
+	LEFT JOIN categories c ON d.category_id = c.id
+`
+
+func (r *Repository) queryDrafts(query string, args ...interface{}) ([]models.Draft, error) {
+	rows, err := r.db.Query(draftSelectSQL+query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var drafts []models.Draft
+	for rows.Next() {
+		var draft models.Draft
+		var postID, parentID, categoryID sql.NullInt64
+		err := rows.Scan(&draft.ID, &draft.UserID, &draft.Kind, &postID, &parentID, &draft.PostTitle, &draft.Title,
+			&draft.Content, &categoryID, &draft.CategoryName, &draft.Tags, &draft.Level, &draft.DueAt,
+			&draft.PublishAt, &draft.CreatedAt, &draft.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		draft.PostID = nullIntPtr(postID)
+		draft.ParentID = nullIntPtr(parentID)
+		draft.CategoryID = nullIntPtr(categoryID)
+		drafts = append(drafts, draft)
+	}
+	rows.Close()
+
+	for i := range drafts {
+		drafts[i].Images, _ = r.GetDraftImages(drafts[i].ID)
+	}
+	return drafts, nil
+}
+
+// GetDraft récupère un brouillon de l'utilisateur avec ses images en attente
+func (r *Repository) GetDraft(id, userID int) (*models.Draft, error) {
+	drafts, err := r.queryDrafts("WHERE d.id = ? AND d.user_id = ?", id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(drafts) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &drafts[0], nil
+}
+
+// GetUserDrafts récupère les brouillons d'un utilisateur, les plus récents d'abord
+func (r *Repository) GetUserDrafts(userID int) ([]models.Draft, error) {
+	return r.queryDrafts("WHERE d.user_id = ? ORDER BY d.updated_at DESC", userID)
+}
+
+// GetCommentDrafts récupère les brouillons de réponse d'un utilisateur sur une question
+func (r *Repository) GetCommentDrafts(userID, postID int) ([]models.Draft, error) {
+	return r.queryDrafts("WHERE d.user_id = ? AND d.kind = ? AND d.post_id = ?", userID, models.DraftKindComment, postID)
+}
+
+// GetDraftImages récupère les images en attente d'un brouillon
+func (r *Repository) GetDraftImages(draftID int) ([]models.Image, error) {
+	rows, err := r.db.Query(`
+		SELECT id, filename, original_name, content_type, size_bytes, width, height, user_id, created_at
+		FROM images
+		WHERE draft_id = ?
+		ORDER BY created_at ASC
+	`, draftID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []models.Image
+	for rows.Next() {
+		img := models.Image{DraftID: &draftID}
+		err := rows.Scan(&img.ID, &img.Filename, &img.OriginalName, &img.ContentType,
+			&img.SizeBytes, &img.Width, &img.Height, &img.UserID, &img.CreatedAt)
+		if err != nil {
+			continue
+		}
+		img.URL = "/uploads/posts/" + img.Filename
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// AddDraftImage enregistre une image en attente et prolonge la durée de vie du brouillon
+func (r *Repository) AddDraftImage(image *models.Image) error {
+	if err := r.CreateImage(image); err != nil {
+		return err
+	}
+	_, err := r.db.Exec("UPDATE drafts SET updated_at = NOW() WHERE id = ?", image.DraftID)
+	return err
+}
+
+// DeleteDraftImage supprime une image en attente de l'utilisateur et retourne son nom de fichier
+func (r *Repository) DeleteDraftImage(imageID, userID int) (string, error) {
+	var filename string
+	err := r.db.QueryRow("SELECT filename FROM images WHERE id = ? AND user_id = ? AND draft_id IS NOT NULL",
+		imageID, userID).Scan(&filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err := r.db.Exec("DELETE FROM images WHERE id = ?", imageID); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// PublishDraft rattache les images en attente d'un brouillon au post ou au commentaire
+// publié, puis supprime le brouillon
+func (r *Repository) PublishDraft(draftID, userID int, postID, commentID *int) error {
+	_, err := r.db.Exec(`
+		UPDATE images SET post_id = ?, comment_id = ?, draft_id = NULL
+		WHERE draft_id = ? AND user_id = ?
+	`, postID, commentID, draftID, userID)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec("DELETE FROM drafts WHERE id = ? AND user_id = ?", draftID, userID)
+	return err
+}
+
+// DeleteDraft supprime un brouillon de l'utilisateur et ses images en attente.
+// Retourne les noms des fichiers à supprimer du disque.
+func (r *Repository) DeleteDraft(id, userID int) ([]string, error) {
+	result, err := r.db.Exec("DELETE FROM drafts WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return r.deleteOrphanDraftImages()
+}
+
+// DeleteStaleDrafts supprime les brouillons qui n'ont pas été modifiés depuis
+// models.DraftRetention, ainsi que leurs images en attente. Retourne les noms des
+// fichiers à supprimer du disque.
+func (r *Repository) DeleteStaleDrafts() ([]string, error) {
+	_, err := r.db.Exec("DELETE FROM drafts WHERE updated_at < NOW() - INTERVAL ? SECOND",
+		int(models.DraftRetention.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	return r.deleteOrphanDraftImages()
+}
+
+// deleteOrphanDraftImages supprime les images en attente dont le brouillon n'existe plus
+func (r *Repository) deleteOrphanDraftImages() ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT i.id, i.filename FROM images i
+		LEFT JOIN drafts d ON i.draft_id = d.id
+		WHERE i.draft_id IS NOT NULL AND d.id IS NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []interface{}
+	var filenames []string
+	for rows.Next() {
+		var id int
+		var filename string
+		if err := rows.Scan(&id, &filename); err != nil {
+			continue
+		}
+		ids = append(ids, id)
+		filenames = append(filenames, filename)
+	}
+	rows.Close()
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	_, err = r.db.Exec("DELETE FROM images WHERE id IN (?"+strings.Repeat(", ?", len(ids)-1)+")", ids...)
+	if err != nil {
+		return nil, err
+	}
+	return filenames, nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"aide-devoir-forum/models"
+)
+
+// === IDENTITÉS EXTERNES (LDAP) ===
+
+// GetUserByExternalID récupère le compte rattaché à une identité externe
+func (r *Repository) GetUserByExternalID(provider, externalID string) (*models.User, error) {
+	return r.getUserWhere("u.auth_provider = ? AND u.external_id = ?", provider, externalID)
+}
+
+// GetUserByEmail récupère un utilisateur par son adresse email
+func (r *Repository) GetUserByEmail(email string) (*models.User, error) {
+	return r.getUserWhere("u.email = ?", email)
+}
+
+// CreateExternalUser crée le compte d'une identité externe (sans mot de passe local)
+func (r *Repository) CreateExternalUser(username, email, provider, externalID string, roleID int) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO users (username, email, password, role_id, auth_provider, external_id)
+		VALUES (?, ?, '', ?, ?, ?)
+	`, username, email, roleID, provider, externalID)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// LinkExternalIdentity rattache un compte local à une identité externe.
+// Le mot de passe local est effacé : la connexion passe désormais par le fournisseur.
+func (r *Repository) LinkExternalIdentity(userID int, provider, externalID string) error {
+	_, err := r.db.Exec(`
+		UPDATE users SET auth_provider = ?, external_id = ?, password = ''
+		WHERE id = ?
+	`, provider, externalID, userID)
+	return err
+}
+
+// getUserWhere récupère un utilisateur selon une condition sur la table users
+func (r *Repository) getUserWhere(condition string, args ...interface{}) (*models.User, error) {
+	user := &models.User{}
+	err := r.db.QueryRow(`
+		SELECT u.id, u.username, u.email, u.role_id, r.name,
+		       u.is_banned, COALESCE(u.ban_reason, '') as ban_reason, u.created_at,
+		       COALESCE(u.auth_provider, 'local')
+		FROM users u
+		JOIN roles r ON u.role_id = r.id
+		WHERE `+condition, args...).
+		Scan(&user.ID, &user.Username, &user.Email, &user.RoleID, &user.RoleName,
+			&user.IsBanned, &user.BanReason, &user.CreatedAt, &user.AuthProvider)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"aide-devoir-forum/models"
+)
+
+// === CODES D'INVITATION ===
+
+// CreateInviteCode enregistre un code d'invitation
+func (r *Repository) CreateInviteCode(code string, createdBy, roleID int, classGroupID *int, maxUses int, expiresAt *time.Time) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO invite_codes (code, created_by, role_id, class_group_id, max_uses, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, code, createdBy, roleID, classGroupID, maxUses, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetInviteCodeByCode récupère un code d'invitation (insensible à la casse)
+func (r *Repository) GetInviteCodeByCode(code string) (*models.InviteCode, error) {
+	rows, err := r.db.Query(inviteCodeSelect+" WHERE ic.code = ?", strings.ToUpper(code))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := scanInviteCodes(rows)
+	if len(invites) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &invites[0], nil
+}
+
+// GetInviteCodes récupère les codes créés par un utilisateur (tous si createdBy vaut 0),
+// avec les comptes créés grâce à chacun
+func (r *Repository) GetInviteCodes(createdBy int) ([]models.InviteCode, error) {
+	rows, err := r.db.Query(inviteCodeSelect+`
+		WHERE (? = 0 OR ic.created_by = ?)
+		ORDER BY ic.revoked_at IS NOT NULL, ic.created_at DESC
+		LIMIT 200
+	`, createdBy, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	invites := scanInviteCodes(rows)
+	rows.Close()
+
+	// Comptes créés avec chaque code
+	usedBy := make(map[int][]string)
+	userRows, err := r.db.Query(`
+		SELECT u.invite_code_id, u.username
+		FROM users u
+		JOIN invite_codes ic ON u.invite_code_id = ic.id
+		WHERE (? = 0 OR ic.created_by = ?)
+		ORDER BY u.created_at
+	`, createdBy, createdBy)
+	if err != nil {
+		return invites, err
+	}
+	defer userRows.Close()
+
+	for userRows.Next() {
+		var codeID int
+		var username string
+		if err := userRows.Scan(&codeID, &username); err == nil {
+			usedBy[codeID] = append(usedBy[codeID], username)
+		}
+	}
+	for i := range invites {
+		invites[i].UsedBy = usedBy[invites[i].ID]
+	}
+
+	return invites, nil
+}
+
+// ConsumeInviteCode réserve une utilisation d'un code d'invitation.
+// La mise à jour conditionnelle évite de dépasser max_uses, même sans transaction.
+func (r *Repository) ConsumeInviteCode(id int) error {
+	result, err := r.db.Exec(`
+		UPDATE invite_codes SET uses = uses + 1
+		WHERE id = ? AND uses < max_uses AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ReleaseInviteCode rend une utilisation réservée (ex: échec de création du compte)
+func (r *Repository) ReleaseInviteCode(id int) error {
+	_, err := r.db.Exec("UPDATE invite_codes SET uses = uses - 1 WHERE id = ? AND uses > 0", id)
+	return err
+}
+
+// RevokeInviteCode révoque un code d'invitation. Seul son créateur peut le révoquer,
+// sauf si createdBy vaut 0 (administrateur).
+func (r *Repository) RevokeInviteCode(id, createdBy int) error {
+	result, err := r.db.Exec(`
+		UPDATE invite_codes SET revoked_at = NOW()
+		WHERE id = ? AND (? = 0 OR created_by = ?) AND revoked_at IS NULL
+	`, id, createdBy, createdBy)
+	if err != nil {
+		return err
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateUserWithInvite crée un compte en enregistrant le code d'invitation utilisé (0 = aucun)
+func (r *Repository) CreateUserWithInvite(username, email, hashedPassword string, roleID, inviteCodeID int) (int64, error) {
+	var inviteID interface{}
+	if inviteCodeID > 0 {
+		inviteID = inviteCodeID
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO users (username, email, password, role_id, invite_code_id)
+		VALUES (?, ?, ?, ?, ?)
+	`, username, email, hashedPassword, roleID, inviteID)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// inviteCodeSelect est la requête commune de lecture des codes d'invitation
+const inviteCodeSelect = `
+	SELECT ic.id, ic.code, ic.created_by, u.username, ic.role_id, r.name,
+	       ic.class_group_id, COALESCE(cg.name, ''), ic.max_uses, ic.uses,
+	       ic.expires_at, ic.revoked_at, ic.created_at
+	FROM invite_codes ic
+	JOIN users u ON ic.created_by = u.id
+	JOIN roles r ON ic.role_id = r.id
+	LEFT JOIN class_groups cg ON ic.class_group_id = cg.id`
+
+// scanInviteCodes lit les lignes d'une requête sur invite_codes
+func scanInviteCodes(rows *sql.Rows) []models.InviteCode {
+	var invites []models.InviteCode
+	for rows.Next() {
+		var invite models.InviteCode
+		var classGroupID sql.NullInt64
+		var expiresAt, revokedAt sql.NullTime
+		err := rows.Scan(&invite.ID, &invite.Code, &invite.CreatedBy, &invite.CreatorName, &invite.RoleID, &invite.RoleName,
+			&classGroupID, &invite.ClassGroupName, &invite.MaxUses, &invite.Uses,
+			&expiresAt, &revokedAt, &invite.CreatedAt)
+		if err != nil {
+			continue
+		}
+
+		if classGroupID.Valid {
+			id := int(classGroupID.Int64)
+			invite.ClassGroupID = &id
+		}
+		if expiresAt.Valid {
+			invite.ExpiresAt = &expiresAt.Time
+		}
+		if revokedAt.Valid {
+			invite.RevokedAt = &revokedAt.Time
+		}
+
+		invites = append(invites, invite)
+	}
+	return invites
+}
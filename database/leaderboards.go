@@ -0,0 +1,192 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"aide-devoir-forum/models"
+)
+
+// === CLASSEMENTS ===
+
+// Les classements lisent la table leaderboard_stats : un agrégat par utilisateur, catégorie
+// et jour, tenu à jour à chaque événement du journal de réputation. Les événements liés à
+// un contenu anonyme n'y figurent pas, pour ne pas révéler son auteur.
+
+// leaderboardColumns associe chaque critère de classement à sa colonne agrégée
+var leaderboardColumns = map[string]string{
+	models.LeaderboardMetricSolutions:  "solutions",
+	models.LeaderboardMetricLikes:      "likes",
+	models.LeaderboardMetricReputation: "reputation",
+}
+
+// recordLeaderboardEvent répercute un événement de réputation (delta = 1) ou son annulation
+// (delta = -1) sur l'agrégat du jour de l'événement d'origine (occurredAt nil = aujourd'hui)
+func (r *Repository) recordLeaderboardEvent(userID int, eventType, sourceType string, sourceID, points, delta int, occurredAt *time.Time) error {
+	var solutions, likes int
+	switch eventType {
+	case models.ReputationSolutionAccepted:
+		solutions = delta
+	case models.ReputationPostLiked, models.ReputationCommentLiked:
+		likes = delta
+	}
+	if solutions == 0 && likes == 0 && points == 0 {
+		return nil
+	}
+
+	source := "posts p WHERE p.id = ? AND p.is_anonymous = FALSE"
+	if sourceType == "comment" {
+		source = "comments cm JOIN posts p ON cm.post_id = p.id WHERE cm.id = ? AND cm.is_anonymous = FALSE"
+	}
+
+	var day interface{}
+	if occurredAt != nil {
+		day = *occurredAt
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO leaderboard_stats (user_id, category_id, day, solutions, likes, reputation)
+		SELECT ?, p.category_id, DATE(COALESCE(?, NOW())), ?, ?, ?
+		FROM `+source+`
+		ON DUPLICATE KEY UPDATE
+			solutions = solutions + VALUES(solutions),
+			likes = likes + VALUES(likes),
+			reputation = reputation + VALUES(reputation)
+	`, userID, day, solutions, likes, points, sourceID)
+	return err
+}
+
+// EnsureLeaderboardStats reconstruit les agrégats depuis le journal de réputation
+// s'ils sont vides (premier démarrage après la création de la table)
+func (r *Repository) EnsureLeaderboardStats() error {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM leaderboard_stats").Scan(&count); err != nil || count > 0 {
+		return err
+	}
+	return r.RebuildLeaderboardStats()
+}
+
+// RebuildLeaderboardStats recalcule entièrement les agrégats depuis le journal de réputation.
+// Une annulation est comptée au jour de l'événement qu'elle annule, comme en incrémental.
+func (r *Repository) RebuildLeaderboardStats() error {
+	if _, err := r.db.Exec("DELETE FROM leaderboard_stats"); err != nil {
+		return err
+	}
+	return r.insertLeaderboardStats("1 = 1")
+}
+
+// rebuildCategoryLeaderboardStats recalcule les agrégats de catégories dont les contenus
+// ont changé (déplacement ou fusion de posts) : les événements passés y sont rattachés
+// à la catégorie actuelle de leur post
+func (r *Repository) rebuildCategoryLeaderboardStats(categoryIDs ...int) error {
+	placeholders := make([]string, len(categoryIDs))
+	args := make([]interface{}, len(categoryIDs))
+	for i, id := range categoryIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	in := "IN (" + strings.Join(placeholders, ", ") + ")"
+
+	if _, err := r.db.Exec("DELETE FROM leaderboard_stats WHERE category_id "+in, args...); err != nil {
+		return err
+	}
+	return r.insertLeaderboardStats("p.category_id "+in, args...)
+}
+
+// insertLeaderboardStats agrège le journal de réputation des posts retenus par la condition
+func (r *Repository) insertLeaderboardStats(condition string, args ...interface{}) error {
+	args = append([]interface{}{models.ReputationSolutionAccepted, models.ReputationPostLiked, models.ReputationCommentLiked}, args...)
+	_, err := r.db.Exec(`
+		INSERT INTO leaderboard_stats (user_id, category_id, day, solutions, likes, reputation)
+		SELECT e.user_id, p.category_id, DATE(COALESCE(orig.created_at, e.created_at)) as day,
+		       SUM(CASE WHEN e.event_type = ? THEN IF(e.reverses_id IS NULL, 1, -1) ELSE 0 END),
+		       SUM(CASE WHEN e.event_type IN (?, ?) THEN IF(e.reverses_id IS NULL, 1, -1) ELSE 0 END),
+		       SUM(e.points)
+		FROM reputation_events e
+		LEFT JOIN reputation_events orig ON e.reverses_id = orig.id
+		LEFT JOIN comments cm ON e.source_type = 'comment' AND cm.id = e.source_id
+		JOIN posts p ON p.id = IF(e.source_type = 'post', e.source_id, cm.post_id)
+		WHERE IF(e.source_type = 'post', p.is_anonymous, cm.is_anonymous) = FALSE AND `+condition+`
+		GROUP BY e.user_id, p.category_id, day
+	`, args...)
+	return err
+}
+
+// LeaderboardPeriodStart retourne le premier jour d'une période (nil = depuis toujours).
+// La semaine commence le lundi, l'année scolaire le 1er septembre.
+func LeaderboardPeriodStart(period string, now time.Time) *time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var start time.Time
+	switch period {
+	case models.LeaderboardPeriodWeek:
+		start = today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	case models.LeaderboardPeriodMonth:
+		start = today.AddDate(0, 0, 1-today.Day())
+	case models.LeaderboardPeriodSchoolYear:
+		year := today.Year()
+		if today.Month() < time.September {
+			year--
+		}
+		start = time.Date(year, time.September, 1, 0, 0, 0, 0, now.Location())
+	default:
+		return nil
+	}
+	return &start
+}
+
+// GetLeaderboard classe les utilisateurs selon un critère sur une période, dans une catégorie
+// (0 = toutes les catégories visibles par le lecteur). Les utilisateurs au profil privé
+// gardent leur rang mais sont anonymisés, sauf pour eux-mêmes.
+func (r *Repository) GetLeaderboard(metric, period string, categoryID int, viewer *models.User, limit int) ([]models.LeaderboardEntry, error) {
+	column, ok := leaderboardColumns[metric]
+	if !ok {
+		return nil, fmt.Errorf("critère de classement inconnu: %s", metric)
+	}
+
+	var since interface{}
+	if start := LeaderboardPeriodStart(period, time.Now()); start != nil {
+		since = start.Format("2006-01-02")
+	}
+
+	visibility, args := visibleCategorySQL(viewer)
+	args = append(args, categoryID, categoryID, since, since, limit)
+	rows, err := r.db.Query(`
+		SELECT s.user_id, u.username, u.avatar_filename, COALESCE(u.profile_visibility, 'public'),
+		       SUM(s.`+column+`) as total
+		FROM leaderboard_stats s
+		JOIN categories c ON s.category_id = c.id
+		JOIN users u ON s.user_id = u.id
+		WHERE `+visibility+`
+		  AND (? = 0 OR s.category_id = ?)
+		  AND (? IS NULL OR s.day >= ?)
+		GROUP BY s.user_id, u.username, u.avatar_filename, u.profile_visibility
+		HAVING total > 0
+		ORDER BY total DESC, u.username
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.LeaderboardEntry
+	for rows.Next() {
+		var entry models.LeaderboardEntry
+		var avatarFilename sql.NullString
+		var visibility string
+		if err := rows.Scan(&entry.UserID, &entry.Username, &avatarFilename, &visibility, &entry.Total); err != nil {
+			continue
+		}
+		entry.Rank = len(entries) + 1
+
+		if visibility == "private" && (viewer == nil || viewer.ID != entry.UserID) {
+			entry = models.LeaderboardEntry{Rank: entry.Rank, IsAnonymous: true, Total: entry.Total}
+		} else if avatarFilename.Valid {
+			entry.AvatarURL = "/uploads/avatars/" + avatarFilename.String
+		}
+
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
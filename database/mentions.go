@@ -0,0 +1,139 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// === MENTIONS ===
+
+// SearchMentionableUsers retourne les utilisateurs non bannis dont le pseudo commence par
+// prefix, pour l'autocomplétion des mentions : professeurs et modérateurs d'abord
+func (r *Repository) SearchMentionableUsers(prefix string, limit int) ([]models.MentionSuggestion, error) {
+	// "_" est un joker de LIKE, autorisé dans les pseudos
+	pattern := strings.ReplaceAll(prefix, "_", `\_`) + "%"
+	rows, err := r.db.Query(`
+		SELECT u.username, r.name, u.avatar_filename
+		FROM users u
+		JOIN roles r ON u.role_id = r.id
+		WHERE u.username LIKE ? AND NOT u.is_banned
+		ORDER BY u.role_id DESC, u.reputation DESC, u.username
+		LIMIT ?
+	`, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []models.MentionSuggestion{}
+	for rows.Next() {
+		var suggestion models.MentionSuggestion
+		var avatarFilename sql.NullString
+		if err := rows.Scan(&suggestion.Username, &suggestion.RoleName, &avatarFilename); err != nil {
+			continue
+		}
+		if avatarFilename.Valid {
+			suggestion.AvatarURL = "/uploads/avatars/" + avatarFilename.String
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
+
+// NotifyMentions notifie les utilisateurs mentionnés (@pseudo) dans une question ou une
+// réponse (commentID nil pour la question). Seuls les utilisateurs qui peuvent lire la
+// question sont notifiés, une seule fois par contenu, dans la limite de
+// models.MaxMentionsPerMessage et du quota horaire de l'auteur (models.MaxMentionsPerHour).
+// Retourne le nombre de mentions non notifiées à cause de ces limites.
+func (r *Repository) NotifyMentions(authorID int, authorName string, postID int, commentID *int, content string) (int, error) {
+	usernames := utils.ParseMentions(content)
+	if len(usernames) == 0 {
+		return 0, nil
+	}
+
+	placeholders := make([]string, len(usernames))
+	args := make([]interface{}, 0, len(usernames)+1)
+	for i, username := range usernames {
+		placeholders[i] = "?"
+		args = append(args, username)
+	}
+	rows, err := r.db.Query(`
+		SELECT id, username, role_id FROM users
+		WHERE username IN (`+strings.Join(placeholders, ", ")+`) AND NOT is_banned AND id != ?
+	`, append(args, authorID)...)
+	if err != nil {
+		return 0, err
+	}
+	var mentioned []models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.RoleID); err != nil {
+			continue
+		}
+		mentioned = append(mentioned, user)
+	}
+	rows.Close()
+
+	var sentLastHour int
+	if err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM mentions
+		WHERE author_id = ? AND created_at > NOW() - INTERVAL 1 HOUR
+	`, authorID).Scan(&sentLastHour); err != nil {
+		return 0, err
+	}
+	allowed := models.MaxMentionsPerHour - sentLastHour
+	if allowed > models.MaxMentionsPerMessage {
+		allowed = models.MaxMentionsPerMessage
+	}
+
+	link := fmt.Sprintf("/post/%d", postID)
+	target := 0
+	if commentID != nil {
+		target = *commentID
+		link += fmt.Sprintf("#comment-%d", target)
+	}
+
+	// Notifier dans l'ordre des mentions dans le contenu
+	skipped := 0
+	for _, username := range usernames {
+		var user *models.User
+		for i := range mentioned {
+			if strings.EqualFold(mentioned[i].Username, username) {
+				user = &mentioned[i]
+				break
+			}
+		}
+		if user == nil {
+			continue
+		}
+
+		// L'utilisateur mentionné doit pouvoir lire la question (catégories privées, classes)
+		title, ok := r.visiblePostTitle(postID, user)
+		if !ok {
+			continue
+		}
+
+		if allowed <= 0 {
+			skipped++
+			continue
+		}
+		result, err := r.db.Exec(`
+			INSERT IGNORE INTO mentions (user_id, author_id, post_id, comment_id) VALUES (?, ?, ?, ?)
+		`, user.ID, authorID, postID, target)
+		if err != nil {
+			return skipped, err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			continue // Déjà notifié pour ce contenu
+		}
+		allowed--
+
+		r.CreateNotification(user.ID, models.NotificationMention,
+			fmt.Sprintf("%s vous a mentionné dans « %s »", authorName, shortTitle(title)), link)
+	}
+	return skipped, nil
+}
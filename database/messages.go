@@ -0,0 +1,430 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"aide-devoir-forum/models"
+)
+
+// === MESSAGERIE PRIVÉE ===
+
+// conversationPair ordonne les participants d'une conversation (user1_id < user2_id)
+func conversationPair(a, b int) (int, int) {
+	if a > b {
+		return b, a
+	}
+	return a, b
+}
+
+// GetConversationBetween retourne la conversation entre deux utilisateurs (sql.ErrNoRows s'il n'y en a pas)
+func (r *Repository) GetConversationBetween(userID, otherID int) (int, error) {
+	user1, user2 := conversationPair(userID, otherID)
+	var id int
+	err := r.db.QueryRow("SELECT id FROM conversations WHERE user1_id = ? AND user2_id = ?", user1, user2).Scan(&id)
+	return id, err
+}
+
+// GetOrCreateConversation retourne la conversation entre deux utilisateurs, créée au besoin
+// par starterID
+func (r *Repository) GetOrCreateConversation(starterID, otherID int) (int, error) {
+	user1, user2 := conversationPair(starterID, otherID)
+	_, err := r.db.Exec(`
+		INSERT IGNORE INTO conversations (user1_id, user2_id, started_by) VALUES (?, ?, ?)
+	`, user1, user2, starterID)
+	if err != nil {
+		return 0, err
+	}
+	return r.GetConversationBetween(starterID, otherID)
+}
+
+// GetConversation récupère une conversation et ses participants
+func (r *Repository) GetConversation(conversationID int) (*models.Conversation, error) {
+	conversation := &models.Conversation{}
+	err := r.db.QueryRow(`
+		SELECT id, user1_id, user2_id, started_by, created_at, last_message_at
+		FROM conversations WHERE id = ?
+	`, conversationID).Scan(&conversation.ID, &conversation.User1ID, &conversation.User2ID,
+		&conversation.StartedBy, &conversation.CreatedAt, &conversation.LastMessageAt)
+	if err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// GetConversations retourne les conversations d'un utilisateur, les plus récemment actives d'abord,
+// avec leur interlocuteur, un extrait du dernier message et le nombre de messages non lus
+func (r *Repository) GetConversations(userID int) ([]models.Conversation, error) {
+	rows, err := r.db.Query(`
+		SELECT cv.id, cv.user1_id, cv.user2_id, cv.started_by, cv.created_at, cv.last_message_at,
+		       u.id, u.username, u.avatar_filename,
+		       COALESCE((SELECT LEFT(m.content, 120) FROM messages m WHERE m.conversation_id = cv.id
+		                 ORDER BY m.created_at DESC, m.id DESC LIMIT 1), ''),
+		       (SELECT COUNT(*) FROM messages m
+		        WHERE m.conversation_id = cv.id AND m.sender_id <> ? AND m.read_at IS NULL)
+		FROM conversations cv
+		JOIN users u ON u.id = IF(cv.user1_id = ?, cv.user2_id, cv.user1_id)
+		WHERE cv.user1_id = ? OR cv.user2_id = ?
+		ORDER BY cv.last_message_at DESC, cv.id DESC
+	`, userID, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := []models.Conversation{}
+	for rows.Next() {
+		var conversation models.Conversation
+		var avatarFilename sql.NullString
+		if err := rows.Scan(&conversation.ID, &conversation.User1ID, &conversation.User2ID, &conversation.StartedBy,
+			&conversation.CreatedAt, &conversation.LastMessageAt, &conversation.OtherUserID,
+			&conversation.OtherUsername, &avatarFilename, &conversation.LastMessage, &conversation.UnreadCount); err != nil {
+			continue
+		}
+		if avatarFilename.Valid && avatarFilename.String != "" {
+			conversation.OtherAvatarURL = "/uploads/avatars/" + avatarFilename.String
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+// GetMessages retourne les derniers messages d'une conversation, dans l'ordre chronologique
+func (r *Repository) GetMessages(conversationID int) ([]models.PrivateMessage, error) {
+	rows, err := r.db.Query(`
+		SELECT id, conversation_id, sender_id, username, content, read_at, created_at FROM (
+			SELECT m.id, m.conversation_id, m.sender_id, u.username, m.content, m.read_at, m.created_at
+			FROM messages m
+			JOIN users u ON m.sender_id = u.id
+			WHERE m.conversation_id = ?
+			ORDER BY m.id DESC
+			LIMIT ?
+		) recent
+		ORDER BY id
+	`, conversationID, models.MaxMessagesListed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.PrivateMessage{}
+	for rows.Next() {
+		var message models.PrivateMessage
+		var readAt sql.NullTime
+		if err := rows.Scan(&message.ID, &message.ConversationID, &message.SenderID, &message.SenderName,
+			&message.Content, &readAt, &message.CreatedAt); err != nil {
+			continue
+		}
+		if readAt.Valid {
+			message.ReadAt = &readAt.Time
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// SendMessage ajoute un message à une conversation. Le destinataire est notifié du premier
+// message non lu seulement, pour ne pas multiplier les notifications d'un même échange.
+func (r *Repository) SendMessage(conversationID int, sender *models.User, recipientID int, content string) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO messages (conversation_id, sender_id, content) VALUES (?, ?, ?)
+	`, conversationID, sender.ID, content)
+	if err != nil {
+		return 0, err
+	}
+	messageID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	r.db.Exec("UPDATE conversations SET last_message_at = NOW() WHERE id = ?", conversationID)
+
+	var unread int
+	r.db.QueryRow(`
+		SELECT COUNT(*) FROM messages WHERE conversation_id = ? AND sender_id = ? AND read_at IS NULL
+	`, conversationID, sender.ID).Scan(&unread)
+	if unread == 1 {
+		r.CreateNotification(recipientID, models.NotificationPrivateMessage,
+			fmt.Sprintf("%s vous a envoyé un message privé", sender.Username),
+			fmt.Sprintf("/messages/conversation?id=%d", conversationID))
+	}
+	return messageID, nil
+}
+
+// MarkConversationRead enregistre la lecture des messages reçus par un utilisateur dans une conversation
+func (r *Repository) MarkConversationRead(conversationID, userID int) error {
+	_, err := r.db.Exec(`
+		UPDATE messages SET read_at = NOW()
+		WHERE conversation_id = ? AND sender_id <> ? AND read_at IS NULL
+	`, conversationID, userID)
+	return err
+}
+
+// countConversationsStarted compte les conversations démarrées par un utilisateur sur la dernière heure
+func (r *Repository) countConversationsStarted(userID int) int {
+	var count int
+	r.db.QueryRow(`
+		SELECT COUNT(*) FROM conversations WHERE started_by = ? AND created_at > NOW() - INTERVAL 1 HOUR
+	`, userID).Scan(&count)
+	return count
+}
+
+// areAcquainted vérifie que deux utilisateurs se sont déjà rencontrés sur le forum : conversation
+// existante, réponse signée de l'un à une question signée de l'autre, ou classe commune
+// (inscrits ou professeur de la classe)
+func (r *Repository) areAcquainted(userID, otherID int) bool {
+	if _, err := r.GetConversationBetween(userID, otherID); err == nil {
+		return true
+	}
+
+	var count int
+	err := r.db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM comments c JOIN posts p ON c.post_id = p.id
+			 WHERE NOT c.is_anonymous AND NOT p.is_anonymous
+			   AND ((c.user_id = ? AND p.user_id = ?) OR (c.user_id = ? AND p.user_id = ?)))
+			+ (SELECT COUNT(*) FROM class_group_members m1
+			   JOIN class_group_members m2 ON m1.class_group_id = m2.class_group_id
+			   WHERE m1.user_id = ? AND m2.user_id = ?)
+			+ (SELECT COUNT(*) FROM class_groups g JOIN class_group_members m ON m.class_group_id = g.id
+			   WHERE (g.owner_id = ? AND m.user_id = ?) OR (g.owner_id = ? AND m.user_id = ?))
+	`, userID, otherID, otherID, userID, userID, otherID, userID, otherID, otherID, userID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// ConversationRestriction retourne la raison pour laquelle sender ne peut pas écrire à recipient,
+// ou "" s'il le peut. Dans une conversation existante, seul un blocage empêche de répondre ;
+// pour en démarrer une, la confidentialité du destinataire s'applique (un profil privé
+// n'accepte que ses contacts) et un élève ne peut écrire qu'à ses contacts.
+func (r *Repository) ConversationRestriction(sender, recipient *models.User) string {
+	if sender.ID == recipient.ID {
+		return "Vous ne pouvez pas vous écrire à vous-même"
+	}
+	if recipient.IsBanned {
+		return "Cet utilisateur est banni"
+	}
+	if r.IsBlockedEitherWay(sender.ID, recipient.ID) {
+		return "Vous ne pouvez pas écrire à cet utilisateur"
+	}
+	if _, err := r.GetConversationBetween(sender.ID, recipient.ID); err == nil {
+		return ""
+	}
+
+	privacy := recipient.MessagePrivacy
+	if privacy == models.MessagePrivacyEveryone && recipient.ProfileVisibility == "private" {
+		privacy = models.MessagePrivacyContacts
+	}
+	switch {
+	case privacy == models.MessagePrivacyNobody:
+		return "Cet utilisateur n'accepte pas de nouvelles conversations"
+	case privacy == models.MessagePrivacyContacts && !r.areAcquainted(sender.ID, recipient.ID):
+		return "Cet utilisateur n'accepte que les messages des personnes avec qui il a déjà échangé"
+	case !sender.IsProfessor() && !r.areAcquainted(sender.ID, recipient.ID):
+		return "Vous ne pouvez écrire qu'aux personnes avec qui vous avez déjà échangé sur le forum (réponses, classes)"
+	}
+	if !sender.CanModerate() && r.countConversationsStarted(sender.ID) >= models.MaxNewConversationsPerHour {
+		return fmt.Sprintf("Vous ne pouvez pas démarrer plus de %d conversations par heure", models.MaxNewConversationsPerHour)
+	}
+	return ""
+}
+
+// SetMessagePrivacy modifie qui peut démarrer une conversation avec un utilisateur
+func (r *Repository) SetMessagePrivacy(userID int, privacy string) error {
+	_, err := r.db.Exec("UPDATE users SET message_privacy = ? WHERE id = ?", privacy, userID)
+	return err
+}
+
+// === BLOCAGES ===
+
+// BlockUser bloque un utilisateur : aucun des deux ne peut plus écrire à l'autre
+func (r *Repository) BlockUser(userID, blockedID int) error {
+	_, err := r.db.Exec(`
+		INSERT IGNORE INTO user_blocks (user_id, blocked_user_id) VALUES (?, ?)
+	`, userID, blockedID)
+	return err
+}
+
+// UnblockUser lève le blocage d'un utilisateur
+func (r *Repository) UnblockUser(userID, blockedID int) error {
+	_, err := r.db.Exec("DELETE FROM user_blocks WHERE user_id = ? AND blocked_user_id = ?", userID, blockedID)
+	return err
+}
+
+// HasBlocked vérifie qu'un utilisateur en a bloqué un autre
+func (r *Repository) HasBlocked(userID, blockedID int) bool {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM user_blocks WHERE user_id = ? AND blocked_user_id = ?
+	`, userID, blockedID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// IsBlockedEitherWay vérifie que l'un des deux utilisateurs a bloqué l'autre
+func (r *Repository) IsBlockedEitherWay(userID, otherID int) bool {
+	return r.HasBlocked(userID, otherID) || r.HasBlocked(otherID, userID)
+}
+
+// GetBlockedUsers retourne les utilisateurs bloqués par un utilisateur
+func (r *Repository) GetBlockedUsers(userID int) ([]models.BlockedUser, error) {
+	rows, err := r.db.Query(`
+		SELECT u.id, u.username, b.created_at
+		FROM user_blocks b
+		JOIN users u ON b.blocked_user_id = u.id
+		WHERE b.user_id = ?
+		ORDER BY u.username
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocked := []models.BlockedUser{}
+	for rows.Next() {
+		var user models.BlockedUser
+		if rows.Scan(&user.UserID, &user.Username, &user.CreatedAt) == nil {
+			blocked = append(blocked, user)
+		}
+	}
+	return blocked, nil
+}
+
+// === SIGNALEMENTS DE CONVERSATIONS ===
+
+// ReportConversation signale une conversation aux modérateurs, qui sont notifiés.
+// Un participant n'a qu'un signalement en attente par conversation.
+func (r *Repository) ReportConversation(conversationID, reporterID int, reason, description string) error {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM reports
+		WHERE reported_type = 'conversation' AND reported_id = ? AND reporter_id = ? AND status IN (?, ?)
+	`, conversationID, reporterID, models.ReportPending, models.ReportReviewed).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("vous avez déjà signalé cette conversation")
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO reports (reporter_id, reported_type, reported_id, reason, description)
+		VALUES (?, 'conversation', ?, ?, ?)
+	`, reporterID, conversationID, reason, description)
+	if err != nil {
+		return err
+	}
+	reportID, _ := result.LastInsertId()
+
+	rows, err := r.db.Query("SELECT id FROM users WHERE role_id >= ? AND NOT is_banned", models.RoleModerator)
+	if err != nil {
+		return nil
+	}
+	var moderatorIDs []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			moderatorIDs = append(moderatorIDs, id)
+		}
+	}
+	rows.Close()
+	for _, moderatorID := range moderatorIDs {
+		r.CreateNotification(moderatorID, models.NotificationMessageReport,
+			fmt.Sprintf("Conversation privée signalée (signalement #%d) : %s", reportID, reason),
+			"/admin/message-reports")
+	}
+	return nil
+}
+
+// conversationReportsQuery sélectionne les signalements de conversations (alias "rp"), avec le nom
+// du signalant, de l'autre participant et du modérateur
+const conversationReportsQuery = `
+	SELECT rp.id, rp.reported_id, rp.reporter_id, ru.username, COALESCE(ou.username, ''),
+	       rp.reason, COALESCE(rp.description, ''), rp.status, COALESCE(mu.username, ''),
+	       rp.created_at, rp.resolved_at
+	FROM reports rp
+	JOIN users ru ON rp.reporter_id = ru.id
+	JOIN conversations cv ON rp.reported_id = cv.id
+	LEFT JOIN users ou ON ou.id = IF(cv.user1_id = rp.reporter_id, cv.user2_id, cv.user1_id)
+	LEFT JOIN users mu ON rp.moderator_id = mu.id
+	WHERE rp.reported_type = 'conversation'`
+
+// scanConversationReports lit les lignes de conversationReportsQuery
+func scanConversationReports(rows *sql.Rows) []models.ConversationReport {
+	reports := []models.ConversationReport{}
+	for rows.Next() {
+		var report models.ConversationReport
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&report.ID, &report.ConversationID, &report.ReporterID, &report.ReporterName,
+			&report.ReportedName, &report.Reason, &report.Description, &report.Status, &report.ModeratorName,
+			&report.CreatedAt, &resolvedAt); err != nil {
+			continue
+		}
+		if resolvedAt.Valid {
+			report.ResolvedAt = &resolvedAt.Time
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// GetOpenConversationReports retourne les signalements de conversations à traiter, les plus anciens d'abord
+func (r *Repository) GetOpenConversationReports() ([]models.ConversationReport, error) {
+	rows, err := r.db.Query(conversationReportsQuery+`
+		  AND rp.status IN (?, ?)
+		ORDER BY rp.created_at, rp.id
+	`, models.ReportPending, models.ReportReviewed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanConversationReports(rows), nil
+}
+
+// GetConversationReports retourne tous les signalements d'une conversation
+func (r *Repository) GetConversationReports(conversationID int) ([]models.ConversationReport, error) {
+	rows, err := r.db.Query(conversationReportsQuery+`
+		  AND rp.reported_id = ?
+		ORDER BY rp.created_at, rp.id
+	`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanConversationReports(rows), nil
+}
+
+// HasOpenConversationReport vérifie qu'une conversation fait l'objet d'un signalement non traité,
+// condition de son accès par les modérateurs
+func (r *Repository) HasOpenConversationReport(conversationID int) bool {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM reports
+		WHERE reported_type = 'conversation' AND reported_id = ? AND status IN (?, ?)
+	`, conversationID, models.ReportPending, models.ReportReviewed).Scan(&count)
+	return err == nil && count > 0
+}
+
+// MarkConversationReportsReviewed passe les signalements en attente d'une conversation au statut
+// "consulté" par un modérateur
+func (r *Repository) MarkConversationReportsReviewed(conversationID, moderatorID int) error {
+	_, err := r.db.Exec(`
+		UPDATE reports SET status = ?, moderator_id = ?
+		WHERE reported_type = 'conversation' AND reported_id = ? AND status = ?
+	`, models.ReportReviewed, moderatorID, conversationID, models.ReportPending)
+	return err
+}
+
+// CloseConversationReport clôt un signalement de conversation (status ReportResolved ou ReportDismissed)
+// et retourne la conversation concernée. Retourne sql.ErrNoRows si le signalement n'est pas ouvert.
+func (r *Repository) CloseConversationReport(reportID, moderatorID int, status string) (int, error) {
+	var conversationID int
+	err := r.db.QueryRow(`
+		SELECT reported_id FROM reports
+		WHERE id = ? AND reported_type = 'conversation' AND status IN (?, ?)
+	`, reportID, models.ReportPending, models.ReportReviewed).Scan(&conversationID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = r.db.Exec(`
+		UPDATE reports SET status = ?, moderator_id = ?, resolved_at = NOW() WHERE id = ?
+	`, status, moderatorID, reportID)
+	return conversationID, err
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"database/sql"
+
+	"aide-devoir-forum/models"
+)
+
+// === NOTIFICATIONS ===
+
+// CreateNotification ajoute une notification pour un utilisateur (link vide = pas de lien)
+func (r *Repository) CreateNotification(userID int, notificationType, message, link string) error {
+	var target interface{}
+	if link != "" {
+		target = link
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO notifications (user_id, type, message, link)
+		VALUES (?, ?, ?, ?)
+	`, userID, notificationType, message, target)
+	return err
+}
+
+// GetNotifications récupère les dernières notifications d'un utilisateur
+func (r *Repository) GetNotifications(userID, limit int) ([]models.Notification, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, type, message, link, is_read, created_at
+		FROM notifications
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var notification models.Notification
+		var link sql.NullString
+		if err := rows.Scan(&notification.ID, &notification.UserID, &notification.Type, &notification.Message,
+			&link, &notification.IsRead, &notification.CreatedAt); err != nil {
+			continue
+		}
+		notification.Link = link.String
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+// MarkNotificationsRead marque toutes les notifications d'un utilisateur comme lues
+func (r *Repository) MarkNotificationsRead(userID int) error {
+	_, err := r.db.Exec("UPDATE notifications SET is_read = TRUE WHERE user_id = ? AND is_read = FALSE", userID)
+	return err
+}
+
+// visiblePostTitle retourne le titre d'une question si l'utilisateur à notifier peut la lire
+// (catégories privées, classes)
+func (r *Repository) visiblePostTitle(postID int, user *models.User) (string, bool) {
+	visibility, args := visibleCategorySQL(user)
+	var title string
+	err := r.db.QueryRow(`
+		SELECT p.title FROM posts p
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.id = ? AND `+visibility,
+		append([]interface{}{postID}, args...)...).Scan(&title)
+	return title, err == nil
+}
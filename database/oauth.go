@@ -0,0 +1,167 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+
+	"aide-devoir-forum/models"
+)
+
+// === FOURNISSEUR OAUTH2 / OPENID CONNECT ===
+
+// CreateOAuthClient enregistre une application cliente (seul le hash du secret est stocké)
+func (r *Repository) CreateOAuthClient(clientID, secretHash, name string, redirectURIs []string, confidential bool, createdBy int) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO oauth_clients (client_id, client_secret_hash, name, redirect_uris, is_confidential, created_by)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, clientID, secretHash, name, strings.Join(redirectURIs, "\n"), confidential, createdBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetOAuthClientByClientID récupère une application cliente par son identifiant public
+func (r *Repository) GetOAuthClientByClientID(clientID string) (*models.OAuthClient, error) {
+	rows, err := r.db.Query(`
+		SELECT id, client_id, COALESCE(client_secret_hash, ''), name, redirect_uris,
+		       is_confidential, created_by, created_at, revoked_at
+		FROM oauth_clients
+		WHERE client_id = ?
+	`, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := scanOAuthClients(rows)
+	if len(clients) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &clients[0], nil
+}
+
+// GetOAuthClients récupère toutes les applications clientes (révoquées incluses)
+func (r *Repository) GetOAuthClients() ([]models.OAuthClient, error) {
+	rows, err := r.db.Query(`
+		SELECT id, client_id, COALESCE(client_secret_hash, ''), name, redirect_uris,
+		       is_confidential, created_by, created_at, revoked_at
+		FROM oauth_clients
+		ORDER BY revoked_at IS NOT NULL, created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanOAuthClients(rows), nil
+}
+
+// RevokeOAuthClient révoque une application cliente et les consentements associés
+func (r *Repository) RevokeOAuthClient(id int) error {
+	var clientID string
+	err := r.db.QueryRow("SELECT client_id FROM oauth_clients WHERE id = ? AND revoked_at IS NULL", id).Scan(&clientID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.db.Exec("UPDATE oauth_clients SET revoked_at = NOW() WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec("DELETE FROM oauth_consents WHERE client_id = ?", clientID)
+	return err
+}
+
+// CreateOAuthCode enregistre un code d'autorisation et purge les codes expirés
+func (r *Repository) CreateOAuthCode(code *models.OAuthAuthorizationCode) error {
+	r.db.Exec("DELETE FROM oauth_authorization_codes WHERE expires_at < NOW() - INTERVAL 1 DAY")
+
+	_, err := r.db.Exec(`
+		INSERT INTO oauth_authorization_codes
+			(code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, code_challenge_method, nonce, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, strings.Join(code.Scopes, " "),
+		code.CodeChallenge, code.CodeChallengeMethod, code.Nonce, code.ExpiresAt)
+	return err
+}
+
+// ConsumeOAuthCode marque un code d'autorisation comme utilisé et le retourne.
+// La mise à jour conditionnelle garantit qu'un code ne sert qu'une fois, même sans transaction.
+func (r *Repository) ConsumeOAuthCode(codeHash string) (*models.OAuthAuthorizationCode, error) {
+	result, err := r.db.Exec(`
+		UPDATE oauth_authorization_codes SET used_at = NOW()
+		WHERE code_hash = ? AND used_at IS NULL AND expires_at > NOW()
+	`, codeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	code := &models.OAuthAuthorizationCode{}
+	var scopes string
+	var usedAt sql.NullTime
+	err = r.db.QueryRow(`
+		SELECT code_hash, client_id, user_id, redirect_uri, scopes, code_challenge,
+		       code_challenge_method, COALESCE(nonce, ''), expires_at, used_at, created_at
+		FROM oauth_authorization_codes
+		WHERE code_hash = ?
+	`, codeHash).Scan(&code.CodeHash, &code.ClientID, &code.UserID, &code.RedirectURI, &scopes,
+		&code.CodeChallenge, &code.CodeChallengeMethod, &code.Nonce, &code.ExpiresAt, &usedAt, &code.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	code.Scopes = strings.Fields(scopes)
+	if usedAt.Valid {
+		code.UsedAt = &usedAt.Time
+	}
+	return code, nil
+}
+
+// GetOAuthConsent récupère les portées déjà accordées par un utilisateur à une application
+func (r *Repository) GetOAuthConsent(userID int, clientID string) ([]string, error) {
+	var scopes string
+	err := r.db.QueryRow("SELECT scopes FROM oauth_consents WHERE user_id = ? AND client_id = ?",
+		userID, clientID).Scan(&scopes)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(scopes), nil
+}
+
+// SaveOAuthConsent enregistre (ou remplace) le consentement d'un utilisateur pour une application
+func (r *Repository) SaveOAuthConsent(userID int, clientID string, scopes []string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO oauth_consents (user_id, client_id, scopes) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE scopes = VALUES(scopes), updated_at = NOW()
+	`, userID, clientID, strings.Join(scopes, " "))
+	return err
+}
+
+// scanOAuthClients lit les lignes d'une requête sur oauth_clients
+func scanOAuthClients(rows *sql.Rows) []models.OAuthClient {
+	var clients []models.OAuthClient
+	for rows.Next() {
+		var client models.OAuthClient
+		var redirectURIs string
+		var revokedAt sql.NullTime
+		err := rows.Scan(&client.ID, &client.ClientID, &client.SecretHash, &client.Name, &redirectURIs,
+			&client.IsConfidential, &client.CreatedBy, &client.CreatedAt, &revokedAt)
+		if err != nil {
+			continue
+		}
+
+		client.RedirectURIs = strings.Fields(redirectURIs)
+		if revokedAt.Valid {
+			client.RevokedAt = &revokedAt.Time
+		}
+
+		clients = append(clients, client)
+	}
+	return clients
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"aide-devoir-forum/models"
+)
+
+// === SONDAGES ===
+
+// CreatePoll attache un sondage et ses options à un post
+func (r *Repository) CreatePoll(poll *models.Poll) error {
+	result, err := r.db.Exec(`
+		INSERT INTO polls (post_id, question, is_multiple, is_anonymous, closes_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, poll.PostID, poll.Question, poll.IsMultiple, poll.IsAnonymous, poll.ClosesAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	poll.ID = int(id)
+
+	for i := range poll.Options {
+		result, err := r.db.Exec("INSERT INTO poll_options (poll_id, label, position) VALUES (?, ?, ?)",
+			poll.ID, poll.Options[i].Label, i)
+		if err != nil {
+			return err
+		}
+		optionID, _ := result.LastInsertId()
+		poll.Options[i].ID = int(optionID)
+	}
+	return nil
+}
+
+// GetPostPoll récupère le sondage d'un post avec ses résultats (sql.ErrNoRows s'il n'y en a pas).
+// viewerID (0 = visiteur) sert à indiquer les choix de l'utilisateur connecté.
+func (r *Repository) GetPostPoll(postID, viewerID int) (*models.Poll, error) {
+	poll := &models.Poll{PostID: postID}
+	var closesAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT id, question, is_multiple, is_anonymous, closes_at, created_at
+		FROM polls WHERE post_id = ?
+	`, postID).Scan(&poll.ID, &poll.Question, &poll.IsMultiple, &poll.IsAnonymous, &closesAt, &poll.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if closesAt.Valid {
+		at := closesAt.Time
+		poll.ClosesAt = &at
+		poll.IsClosed = !at.After(time.Now())
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, label, votes_count FROM poll_options
+		WHERE poll_id = ?
+		ORDER BY position, id
+	`, poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	positions := map[int]int{}
+	for rows.Next() {
+		var option models.PollOption
+		if err := rows.Scan(&option.ID, &option.Label, &option.VotesCount); err != nil {
+			continue
+		}
+		positions[option.ID] = len(poll.Options)
+		poll.Options = append(poll.Options, option)
+	}
+	rows.Close()
+
+	r.db.QueryRow("SELECT COUNT(DISTINCT user_id) FROM poll_votes WHERE poll_id = ?", poll.ID).Scan(&poll.VotersCount)
+	for i := range poll.Options {
+		if poll.VotersCount > 0 {
+			poll.Options[i].Percent = poll.Options[i].VotesCount * 100 / poll.VotersCount
+		}
+	}
+
+	// Choix de l'utilisateur connecté et, pour les votes visibles, noms des votants
+	rows, err = r.db.Query(`
+		SELECT v.option_id, v.user_id, u.username
+		FROM poll_votes v
+		JOIN users u ON v.user_id = u.id
+		WHERE v.poll_id = ?
+		ORDER BY v.created_at, v.id
+	`, poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var optionID, userID int
+		var username string
+		if err := rows.Scan(&optionID, &userID, &username); err != nil {
+			continue
+		}
+		i, ok := positions[optionID]
+		if !ok {
+			continue
+		}
+		if viewerID > 0 && userID == viewerID {
+			poll.Options[i].Selected = true
+			poll.HasVoted = true
+		}
+		if !poll.IsAnonymous {
+			poll.Options[i].Voters = append(poll.Options[i].Voters, username)
+		}
+	}
+	return poll, nil
+}
+
+// VotePoll enregistre les choix d'un utilisateur, qui remplacent son vote précédent
+// (aucun choix = retrait du vote). Les options doivent appartenir au sondage.
+func (r *Repository) VotePoll(pollID, userID int, optionIDs []int) error {
+	if _, err := r.db.Exec("DELETE FROM poll_votes WHERE poll_id = ? AND user_id = ?", pollID, userID); err != nil {
+		return err
+	}
+
+	if len(optionIDs) > 0 {
+		values := make([]string, 0, len(optionIDs))
+		args := make([]interface{}, 0, len(optionIDs)*3)
+		for _, optionID := range optionIDs {
+			values = append(values, "(?, ?, ?)")
+			args = append(args, pollID, optionID, userID)
+		}
+		_, err := r.db.Exec("INSERT IGNORE INTO poll_votes (poll_id, option_id, user_id) VALUES "+
+			strings.Join(values, ", "), args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.updatePollVoteCounts(pollID)
+}
+
+// updatePollVoteCounts recalcule le nombre de votes de chaque option d'un sondage
+func (r *Repository) updatePollVoteCounts(pollID int) error {
+	_, err := r.db.Exec(`
+		UPDATE poll_options o
+		SET votes_count = (SELECT COUNT(*) FROM poll_votes v WHERE v.option_id = o.id)
+		WHERE o.poll_id = ?
+	`, pollID)
+	return err
+}
+
+// deletePostPoll supprime le sondage d'un post, ses options et ses votes
+func (r *Repository) deletePostPoll(postID int) error {
+	var pollID int
+	err := r.db.QueryRow("SELECT id FROM polls WHERE post_id = ?", postID).Scan(&pollID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		"DELETE FROM poll_votes WHERE poll_id = ?",
+		"DELETE FROM poll_options WHERE poll_id = ?",
+		"DELETE FROM polls WHERE id = ?",
+	} {
+		if _, err := r.db.Exec(query, pollID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
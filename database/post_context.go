@@ -0,0 +1,87 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+
+	"aide-devoir-forum/models"
+)
+
+// === NIVEAU SCOLAIRE ET DATE DE RENDU ===
+
+// postFilterSQL traduit les filtres d'une liste de posts en conditions SQL (alias p)
+func postFilterSQL(filter models.PostFilter) (string, []interface{}) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+
+	if filter.Level != "" {
+		conditions = append(conditions, "p.level = ?")
+		args = append(args, filter.Level)
+	}
+
+	switch filter.Due {
+	case models.DueFilterUpcoming:
+		conditions = append(conditions, "p.due_at >= NOW()")
+	case models.DueFilterWeek:
+		conditions = append(conditions, "p.due_at >= NOW() AND p.due_at < NOW() + INTERVAL 7 DAY")
+	case models.DueFilterPast:
+		conditions = append(conditions, "p.due_at < NOW()")
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// postOrderSQL retourne l'ordre d'une liste de posts. Le tri "urgent" place d'abord les
+// questions non résolues dont la date de rendu approche, puis les autres par date.
+func postOrderSQL(sort string) string {
+	if sort == models.PostSortUrgent {
+		return `COALESCE(p.due_at >= NOW() AND p.is_solved = FALSE, FALSE) DESC,
+		        CASE WHEN p.due_at >= NOW() AND p.is_solved = FALSE THEN p.due_at END ASC,
+		        p.created_at DESC`
+	}
+	return "p.is_pinned DESC, p.created_at DESC"
+}
+
+// scanPostContext convertit les colonnes de niveau et de date de rendu d'un post
+func scanPostContext(post *models.Post, level sql.NullString, dueAt sql.NullTime) {
+	post.Level = level.String
+	if dueAt.Valid {
+		at := dueAt.Time
+		post.DueAt = &at
+	}
+}
+
+// GetArchiveSuggestions récupère les questions non archivées dont la date de rendu est
+// dépassée depuis plus de models.ArchiveSuggestionDelay, les plus anciennes d'abord
+func (r *Repository) GetArchiveSuggestions(limit int) ([]models.Post, error) {
+	rows, err := r.db.Query(`
+		SELECT p.id, p.title, `+postAuthorIDSQL+`, `+postAuthorNameSQL+`, p.category_id, c.name, p.status, p.is_solved, p.created_at,
+		       p.level, p.due_at
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.status != 'archived' AND `+notMergedPostSQL+` AND `+publishedPostSQL+`
+		  AND p.due_at < NOW() - INTERVAL ? SECOND
+		ORDER BY p.due_at ASC
+		LIMIT ?
+	`, int(models.ArchiveSuggestionDelay.Seconds()), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		var post models.Post
+		var level sql.NullString
+		var dueAt sql.NullTime
+		err := rows.Scan(&post.ID, &post.Title, &post.UserID, &post.Username, &post.CategoryID, &post.CategoryName,
+			&post.Status, &post.IsSolved, &post.CreatedAt, &level, &dueAt)
+		if err != nil {
+			continue
+		}
+		scanPostContext(&post, level, dueAt)
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
@@ -0,0 +1,244 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"aide-devoir-forum/models"
+)
+
+// === DÉPLACEMENT, FUSION ET DOUBLONS ===
+
+// notMergedPostSQL exclut (posts sous l'alias "p") les doublons fusionnés,
+// qui ne subsistent que pour rediriger les anciens liens
+const notMergedPostSQL = "p.merged_into_id IS NULL"
+
+// listedPostSQL exclut en plus des listes par défaut et de la recherche les posts
+// marqués comme doublons (ils restent accessibles par leur lien et sur le profil de l'auteur)
+// et les posts programmés pas encore publiés
+const listedPostSQL = notMergedPostSQL + " AND p.duplicate_of_id IS NULL AND " + publishedPostSQL
+
+// MovePost déplace un post vers une autre catégorie, avec les agrégats de classement
+// de ses réponses et votes
+func (r *Repository) MovePost(postID, categoryID int) error {
+	var previousID int
+	if err := r.db.QueryRow("SELECT category_id FROM posts WHERE id = ?", postID).Scan(&previousID); err != nil {
+		return err
+	}
+
+	if _, err := r.db.Exec("UPDATE posts SET category_id = ? WHERE id = ?", categoryID, postID); err != nil {
+		return err
+	}
+	return r.rebuildCategoryLeaderboardStats(previousID, categoryID)
+}
+
+// MergePost fusionne un doublon dans le post canonique : commentaires, votes et images
+// sont transférés, puis le doublon est fermé et ne sert plus qu'à rediriger.
+// Un utilisateur ayant voté sur les deux posts ne garde que son vote sur le post canonique.
+// La prime en cours du doublon est remboursée et son sondage supprimé. Hors mode
+// multi-solutions, les solutions du doublon ne sont conservées que si le post canonique
+// n'en a pas et qu'il n'y en a qu'une.
+// Les tables étant en MyISAM, la fusion se fait sans transaction : chaque étape peut
+// être rejouée et le doublon n'est marqué fusionné qu'à la fin, si bien qu'une fusion
+// interrompue se termine en la relançant.
+func (r *Repository) MergePost(sourceID, targetID int) error {
+	if sourceID == targetID {
+		return fmt.Errorf("un post ne peut pas être fusionné avec lui-même")
+	}
+
+	for _, id := range []int{sourceID, targetID} {
+		var count int
+		err := r.db.QueryRow("SELECT COUNT(*) FROM posts WHERE id = ? AND merged_into_id IS NOT NULL", id).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("le post #%d a déjà été fusionné", id)
+		}
+	}
+
+	var multiple bool
+	var targetCategoryID, targetSolutions int
+	err := r.db.QueryRow(`
+		SELECT c.allow_multiple_solutions, p.category_id,
+		       (SELECT COUNT(*) FROM comments WHERE post_id = p.id AND is_solution = TRUE)
+		FROM posts p
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.id = ?
+	`, targetID).Scan(&multiple, &targetCategoryID, &targetSolutions)
+	if err != nil {
+		return err
+	}
+
+	var sourceCategoryID int
+	if err := r.db.QueryRow("SELECT category_id FROM posts WHERE id = ?", sourceID).Scan(&sourceCategoryID); err != nil {
+		return err
+	}
+
+	sourceSolutions, err := r.solutionCandidates(sourceID, 0)
+	if err != nil {
+		return err
+	}
+	keepSolutions := multiple || (targetSolutions == 0 && len(sourceSolutions) <= 1)
+
+	if err := r.RefundOpenBounty(sourceID); err != nil {
+		return err
+	}
+
+	// Les solutions retirées ne rapportent plus de réputation à leurs auteurs.
+	// L'annulation précède le transfert des réponses, qui efface leur marque de solution.
+	if !keepSolutions {
+		for _, solution := range sourceSolutions {
+			if err := r.reverseReputation(models.ReputationSolutionAccepted, "comment", solution.ID, 0); err != nil {
+				return err
+			}
+			r.UpdateUserStats(solution.UserID)
+		}
+	}
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"DELETE FROM poll_votes WHERE poll_id IN (SELECT id FROM polls WHERE post_id = ?)", []interface{}{sourceID}},
+		{"DELETE FROM poll_options WHERE poll_id IN (SELECT id FROM polls WHERE post_id = ?)", []interface{}{sourceID}},
+		{"DELETE FROM polls WHERE post_id = ?", []interface{}{sourceID}},
+		{"UPDATE comments SET post_id = ?, is_solution = is_solution AND ? WHERE post_id = ?", []interface{}{targetID, keepSolutions, sourceID}},
+		{"UPDATE images SET post_id = ? WHERE post_id = ?", []interface{}{targetID, sourceID}},
+		{"UPDATE IGNORE post_votes SET post_id = ? WHERE post_id = ?", []interface{}{targetID, sourceID}},
+		{"DELETE FROM post_votes WHERE post_id = ?", []interface{}{sourceID}},
+		{"UPDATE IGNORE subscriptions SET target_id = ? WHERE target_type = ? AND target_id = ?", []interface{}{targetID, models.SubscriptionPost, sourceID}},
+		{"DELETE FROM subscriptions WHERE target_type = ? AND target_id = ?", []interface{}{models.SubscriptionPost, sourceID}},
+		{"UPDATE bookmarks SET post_id = ? WHERE post_id = ? AND comment_id <> 0", []interface{}{targetID, sourceID}},
+		{`UPDATE posts SET
+			likes_count = (SELECT COUNT(*) FROM post_votes WHERE post_id = ? AND vote_type = 'like'),
+			dislikes_count = (SELECT COUNT(*) FROM post_votes WHERE post_id = ? AND vote_type = 'dislike'),
+			is_solved = (SELECT COUNT(*) FROM comments WHERE post_id = ? AND is_solution = TRUE) > 0
+		  WHERE id = ?`, []interface{}{targetID, targetID, targetID, targetID}},
+	}
+
+	for _, stmt := range statements {
+		if _, err := r.db.Exec(stmt.query, stmt.args...); err != nil {
+			return err
+		}
+	}
+
+	// Les réponses transférées comptent désormais dans la catégorie du post canonique
+	if sourceCategoryID != targetCategoryID {
+		if err := r.rebuildCategoryLeaderboardStats(sourceCategoryID, targetCategoryID); err != nil {
+			return err
+		}
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE posts SET merged_into_id = ?, status = 'closed', is_pinned = FALSE, is_solved = FALSE,
+			likes_count = 0, dislikes_count = 0
+		WHERE id = ?
+	`, targetID, sourceID)
+	return err
+}
+
+// MarkPostDuplicate marque un post comme doublon d'une question canonique et le ferme
+func (r *Repository) MarkPostDuplicate(postID, canonicalID, markedBy int) error {
+	if postID == canonicalID {
+		return fmt.Errorf("un post ne peut pas être le doublon de lui-même")
+	}
+
+	// La question canonique ne doit pas être elle-même un doublon
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM posts
+		WHERE id = ? AND merged_into_id IS NULL AND duplicate_of_id IS NULL
+	`, canonicalID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("la question #%d ne peut pas servir de référence", canonicalID)
+	}
+
+	// Le statut d'avant le premier marquage est conservé pour être rétabli au retrait.
+	// Un nouveau marquage peut être contesté à son tour.
+	_, err = r.db.Exec(`
+		UPDATE posts SET duplicate_previous_status = IF(duplicate_of_id IS NULL, status, duplicate_previous_status),
+		                 duplicate_of_id = ?, duplicate_marked_by = ?, duplicate_contested = FALSE, status = 'closed'
+		WHERE id = ? AND merged_into_id IS NULL
+	`, canonicalID, markedBy, postID)
+	return err
+}
+
+// UnmarkPostDuplicate retire le marquage de doublon et rétablit le statut du post
+// d'avant le marquage. Retourne sql.ErrNoRows si le post n'est pas marqué.
+func (r *Repository) UnmarkPostDuplicate(postID int) error {
+	result, err := r.db.Exec(`
+		UPDATE posts SET status = COALESCE(duplicate_previous_status, status), duplicate_previous_status = NULL,
+		                 duplicate_of_id = NULL, duplicate_marked_by = NULL, duplicate_contested = FALSE
+		WHERE id = ? AND duplicate_of_id IS NOT NULL
+	`, postID)
+	if err != nil {
+		return err
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ContestPostDuplicate enregistre la contestation d'un marquage par l'auteur du post, qui
+// reste marqué jusqu'à la décision d'un modérateur. Celui qui a marqué le post et les
+// modérateurs nommés de la catégorie (à défaut, les modérateurs globaux) sont notifiés.
+// Une seule contestation par marquage : sql.ErrNoRows si elle a déjà été utilisée.
+func (r *Repository) ContestPostDuplicate(postID int) error {
+	result, err := r.db.Exec(`
+		UPDATE posts SET duplicate_contested = TRUE
+		WHERE id = ? AND duplicate_of_id IS NOT NULL AND duplicate_contested = FALSE
+	`, postID)
+	if err != nil {
+		return err
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	rows, err := r.db.Query(`
+		SELECT p.duplicate_marked_by FROM posts p WHERE p.id = ? AND p.duplicate_marked_by IS NOT NULL
+		UNION
+		SELECT cm.user_id FROM category_moderators cm JOIN posts p ON cm.category_id = p.category_id WHERE p.id = ?
+	`, postID, postID)
+	if err != nil {
+		return nil
+	}
+	var recipientIDs []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			recipientIDs = append(recipientIDs, id)
+		}
+	}
+	rows.Close()
+
+	if len(recipientIDs) == 0 {
+		rows, err := r.db.Query("SELECT id FROM users WHERE role_id >= ? AND NOT is_banned", models.RoleModerator)
+		if err != nil {
+			return nil
+		}
+		for rows.Next() {
+			var id int
+			if rows.Scan(&id) == nil {
+				recipientIDs = append(recipientIDs, id)
+			}
+		}
+		rows.Close()
+	}
+
+	for _, recipientID := range recipientIDs {
+		r.CreateNotification(recipientID, models.NotificationDuplicateContested,
+			fmt.Sprintf("L'auteur du post #%d conteste son marquage comme doublon", postID),
+			fmt.Sprintf("/post/%d", postID))
+	}
+	return nil
+}
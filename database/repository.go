@@ -4,12 +4,14 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"aide-devoir-forum/models"
 )
 
 type Repository struct {
-	db *sql.DB
+	db         *sql.DB
+	reputation models.ReputationRules
 }
 
 func NewRepository(db *sql.DB) *Repository {
@@ -24,12 +26,13 @@ func (r *Repository) GetUserByUsername(username string) (*models.User, error) {
 	fmt.Println("user", user)
 	err := r.db.QueryRow(`
 		SELECT u.id, u.username, u.email, u.password, u.role_id, r.name, 
-		       u.is_banned, COALESCE(u.ban_reason, '') as ban_reason, u.created_at
+		       u.is_banned, COALESCE(u.ban_reason, '') as ban_reason, u.created_at,
+		       COALESCE(u.auth_provider, 'local')
 		FROM users u 
 		JOIN roles r ON u.role_id = r.id 
 		WHERE u.username = ?`, username).
 		Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.RoleID, &user.RoleName,
-			&user.IsBanned, &user.BanReason, &user.CreatedAt)
+			&user.IsBanned, &user.BanReason, &user.CreatedAt, &user.AuthProvider)
 	return user, err
 }
 
@@ -54,14 +57,19 @@ func (r *Repository) GetUserByIDComplete(id int) (*models.User, error) {
 		       u.is_banned, COALESCE(u.ban_reason, '') as ban_reason, 
 		       COALESCE(u.avatar, '') as avatar, COALESCE(u.bio, '') as bio,
 		       u.avatar_filename, u.last_login, COALESCE(u.profile_visibility, 'public') as profile_visibility, 
-		       u.date_inscription, u.location, u.created_at
+		       u.date_inscription, u.location, u.created_at, u.reputation,
+		       (SELECT COUNT(*) FROM notifications n WHERE n.user_id = u.id AND n.is_read = FALSE) as unread_notifications,
+		       u.email_digest, u.message_privacy,
+		       (SELECT COUNT(*) FROM messages m JOIN conversations cv ON m.conversation_id = cv.id
+		        WHERE (cv.user1_id = u.id OR cv.user2_id = u.id) AND m.sender_id <> u.id AND m.read_at IS NULL) as unread_messages
 		FROM users u 
 		JOIN roles r ON u.role_id = r.id 
 		WHERE u.id = ?`, id).
 		Scan(&user.ID, &user.Username, &user.Email, &user.RoleID, &user.RoleName,
 			&user.IsBanned, &user.BanReason, &user.Avatar, &user.Bio,
 			&user.AvatarFilename, &user.LastLogin, &user.ProfileVisibility,
-			&user.DateInscription, &user.Location, &user.CreatedAt)
+			&user.DateInscription, &user.Location, &user.CreatedAt, &user.Reputation,
+			&user.UnreadNotifications, &user.EmailDigest, &user.MessagePrivacy, &user.UnreadMessages)
 
 	if err != nil {
 		return nil, err
@@ -119,6 +127,25 @@ func (r *Repository) PromoteUser(userID, newRoleID int) error {
 }
 
 // UnbanUser débannit un utilisateur
+// GetRoles récupère la liste des rôles
+func (r *Repository) GetRoles() ([]models.Role, error) {
+	rows, err := r.db.Query("SELECT id, name, COALESCE(description, '') FROM roles ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description); err != nil {
+			continue
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
 func (r *Repository) UnbanUser(userID int) error {
 	query := `UPDATE users SET is_banned = FALSE, ban_reason = '', banned_until = NULL WHERE id = ?`
 	_, err := r.db.Exec(query, userID)
@@ -127,14 +154,23 @@ func (r *Repository) UnbanUser(userID int) error {
 
 // === CATEGORIES ===
 
-func (r *Repository) GetCategories() ([]models.Category, error) {
+// GetCategories récupère les catégories visibles par l'utilisateur (nil = visiteur)
+func (r *Repository) GetCategories(viewer *models.User) ([]models.Category, error) {
+	visibility, args := visibleCategorySQL(viewer)
 	rows, err := r.db.Query(`
-		SELECT c.id, c.name, c.description, c.color, c.icon, COUNT(p.id) as post_count
+		SELECT c.id, c.name, c.description, c.color, c.icon, COUNT(p.id) as post_count,
+		       c.class_group_id, COALESCE(cg.name, ''),
+		       c.read_min_role, c.post_min_role, c.comment_min_role, c.is_announcement, c.allow_multiple_solutions, c.allow_anonymous,
+		       c.parent_id, c.position
 		FROM categories c
-		LEFT JOIN posts p ON c.id = p.category_id
-		GROUP BY c.id, c.name, c.description, c.color, c.icon
-		ORDER BY c.name
-	`)
+		LEFT JOIN posts p ON c.id = p.category_id AND `+publishedPostSQL+`
+		LEFT JOIN class_groups cg ON c.class_group_id = cg.id
+		WHERE `+visibility+`
+		GROUP BY c.id, c.name, c.description, c.color, c.icon, c.class_group_id, cg.name,
+		         c.read_min_role, c.post_min_role, c.comment_min_role, c.is_announcement, c.allow_multiple_solutions, c.allow_anonymous,
+		         c.parent_id, c.position
+		ORDER BY c.class_group_id IS NOT NULL, c.position, c.name
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -143,44 +179,64 @@ func (r *Repository) GetCategories() ([]models.Category, error) {
 	var categories []models.Category
 	for rows.Next() {
 		var category models.Category
+		var classGroupID, parentID sql.NullInt64
 		err := rows.Scan(&category.ID, &category.Name, &category.Description,
-			&category.Color, &category.Icon, &category.PostCount)
+			&category.Color, &category.Icon, &category.PostCount,
+			&classGroupID, &category.ClassGroupName,
+			&category.ReadMinRole, &category.PostMinRole, &category.CommentMinRole, &category.IsAnnouncement, &category.AllowMultipleSolutions, &category.AllowAnonymous,
+			&parentID, &category.Position)
 		if err != nil {
 			continue
 		}
+		category.ClassGroupID = nullIntPtr(classGroupID)
+		category.ParentID = nullIntPtr(parentID)
 		categories = append(categories, category)
 	}
-	return categories, nil
+	return buildCategoryTree(categories), nil
 }
 
+// GetCategory récupère une catégorie, sans contrôle de visibilité (voir CanViewCategory)
 func (r *Repository) GetCategory(id int) (*models.Category, error) {
 	category := &models.Category{}
+	var classGroupID, parentID sql.NullInt64
 	err := r.db.QueryRow(`
-		SELECT c.id, c.name, c.description, c.color, c.icon, COUNT(p.id) as post_count
+		SELECT c.id, c.name, c.description, c.color, c.icon, COUNT(p.id) as post_count,
+		       c.class_group_id, COALESCE(cg.name, ''),
+		       c.read_min_role, c.post_min_role, c.comment_min_role, c.is_announcement, c.allow_multiple_solutions, c.allow_anonymous,
+		       c.parent_id, c.position
 		FROM categories c
-		LEFT JOIN posts p ON c.id = p.category_id
+		LEFT JOIN posts p ON c.id = p.category_id AND `+publishedPostSQL+`
+		LEFT JOIN class_groups cg ON c.class_group_id = cg.id
 		WHERE c.id = ?
-		GROUP BY c.id, c.name, c.description, c.color, c.icon
+		GROUP BY c.id, c.name, c.description, c.color, c.icon, c.class_group_id, cg.name,
+		         c.read_min_role, c.post_min_role, c.comment_min_role, c.is_announcement, c.allow_multiple_solutions, c.allow_anonymous,
+		         c.parent_id, c.position
 	`, id).Scan(&category.ID, &category.Name, &category.Description,
-		&category.Color, &category.Icon, &category.PostCount)
+		&category.Color, &category.Icon, &category.PostCount,
+		&classGroupID, &category.ClassGroupName,
+		&category.ReadMinRole, &category.PostMinRole, &category.CommentMinRole, &category.IsAnnouncement, &category.AllowMultipleSolutions, &category.AllowAnonymous,
+		&parentID, &category.Position)
+	category.ClassGroupID = nullIntPtr(classGroupID)
+	category.ParentID = nullIntPtr(parentID)
 	return category, err
 }
 
 // === POSTS ===
 
-func (r *Repository) GetRecentPosts(limit int) ([]models.Post, error) {
+func (r *Repository) GetRecentPosts(limit int, viewer *models.User) ([]models.Post, error) {
+	visibility, args := visibleCategorySQL(viewer)
 	rows, err := r.db.Query(`
-		SELECT p.id, p.title, LEFT(p.content, 200) as content, p.user_id, u.username, r.name as role_name, u.is_banned, u.avatar_filename,
+		SELECT p.id, p.title, LEFT(p.content, 200) as content, `+postAuthorSQL+`, `+postAvatarSQL+`, p.is_anonymous,
 		       p.category_id, c.name as category_name, p.status, p.is_solved, p.is_pinned, p.is_locked,
-		       p.views_count, p.likes_count, p.dislikes_count, p.created_at
+		       p.views_count, p.likes_count, p.dislikes_count, p.created_at, p.level, p.due_at
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 		JOIN roles r ON u.role_id = r.id
 		JOIN categories c ON p.category_id = c.id
-		WHERE p.status != 'archived'
+		WHERE p.status != 'archived' AND `+listedPostSQL+` AND `+visibility+`
 		ORDER BY p.created_at DESC 
 		LIMIT ?
-	`, limit)
+	`, append(args, limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -189,13 +245,15 @@ func (r *Repository) GetRecentPosts(limit int) ([]models.Post, error) {
 	var posts []models.Post
 	for rows.Next() {
 		var post models.Post
-		var avatarFilename sql.NullString
-		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.UserRole, &post.UserBanned, &avatarFilename,
+		var avatarFilename, level sql.NullString
+		var dueAt sql.NullTime
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.UserRole, &post.UserBanned, &avatarFilename, &post.IsAnonymous,
 			&post.CategoryID, &post.CategoryName, &post.Status, &post.IsSolved, &post.IsPinned, &post.IsLocked,
-			&post.ViewsCount, &post.LikesCount, &post.DislikesCount, &post.CreatedAt)
+			&post.ViewsCount, &post.LikesCount, &post.DislikesCount, &post.CreatedAt, &level, &dueAt)
 		if err != nil {
 			continue
 		}
+		scanPostContext(&post, level, dueAt)
 
 		// Calculer l'URL de l'avatar
 		if avatarFilename.Valid {
@@ -207,18 +265,23 @@ func (r *Repository) GetRecentPosts(limit int) ([]models.Post, error) {
 	return posts, nil
 }
 
-func (r *Repository) GetPostsByCategory(categoryID int) ([]models.Post, error) {
+// GetPostsByCategory récupère les posts d'une catégorie (aucun si elle n'est pas visible par l'utilisateur),
+// filtrés par niveau et date de rendu
+func (r *Repository) GetPostsByCategory(categoryID int, viewer *models.User, filter models.PostFilter) ([]models.Post, error) {
+	visibility, args := visibleCategorySQL(viewer)
+	filterSQL, filterArgs := postFilterSQL(filter)
+	args = append(append([]interface{}{categoryID}, args...), filterArgs...)
 	rows, err := r.db.Query(`
-		SELECT p.id, p.title, LEFT(p.content, 200) as content, p.user_id, u.username, r.name as role_name, u.is_banned, u.avatar_filename,
+		SELECT p.id, p.title, LEFT(p.content, 200) as content, `+postAuthorSQL+`, `+postAvatarSQL+`, p.is_anonymous,
 		       p.category_id, c.name as category_name, p.status, p.is_solved, p.is_pinned, p.is_locked,
-		       p.views_count, p.likes_count, p.dislikes_count, p.created_at
+		       p.views_count, p.likes_count, p.dislikes_count, p.created_at, p.level, p.due_at
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 		JOIN roles r ON u.role_id = r.id
 		JOIN categories c ON p.category_id = c.id
-		WHERE p.category_id = ? AND p.status != 'archived'
-		ORDER BY p.is_pinned DESC, p.created_at DESC
-	`, categoryID)
+		WHERE p.category_id = ? AND p.status != 'archived' AND `+listedPostSQL+` AND `+visibility+` AND `+filterSQL+`
+		ORDER BY `+postOrderSQL(filter.Sort)+`
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -227,13 +290,15 @@ func (r *Repository) GetPostsByCategory(categoryID int) ([]models.Post, error) {
 	var posts []models.Post
 	for rows.Next() {
 		var post models.Post
-		var avatarFilename sql.NullString
-		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.UserRole, &post.UserBanned, &avatarFilename,
+		var avatarFilename, level sql.NullString
+		var dueAt sql.NullTime
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.UserRole, &post.UserBanned, &avatarFilename, &post.IsAnonymous,
 			&post.CategoryID, &post.CategoryName, &post.Status, &post.IsSolved, &post.IsPinned, &post.IsLocked,
-			&post.ViewsCount, &post.LikesCount, &post.DislikesCount, &post.CreatedAt)
+			&post.ViewsCount, &post.LikesCount, &post.DislikesCount, &post.CreatedAt, &level, &dueAt)
 		if err != nil {
 			continue
 		}
+		scanPostContext(&post, level, dueAt)
 
 		// Calculer l'URL de l'avatar
 		if avatarFilename.Valid {
@@ -245,25 +310,42 @@ func (r *Repository) GetPostsByCategory(categoryID int) ([]models.Post, error) {
 	return posts, nil
 }
 
+// GetPost récupère un post complet. Un post d'une catégorie privée que l'utilisateur
+// ne peut pas voir est traité comme inexistant (sql.ErrNoRows).
 func (r *Repository) GetPost(id int, user *models.User) (*models.Post, error) {
 	post := &models.Post{}
-	var avatarFilename sql.NullString
+	var avatarFilename, level sql.NullString
+	var mergedIntoID, duplicateOfID sql.NullInt64
+	var dueAt, publishAt sql.NullTime
+	visibility, args := visibleCategorySQL(user)
 	err := r.db.QueryRow(`
-		SELECT p.id, p.title, p.content, p.user_id, u.username, r.name as role_name, u.is_banned, u.avatar_filename,
+		SELECT p.id, p.title, p.content, p.user_id, u.username, r.name as role_name, u.is_banned, u.avatar_filename, p.is_anonymous,
 		       p.category_id, c.name as category_name, p.status, p.is_solved, p.is_pinned, p.is_locked,
-		       p.views_count, p.likes_count, p.dislikes_count, p.created_at
+		       p.views_count, p.likes_count, p.dislikes_count, p.created_at, p.merged_into_id,
+		       p.duplicate_of_id, COALESCE(dp.title, ''), p.duplicate_contested, u.reputation, p.level, p.due_at,
+		       p.publish_at
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 		JOIN roles r ON u.role_id = r.id
 		JOIN categories c ON p.category_id = c.id
-		WHERE p.id = ?
-	`, id).Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.UserRole, &post.UserBanned, &avatarFilename,
+		LEFT JOIN posts dp ON p.duplicate_of_id = dp.id
+		WHERE p.id = ? AND `+visibility+`
+	`, append([]interface{}{id}, args...)...).Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.UserRole, &post.UserBanned, &avatarFilename, &post.IsAnonymous,
 		&post.CategoryID, &post.CategoryName, &post.Status, &post.IsSolved, &post.IsPinned, &post.IsLocked,
-		&post.ViewsCount, &post.LikesCount, &post.DislikesCount, &post.CreatedAt)
+		&post.ViewsCount, &post.LikesCount, &post.DislikesCount, &post.CreatedAt, &mergedIntoID,
+		&duplicateOfID, &post.DuplicateOfTitle, &post.DuplicateContested, &post.UserReputation, &level, &dueAt,
+		&publishAt)
 
 	if err != nil {
 		return nil, err
 	}
+	scanPostContext(post, level, dueAt)
+	if publishAt.Valid {
+		at := publishAt.Time
+		post.PublishAt = &at
+	}
+	post.MergedIntoID = nullIntPtr(mergedIntoID)
+	post.DuplicateOfID = nullIntPtr(duplicateOfID)
 
 	// Calculer l'URL de l'avatar
 	if avatarFilename.Valid {
@@ -289,12 +371,26 @@ func (r *Repository) GetPost(id int, user *models.User) (*models.Post, error) {
 	return post, nil
 }
 
-func (r *Repository) CreatePost(title, content string, userID, categoryID int) (int64, error) {
-	result, err := r.db.Exec("INSERT INTO posts (title, content, user_id, category_id) VALUES (?, ?, ?, ?)",
-		title, content, userID, categoryID)
+// CreatePost crée un post ; le niveau ("" = non précisé) et la date de rendu (nil) sont facultatifs.
+// Avec une date de publication (publishAt), le post reste programmé jusqu'à cette date.
+// Un post anonyme (isAnonymous) n'affiche pas le nom de son auteur.
+func (r *Repository) CreatePost(title, content string, userID, categoryID int, level string, dueAt, publishAt *time.Time, isAnonymous bool) (int64, error) {
+	var levelValue interface{}
+	if level != "" {
+		levelValue = level
+	}
+	status := models.PostStatusOpen
+	if publishAt != nil {
+		status = models.PostStatusScheduled
+	}
+	result, err := r.db.Exec(`
+		INSERT INTO posts (title, content, user_id, category_id, level, due_at, status, publish_at, is_anonymous)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, title, content, userID, categoryID, levelValue, dueAt, status, publishAt, isAnonymous)
 	if err != nil {
 		return 0, err
 	}
+	r.recordContribution(userID)
 	return result.LastInsertId()
 }
 
@@ -303,13 +399,34 @@ func (r *Repository) IncrementPostViews(postID int) error {
 	return err
 }
 
-func (r *Repository) MarkPostAsSolved(postID int) error {
-	_, err := r.db.Exec("UPDATE posts SET is_solved = TRUE WHERE id = ?", postID)
-	return err
-}
-
 func (r *Repository) DeletePost(postID int) error {
-	_, err := r.db.Exec("DELETE FROM posts WHERE id = ?", postID)
+	// Annuler la réputation gagnée ou perdue sur le post et ses réponses
+	r.reverseContentReputation("post", postID)
+	rows, err := r.db.Query("SELECT id FROM comments WHERE post_id = ?", postID)
+	if err == nil {
+		var commentIDs []int
+		for rows.Next() {
+			var commentID int
+			if rows.Scan(&commentID) == nil {
+				commentIDs = append(commentIDs, commentID)
+			}
+		}
+		rows.Close()
+		for _, commentID := range commentIDs {
+			r.reverseContentReputation("comment", commentID)
+		}
+	}
+
+	// Une prime encore en cours est perdue : la modération la rembourse avant de supprimer
+	// la question (RefundOpenBounty)
+	r.db.Exec("UPDATE post_bounties SET status = ?, resolved_at = NOW() WHERE post_id = ? AND status = ?",
+		models.BountyExpired, postID, models.BountyOpen)
+
+	r.deletePostPoll(postID)
+	r.db.Exec("DELETE FROM subscriptions WHERE target_type = ? AND target_id = ?", models.SubscriptionPost, postID)
+	r.db.Exec("DELETE FROM bookmarks WHERE post_id = ?", postID)
+
+	_, err = r.db.Exec("DELETE FROM posts WHERE id = ?", postID)
 	return err
 }
 
@@ -320,27 +437,29 @@ func (r *Repository) GetComments(postID int, user *models.User) ([]models.Commen
 }
 
 func (r *Repository) GetCommentsWithSort(postID int, user *models.User, sortBy string) ([]models.Comment, error) {
-	// Définir l'ordre SQL selon le type de tri
+	// Définir l'ordre SQL selon le type de tri (réponses validées par un professeur, puis solutions)
 	var orderClause string
 	switch sortBy {
 	case "oldest":
-		orderClause = "ORDER BY c.is_solution DESC, c.created_at ASC"
+		orderClause = "ORDER BY c.verified_by IS NOT NULL DESC, c.is_solution DESC, c.created_at ASC"
 	case "newest":
-		orderClause = "ORDER BY c.is_solution DESC, c.created_at DESC"
+		orderClause = "ORDER BY c.verified_by IS NOT NULL DESC, c.is_solution DESC, c.created_at DESC"
 	case "most_liked":
-		orderClause = "ORDER BY c.is_solution DESC, c.likes_count DESC, c.created_at DESC"
+		orderClause = "ORDER BY c.verified_by IS NOT NULL DESC, c.is_solution DESC, c.likes_count DESC, c.created_at DESC"
 	case "solutions_first":
-		orderClause = "ORDER BY c.is_solution DESC, c.likes_count DESC, c.created_at ASC"
+		orderClause = "ORDER BY c.verified_by IS NOT NULL DESC, c.is_solution DESC, c.likes_count DESC, c.created_at ASC"
 	default:
-		orderClause = "ORDER BY c.is_solution DESC, c.created_at DESC"
+		orderClause = "ORDER BY c.verified_by IS NOT NULL DESC, c.is_solution DESC, c.created_at DESC"
 	}
 
 	query := fmt.Sprintf(`
 		SELECT c.id, c.post_id, c.content, c.user_id, u.username, r.name as role_name, u.is_banned, u.avatar_filename,
-		       COALESCE(c.parent_id, 0) as parent_id, c.is_solution, c.likes_count, c.dislikes_count, c.created_at
+		       COALESCE(c.parent_id, 0) as parent_id, c.is_solution, c.likes_count, c.dislikes_count, c.created_at,
+		       c.verified_by, vu.username, c.verified_at, u.reputation, c.is_anonymous
 		FROM comments c
 		JOIN users u ON c.user_id = u.id
 		JOIN roles r ON u.role_id = r.id
+		LEFT JOIN users vu ON c.verified_by = vu.id
 		WHERE c.post_id = ?
 		%s
 	`, orderClause)
@@ -356,19 +475,30 @@ func (r *Repository) GetCommentsWithSort(postID int, user *models.User, sortBy s
 
 	for rows.Next() {
 		var comment models.Comment
-		var avatarFilename sql.NullString
+		var avatarFilename, verifierName sql.NullString
+		var verifiedBy sql.NullInt64
+		var verifiedAt sql.NullTime
 		err := rows.Scan(&comment.ID, &comment.PostID, &comment.Content, &comment.UserID,
 			&comment.Username, &comment.UserRole, &comment.UserBanned, &avatarFilename, &comment.ParentID, &comment.IsSolution,
-			&comment.LikesCount, &comment.DislikesCount, &comment.CreatedAt)
+			&comment.LikesCount, &comment.DislikesCount, &comment.CreatedAt,
+			&verifiedBy, &verifierName, &verifiedAt, &comment.UserReputation, &comment.IsAnonymous)
 		if err != nil {
 			continue
 		}
+		scanCommentVerification(&comment, verifiedBy, verifierName, verifiedAt)
 
 		// Calculer l'URL de l'avatar
 		if avatarFilename.Valid {
 			comment.UserAvatarURL = "/uploads/avatars/" + avatarFilename.String
 		}
 
+		// Réponse anonyme : l'auteur n'est visible que de lui-même
+		viewerID := 0
+		if user != nil {
+			viewerID = user.ID
+		}
+		comment.HideAnonymousAuthor(viewerID)
+
 		// Charger le vote utilisateur si connecté
 		if user != nil {
 			var voteType sql.NullString
@@ -424,31 +554,38 @@ func (r *Repository) GetCommentsWithSort(postID int, user *models.User, sortBy s
 }
 
 func (r *Repository) CreateComment(postID int, content string, userID int, parentID *int) error {
-	_, err := r.db.Exec("INSERT INTO comments (post_id, content, user_id, parent_id) VALUES (?, ?, ?, ?)",
-		postID, content, userID, parentID)
+	_, err := r.CreateCommentWithID(postID, content, userID, parentID, false)
 	return err
 }
 
-// CreateCommentWithID crée un commentaire et retourne son ID
-func (r *Repository) CreateCommentWithID(postID int, content string, userID int, parentID *int) (int64, error) {
-	result, err := r.db.Exec("INSERT INTO comments (post_id, content, user_id, parent_id) VALUES (?, ?, ?, ?)",
-		postID, content, userID, parentID)
+// CreateCommentWithID crée un commentaire et retourne son ID ; une réponse anonyme
+// (isAnonymous) n'affiche pas le nom de son auteur
+func (r *Repository) CreateCommentWithID(postID int, content string, userID int, parentID *int, isAnonymous bool) (int64, error) {
+	result, err := r.db.Exec("INSERT INTO comments (post_id, content, user_id, parent_id, is_anonymous) VALUES (?, ?, ?, ?, ?)",
+		postID, content, userID, parentID, isAnonymous)
 	if err != nil {
 		return 0, err
 	}
+	r.recordContribution(userID, models.BadgeMetricAnswers)
 	return result.LastInsertId()
 }
 
-func (r *Repository) MarkCommentAsSolution(commentID int) error {
-	_, err := r.db.Exec("UPDATE comments SET is_solution = TRUE WHERE id = ?", commentID)
-	return err
-}
-
 func (r *Repository) DeleteComment(commentID int) error {
+	// Annuler la réputation gagnée ou perdue sur la réponse
+	r.reverseContentReputation("comment", commentID)
+	r.db.Exec("DELETE FROM bookmarks WHERE comment_id = ?", commentID)
+
 	_, err := r.db.Exec("DELETE FROM comments WHERE id = ?", commentID)
 	return err
 }
 
+// GetCommentPostID retourne la question d'une réponse
+func (r *Repository) GetCommentPostID(commentID int) (int, error) {
+	var postID int
+	err := r.db.QueryRow("SELECT post_id FROM comments WHERE id = ?", commentID).Scan(&postID)
+	return postID, err
+}
+
 // === TAGS ===
 
 func (r *Repository) GetPostTags(postID int) ([]models.Tag, error) {
@@ -522,8 +659,26 @@ func (r *Repository) VotePost(postID, userID int, voteType string) error {
 		return err
 	}
 
+	// Répercuter le vote sur la réputation de l'auteur
+	newVote := voteType
+	if existingVote == voteType {
+		newVote = ""
+	}
+	var authorID int
+	if err := r.db.QueryRow("SELECT user_id FROM posts WHERE id = ?", postID).Scan(&authorID); err == nil {
+		r.recordVoteReputation("post", postID, authorID, userID, existingVote, newVote)
+	}
+
 	// Mettre à jour les compteurs
-	return r.UpdatePostVoteCounts(postID)
+	if err := r.UpdatePostVoteCounts(postID); err != nil {
+		return err
+	}
+
+	// Badges de likes reçus
+	if newVote == models.VoteLike && authorID > 0 {
+		r.EvaluateBadges(authorID, models.BadgeMetricLikes)
+	}
+	return nil
 }
 
 func (r *Repository) VoteComment(commentID, userID int, voteType string) error {
@@ -553,8 +708,26 @@ func (r *Repository) VoteComment(commentID, userID int, voteType string) error {
 		return err
 	}
 
+	// Répercuter le vote sur la réputation de l'auteur
+	newVote := voteType
+	if existingVote == voteType {
+		newVote = ""
+	}
+	var authorID int
+	if err := r.db.QueryRow("SELECT user_id FROM comments WHERE id = ?", commentID).Scan(&authorID); err == nil {
+		r.recordVoteReputation("comment", commentID, authorID, userID, existingVote, newVote)
+	}
+
 	// Mettre à jour les compteurs
-	return r.UpdateCommentVoteCounts(commentID)
+	if err := r.UpdateCommentVoteCounts(commentID); err != nil {
+		return err
+	}
+
+	// Badges de likes reçus
+	if newVote == models.VoteLike && authorID > 0 {
+		r.EvaluateBadges(authorID, models.BadgeMetricLikes)
+	}
+	return nil
 }
 
 func (r *Repository) UpdatePostVoteCounts(postID int) error {
@@ -601,18 +774,19 @@ func (r *Repository) GetPostAuthorID(postID int) (int, error) {
 
 // === RECHERCHE ===
 
-func (r *Repository) SearchPosts(query string, categoryID int, limit int) ([]models.Post, error) {
-	return r.SearchPostsAdvanced(query, categoryID, limit)
+func (r *Repository) SearchPosts(query string, categoryID int, limit int, viewer *models.User, filter models.PostFilter) ([]models.Post, error) {
+	return r.SearchPostsAdvanced(query, categoryID, limit, viewer, filter)
 }
 
-// SearchPostsAdvanced effectue une recherche intelligente par titre et/ou tags
-func (r *Repository) SearchPostsAdvanced(query string, categoryID int, limit int) ([]models.Post, error) {
-	if query == "" {
+// SearchPostsAdvanced effectue une recherche intelligente par titre et/ou tags,
+// filtrée par niveau et date de rendu
+func (r *Repository) SearchPostsAdvanced(query string, categoryID int, limit int, viewer *models.User, filter models.PostFilter) ([]models.Post, error) {
+	if query == "" && filter.IsEmpty() {
 		// Si pas de recherche, retourner les posts récents
 		if categoryID > 0 {
-			return r.GetPostsByCategory(categoryID)
+			return r.GetPostsByCategory(categoryID, viewer, filter)
 		}
-		return r.GetRecentPosts(limit)
+		return r.GetRecentPosts(limit, viewer)
 	}
 
 	// Analyser la requête pour détecter les tags (#hashtag)
@@ -655,22 +829,32 @@ func (r *Repository) SearchPostsAdvanced(query string, categoryID int, limit int
 		args = append(args, categoryID)
 	}
 
-	// Exclure les posts archivés des résultats publics
-	whereClause = append(whereClause, "p.status != 'archived'")
+	// Exclure les posts archivés et fusionnés des résultats publics
+	whereClause = append(whereClause, "p.status != 'archived'", listedPostSQL)
+
+	// Filtrer par niveau scolaire et date de rendu
+	filterSQL, filterArgs := postFilterSQL(filter)
+	whereClause = append(whereClause, filterSQL)
+	args = append(args, filterArgs...)
+
+	// Exclure les catégories privées des classes dont l'utilisateur n'est pas membre
+	visibility, visibilityArgs := visibleCategorySQL(viewer)
+	whereClause = append(whereClause, visibility)
+	args = append(args, visibilityArgs...)
 
 	// Construire la requête finale
 	joinSQL := baseJoin + strings.Join(joins, " ")
 	whereSQL := "WHERE " + strings.Join(whereClause, " AND ")
 
 	query = fmt.Sprintf(`
-		SELECT DISTINCT p.id, p.title, LEFT(p.content, 200) as content, p.user_id, u.username, r.name as role_name, u.is_banned,
+		SELECT DISTINCT p.id, p.title, LEFT(p.content, 200) as content, `+postAuthorSQL+`, p.is_anonymous,
 		       p.category_id, c.name as category_name, p.status, p.is_solved, p.is_pinned, p.is_locked,
-		       p.views_count, p.likes_count, p.dislikes_count, p.created_at
+		       p.views_count, p.likes_count, p.dislikes_count, p.created_at, p.level, p.due_at
 		%s
 		%s
-		ORDER BY p.is_pinned DESC, p.created_at DESC 
+		ORDER BY %s
 		LIMIT ?
-	`, joinSQL, whereSQL)
+	`, joinSQL, whereSQL, postOrderSQL(filter.Sort))
 
 	args = append(args, limit)
 
@@ -683,12 +867,15 @@ func (r *Repository) SearchPostsAdvanced(query string, categoryID int, limit int
 	var posts []models.Post
 	for rows.Next() {
 		var post models.Post
-		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.UserRole, &post.UserBanned,
+		var level sql.NullString
+		var dueAt sql.NullTime
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.UserRole, &post.UserBanned, &post.IsAnonymous,
 			&post.CategoryID, &post.CategoryName, &post.Status, &post.IsSolved, &post.IsPinned, &post.IsLocked,
-			&post.ViewsCount, &post.LikesCount, &post.DislikesCount, &post.CreatedAt)
+			&post.ViewsCount, &post.LikesCount, &post.DislikesCount, &post.CreatedAt, &level, &dueAt)
 		if err != nil {
 			continue
 		}
+		scanPostContext(&post, level, dueAt)
 
 		// Charger les tags pour chaque post
 		post.Tags, _ = r.GetPostTags(post.ID)
@@ -728,7 +915,7 @@ func (r *Repository) GetPopularTags(limit int) ([]models.Tag, error) {
 		FROM tags t
 		JOIN post_tags pt ON t.id = pt.tag_id
 		JOIN posts p ON pt.post_id = p.id
-		WHERE p.status != 'archived'
+		WHERE p.status != 'archived' AND `+publishedPostSQL+`
 		GROUP BY t.id, t.name, t.color
 		ORDER BY usage_count DESC
 		LIMIT ?
@@ -752,21 +939,24 @@ func (r *Repository) GetPopularTags(limit int) ([]models.Tag, error) {
 }
 
 // SearchSuggestions retourne des suggestions de recherche
-func (r *Repository) SearchSuggestions(query string, limit int) ([]string, error) {
+func (r *Repository) SearchSuggestions(query string, limit int, viewer *models.User) ([]string, error) {
 	if len(query) < 2 {
 		return []string{}, nil
 	}
 
 	var suggestions []string
+	visibility, visibilityArgs := visibleCategorySQL(viewer)
 
 	// Suggestions de titres
+	args := append([]interface{}{"%" + query + "%"}, visibilityArgs...)
 	rows, err := r.db.Query(`
 		SELECT DISTINCT p.title
 		FROM posts p
-		WHERE p.title LIKE ? AND p.status != 'archived'
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.title LIKE ? AND p.status != 'archived' AND `+listedPostSQL+` AND `+visibility+`
 		ORDER BY p.views_count DESC, p.created_at DESC
 		LIMIT ?
-	`, "%"+query+"%", limit/2)
+	`, append(args, limit/2)...)
 
 	if err == nil {
 		defer rows.Close()
@@ -784,7 +974,7 @@ func (r *Repository) SearchSuggestions(query string, limit int) ([]string, error
 		FROM tags t
 		JOIN post_tags pt ON t.id = pt.tag_id
 		JOIN posts p ON pt.post_id = p.id
-		WHERE t.name LIKE ? AND p.status != 'archived'
+		WHERE t.name LIKE ? AND p.status != 'archived' AND `+publishedPostSQL+`
 		ORDER BY (SELECT COUNT(*) FROM post_tags pt2 WHERE pt2.tag_id = t.id) DESC
 		LIMIT ?
 	`, "%"+query+"%", limit/2)
@@ -901,10 +1091,19 @@ func (r *Repository) GetPostsWithAllStatuses(userID int, userRoleID int, categor
 		}
 	}
 
+	// Catégories privées des classes, posts fusionnés
+	visibility, visibilityArgs := visibleCategorySQL(&models.User{ID: userID, RoleID: userRoleID})
+	if whereClause == "" {
+		whereClause = "WHERE " + listedPostSQL + " AND " + visibility
+	} else {
+		whereClause += " AND " + listedPostSQL + " AND " + visibility
+	}
+	args = append(args, visibilityArgs...)
+
 	args = append(args, limit)
 
 	query := fmt.Sprintf(`
-		SELECT p.id, p.title, LEFT(p.content, 200) as content, p.user_id, u.username, r.name as role_name, u.is_banned,
+		SELECT p.id, p.title, LEFT(p.content, 200) as content, `+postAuthorSQL+`, p.is_anonymous,
 		       p.category_id, c.name as category_name, p.status, p.is_solved, p.is_pinned, p.is_locked,
 		       p.views_count, p.likes_count, p.dislikes_count, p.created_at
 		FROM posts p
@@ -925,7 +1124,7 @@ func (r *Repository) GetPostsWithAllStatuses(userID int, userRoleID int, categor
 	var posts []models.Post
 	for rows.Next() {
 		var post models.Post
-		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.UserRole, &post.UserBanned,
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.UserRole, &post.UserBanned, &post.IsAnonymous,
 			&post.CategoryID, &post.CategoryName, &post.Status, &post.IsSolved, &post.IsPinned, &post.IsLocked,
 			&post.ViewsCount, &post.LikesCount, &post.DislikesCount, &post.CreatedAt)
 		if err != nil {
@@ -941,12 +1140,12 @@ func (r *Repository) GetPostsWithAllStatuses(userID int, userRoleID int, categor
 // CreateImage enregistre une nouvelle image en base de données
 func (r *Repository) CreateImage(image *models.Image) error {
 	query := `
-		INSERT INTO images (filename, original_name, content_type, size_bytes, width, height, post_id, comment_id, user_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO images (filename, original_name, content_type, size_bytes, width, height, post_id, comment_id, draft_id, user_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	result, err := r.db.Exec(query,
 		image.Filename, image.OriginalName, image.ContentType, image.SizeBytes,
-		image.Width, image.Height, image.PostID, image.CommentID, image.UserID)
+		image.Width, image.Height, image.PostID, image.CommentID, image.DraftID, image.UserID)
 
 	if err != nil {
 		return err
@@ -1149,7 +1348,7 @@ func (r *Repository) GetUserStats(userID int) (*models.UserStats, error) {
 	query := `
 		SELECT user_id, posts_count, comments_count, solutions_given, solutions_received,
 		       likes_received_posts, likes_received_comments, total_views_posts,
-		       created_at, updated_at
+		       created_at, updated_at, current_streak, longest_streak, last_active_date
 		FROM user_stats
 		WHERE user_id = ?
 	`
@@ -1158,6 +1357,7 @@ func (r *Repository) GetUserStats(userID int) (*models.UserStats, error) {
 		&stats.UserID, &stats.PostsCount, &stats.CommentsCount, &stats.SolutionsGiven,
 		&stats.SolutionsReceived, &stats.LikesReceivedPosts, &stats.LikesReceivedComments,
 		&stats.TotalViewsPosts, &stats.CreatedAt, &stats.UpdatedAt,
+		&stats.CurrentStreak, &stats.LongestStreak, &stats.LastActiveDate,
 	)
 
 	if err != nil {
@@ -1213,21 +1413,25 @@ func (r *Repository) UpdateLastLogin(userID int) error {
 	return err
 }
 
-// GetUserActivity récupère l'activité récente d'un utilisateur
-func (r *Repository) GetUserActivity(userID int, limit int) ([]models.UserActivity, error) {
+// GetUserActivity récupère l'activité récente d'un utilisateur, limitée à ce que le visiteur peut voir
+func (r *Repository) GetUserActivity(userID int, limit int, viewer *models.User) ([]models.UserActivity, error) {
 	var activities []models.UserActivity
+	visibility, visibilityArgs := visibleCategorySQL(viewer)
+	args := append(append([]interface{}{userID}, visibilityArgs...), limit/2)
 
 	// Récupérer les posts récents
 	postQuery := `
 		SELECT 'post' as type, p.id, p.title, LEFT(p.content, 150) as content, 
 		       0 as post_id, '' as post_title, p.created_at
 		FROM posts p
-		WHERE p.user_id = ?
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.user_id = ? AND ` + notMergedPostSQL + ` AND ` + publishedPostSQL + ` AND ` + visibility + `
+		  AND ` + signedContentSQL("p", userID, viewer) + `
 		ORDER BY p.created_at DESC
 		LIMIT ?
 	`
 
-	rows, err := r.db.Query(postQuery, userID, limit/2)
+	rows, err := r.db.Query(postQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -1245,16 +1449,17 @@ func (r *Repository) GetUserActivity(userID int, limit int) ([]models.UserActivi
 
 	// Récupérer les commentaires récents
 	commentQuery := `
-		SELECT 'comment' as type, c.id, '' as title, LEFT(c.content, 150) as content,
-		       c.post_id, p.title as post_title, c.created_at
-		FROM comments c
-		JOIN posts p ON c.post_id = p.id
-		WHERE c.user_id = ?
-		ORDER BY c.created_at DESC
+		SELECT 'comment' as type, cm.id, '' as title, LEFT(cm.content, 150) as content,
+		       cm.post_id, p.title as post_title, cm.created_at
+		FROM comments cm
+		JOIN posts p ON cm.post_id = p.id
+		JOIN categories c ON p.category_id = c.id
+		WHERE cm.user_id = ? AND ` + visibility + ` AND ` + signedContentSQL("cm", userID, viewer) + `
+		ORDER BY cm.created_at DESC
 		LIMIT ?
 	`
 
-	rows2, err := r.db.Query(commentQuery, userID, limit/2)
+	rows2, err := r.db.Query(commentQuery, args...)
 	if err != nil {
 		return activities, nil // Retourner au moins les posts
 	}
@@ -1278,8 +1483,9 @@ func (r *Repository) GetUserActivity(userID int, limit int) ([]models.UserActivi
 	return activities, nil
 }
 
-// GetUserPosts récupère les posts d'un utilisateur
-func (r *Repository) GetUserPosts(userID int, limit int) ([]models.Post, error) {
+// GetUserPosts récupère les posts d'un utilisateur visibles par le visiteur
+func (r *Repository) GetUserPosts(userID int, limit int, viewer *models.User) ([]models.Post, error) {
+	visibility, visibilityArgs := visibleCategorySQL(viewer)
 	query := `
 		SELECT p.id, p.title, LEFT(p.content, 200) as content, p.user_id, u.username,
 		       p.category_id, c.name as category_name, p.status, p.is_solved,
@@ -1287,12 +1493,14 @@ func (r *Repository) GetUserPosts(userID int, limit int) ([]models.Post, error)
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 		JOIN categories c ON p.category_id = c.id
-		WHERE p.user_id = ?
+		WHERE p.user_id = ? AND ` + notMergedPostSQL + ` AND ` + publishedPostSQL + ` AND ` + visibility + `
+		  AND ` + signedContentSQL("p", userID, viewer) + `
 		ORDER BY p.created_at DESC
 		LIMIT ?
 	`
 
-	rows, err := r.db.Query(query, userID, limit)
+	args := append(append([]interface{}{userID}, visibilityArgs...), limit)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -1313,19 +1521,23 @@ func (r *Repository) GetUserPosts(userID int, limit int) ([]models.Post, error)
 	return posts, nil
 }
 
-// GetUserComments récupère les commentaires d'un utilisateur
-func (r *Repository) GetUserComments(userID int, limit int) ([]models.Comment, error) {
+// GetUserComments récupère les commentaires d'un utilisateur visibles par le visiteur
+func (r *Repository) GetUserComments(userID int, limit int, viewer *models.User) ([]models.Comment, error) {
+	visibility, visibilityArgs := visibleCategorySQL(viewer)
 	query := `
-		SELECT c.id, c.post_id, c.content, c.user_id, u.username,
-		       c.is_solution, c.likes_count, c.dislikes_count, c.created_at
-		FROM comments c
-		JOIN users u ON c.user_id = u.id
-		WHERE c.user_id = ?
-		ORDER BY c.created_at DESC
+		SELECT cm.id, cm.post_id, cm.content, cm.user_id, u.username,
+		       cm.is_solution, cm.likes_count, cm.dislikes_count, cm.created_at
+		FROM comments cm
+		JOIN users u ON cm.user_id = u.id
+		JOIN posts p ON cm.post_id = p.id
+		JOIN categories c ON p.category_id = c.id
+		WHERE cm.user_id = ? AND ` + visibility + ` AND ` + signedContentSQL("cm", userID, viewer) + `
+		ORDER BY cm.created_at DESC
 		LIMIT ?
 	`
 
-	rows, err := r.db.Query(query, userID, limit)
+	args := append(append([]interface{}{userID}, visibilityArgs...), limit)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -1348,28 +1560,29 @@ func (r *Repository) GetUserComments(userID int, limit int) ([]models.Comment, e
 
 // UpdateUserStats met à jour les statistiques d'un utilisateur
 func (r *Repository) UpdateUserStats(userID int) error {
+	// Statistiques publiques : les contenus anonymes ne sont pas comptés
 	// Compter les posts
 	var postsCount int
-	r.db.QueryRow("SELECT COUNT(*) FROM posts WHERE user_id = ?", userID).Scan(&postsCount)
+	r.db.QueryRow("SELECT COUNT(*) FROM posts WHERE user_id = ? AND NOT is_anonymous", userID).Scan(&postsCount)
 
 	// Compter les commentaires
 	var commentsCount int
-	r.db.QueryRow("SELECT COUNT(*) FROM comments WHERE user_id = ?", userID).Scan(&commentsCount)
+	r.db.QueryRow("SELECT COUNT(*) FROM comments WHERE user_id = ? AND NOT is_anonymous", userID).Scan(&commentsCount)
 
 	// Compter les solutions données
 	var solutionsGiven int
-	r.db.QueryRow("SELECT COUNT(*) FROM comments WHERE user_id = ? AND is_solution = TRUE", userID).Scan(&solutionsGiven)
+	r.db.QueryRow("SELECT COUNT(*) FROM comments WHERE user_id = ? AND NOT is_anonymous AND is_solution = TRUE", userID).Scan(&solutionsGiven)
 
 	// Compter les solutions reçues (posts résolus)
 	var solutionsReceived int
-	r.db.QueryRow("SELECT COUNT(*) FROM posts WHERE user_id = ? AND is_solved = TRUE", userID).Scan(&solutionsReceived)
+	r.db.QueryRow("SELECT COUNT(*) FROM posts WHERE user_id = ? AND NOT is_anonymous AND is_solved = TRUE", userID).Scan(&solutionsReceived)
 
 	// Compter les likes reçus sur les posts
 	var likesReceivedPosts int
 	r.db.QueryRow(`
 		SELECT COALESCE(SUM(p.likes_count), 0)
 		FROM posts p
-		WHERE p.user_id = ?
+		WHERE p.user_id = ? AND NOT p.is_anonymous
 	`, userID).Scan(&likesReceivedPosts)
 
 	// Compter les likes reçus sur les commentaires
@@ -1377,7 +1590,7 @@ func (r *Repository) UpdateUserStats(userID int) error {
 	r.db.QueryRow(`
 		SELECT COALESCE(SUM(c.likes_count), 0)
 		FROM comments c
-		WHERE c.user_id = ?
+		WHERE c.user_id = ? AND NOT c.is_anonymous
 	`, userID).Scan(&likesReceivedComments)
 
 	// Compter les vues totales des posts
@@ -1385,7 +1598,7 @@ func (r *Repository) UpdateUserStats(userID int) error {
 	r.db.QueryRow(`
 		SELECT COALESCE(SUM(p.views_count), 0)
 		FROM posts p
-		WHERE p.user_id = ?
+		WHERE p.user_id = ? AND NOT p.is_anonymous
 	`, userID).Scan(&totalViewsPosts)
 
 	// Mettre à jour ou insérer
@@ -1462,20 +1675,47 @@ func (r *Repository) GetAdminStats() (models.AdminStats, error) {
 	return stats, nil
 }
 
-func (r *Repository) CreateCategory(name, description, color, icon string) error {
-	_, err := r.db.Exec(`
-		INSERT INTO categories (name, description, color, icon, created_at)
-		VALUES (?, ?, ?, ?, NOW())
-	`, name, description, color, icon)
+func (r *Repository) CreateCategory(name, description, color, icon string, parentID *int, rules models.CategoryRules) error {
+	// La nouvelle catégorie est placée après ses sœurs
+	position, err := r.nextCategoryPosition(parentID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO categories (name, description, color, icon, parent_id, position,
+		                        read_min_role, post_min_role, comment_min_role, is_announcement,
+		                        allow_multiple_solutions, allow_anonymous, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`, name, description, color, icon, parentID, position,
+		rules.ReadMinRole, rules.PostMinRole, rules.CommentMinRole, rules.IsAnnouncement, rules.AllowMultipleSolutions,
+		rules.AllowAnonymous)
 	return err
 }
 
-func (r *Repository) UpdateCategory(id int, name, description, color, icon string) error {
-	_, err := r.db.Exec(`
+func (r *Repository) UpdateCategory(id int, name, description, color, icon string, parentID *int, rules models.CategoryRules) error {
+	current, err := r.GetCategory(id)
+	if err != nil {
+		return err
+	}
+
+	// Une catégorie déplacée sous un autre parent passe après ses nouvelles sœurs
+	position := current.Position
+	if !sameParent(current.ParentID, parentID) {
+		if position, err = r.nextCategoryPosition(parentID); err != nil {
+			return err
+		}
+	}
+
+	_, err = r.db.Exec(`
 		UPDATE categories 
-		SET name = ?, description = ?, color = ?, icon = ?
+		SET name = ?, description = ?, color = ?, icon = ?, parent_id = ?, position = ?,
+		    read_min_role = ?, post_min_role = ?, comment_min_role = ?, is_announcement = ?,
+		    allow_multiple_solutions = ?, allow_anonymous = ?
 		WHERE id = ?
-	`, name, description, color, icon, id)
+	`, name, description, color, icon, parentID, position,
+		rules.ReadMinRole, rules.PostMinRole, rules.CommentMinRole, rules.IsAnnouncement,
+		rules.AllowMultipleSolutions, rules.AllowAnonymous, id)
 	return err
 }
 
@@ -1491,6 +1731,19 @@ func (r *Repository) DeleteCategory(id int) error {
 		return fmt.Errorf("impossible de supprimer une catégorie contenant des posts")
 	}
 
+	// Les sous-catégories ne doivent pas devenir orphelines
+	err = r.db.QueryRow("SELECT COUNT(*) FROM categories WHERE parent_id = ?", id).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return fmt.Errorf("impossible de supprimer une catégorie contenant des sous-catégories")
+	}
+
+	if _, err := r.db.Exec("DELETE FROM category_moderators WHERE category_id = ?", id); err != nil {
+		return err
+	}
 	_, err = r.db.Exec("DELETE FROM categories WHERE id = ?", id)
 	return err
 }
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"aide-devoir-forum/models"
+)
+
+// === RÉPUTATION ===
+
+// SetReputationRules définit les points attribués par événement (voir config.Reputation)
+func (r *Repository) SetReputationRules(rules models.ReputationRules) {
+	r.reputation = rules
+}
+
+// cappedReputationEvents sont les gains soumis au plafond quotidien
+var cappedReputationEvents = []interface{}{models.ReputationPostLiked, models.ReputationCommentLiked}
+
+// awardReputation ajoute un événement au journal et met à jour la réputation de l'utilisateur.
+// Aucun point n'est attribué pour ses propres contenus (actorID = userID). Les gains par likes
+// sont limités au plafond quotidien : l'événement enregistre les points réellement accordés,
+// afin qu'une annulation ultérieure retire exactement ce qui avait été gagné. Le plafond
+// compte les gains du jour sans déduire les annulations : retirer puis remettre un like
+// ne permet pas de le dépasser.
+func (r *Repository) awardReputation(userID, actorID int, eventType, sourceType string, sourceID, points int) error {
+	if userID == actorID || points == 0 {
+		return nil
+	}
+
+	if points > 0 && r.reputation.DailyCap > 0 && isCappedReputationEvent(eventType) {
+		var earnedToday int
+		err := r.db.QueryRow(`
+			SELECT COALESCE(SUM(points), 0) FROM reputation_events
+			WHERE user_id = ? AND created_at >= CURDATE() AND event_type IN (?, ?)
+			  AND reverses_id IS NULL AND points > 0
+		`, append([]interface{}{userID}, cappedReputationEvents...)...).Scan(&earnedToday)
+		if err != nil {
+			return err
+		}
+		if remaining := r.reputation.DailyCap - earnedToday; points > remaining {
+			points = max(remaining, 0)
+		}
+	}
+
+	return r.insertReputationEvent(userID, actorID, eventType, sourceType, sourceID, points)
+}
+
+// insertReputationEvent inscrit un événement au journal, sans règle d'attribution, et
+// l'applique à la réputation de l'utilisateur et aux classements
+func (r *Repository) insertReputationEvent(userID, actorID int, eventType, sourceType string, sourceID, points int) error {
+	var actor interface{}
+	if actorID > 0 {
+		actor = actorID
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO reputation_events (user_id, actor_id, event_type, source_type, source_id, points)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, actor, eventType, sourceType, sourceID, points)
+	if err != nil {
+		return err
+	}
+	r.recordLeaderboardEvent(userID, eventType, sourceType, sourceID, points, 1, nil)
+
+	if points != 0 {
+		_, err = r.db.Exec("UPDATE users SET reputation = reputation + ? WHERE id = ?", points, userID)
+	}
+	return err
+}
+
+func isCappedReputationEvent(eventType string) bool {
+	for _, capped := range cappedReputationEvents {
+		if capped == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// reverseReputation annule les événements encore actifs d'un type pour un contenu
+// (actorID = 0 : quel que soit l'auteur de l'action) en ajoutant des lignes opposées
+func (r *Repository) reverseReputation(eventType, sourceType string, sourceID, actorID int) error {
+	return r.reverseReputationWhere(`e.event_type = ? AND e.source_type = ? AND e.source_id = ? AND (? = 0 OR e.actor_id = ?)`,
+		eventType, sourceType, sourceID, actorID, actorID)
+}
+
+// reverseContentReputation annule tous les gains et pertes liés à un contenu supprimé,
+// hors pénalités de modération et primes (réglées par leurs propres règles)
+func (r *Repository) reverseContentReputation(sourceType string, sourceID int) error {
+	return r.reverseReputationWhere(`e.source_type = ? AND e.source_id = ? AND e.event_type NOT IN (?, ?, ?, ?)`,
+		sourceType, sourceID, models.ReputationModerationPenalty,
+		models.ReputationBountyOffered, models.ReputationBountyAwarded, models.ReputationBountyRefunded)
+}
+
+func (r *Repository) reverseReputationWhere(condition string, args ...interface{}) error {
+	rows, err := r.db.Query(`
+		SELECT e.id, e.user_id, e.actor_id, e.event_type, e.source_type, e.source_id, e.points, e.created_at
+		FROM reputation_events e
+		WHERE `+condition+` AND e.reverses_id IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM reputation_events rev WHERE rev.reverses_id = e.id)
+	`, args...)
+	if err != nil {
+		return err
+	}
+
+	var events []models.ReputationEvent
+	for rows.Next() {
+		var event models.ReputationEvent
+		if err := rows.Scan(&event.ID, &event.UserID, &event.ActorID, &event.EventType,
+			&event.SourceType, &event.SourceID, &event.Points, &event.CreatedAt); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+
+	for _, event := range events {
+		_, err := r.db.Exec(`
+			INSERT INTO reputation_events (user_id, actor_id, event_type, source_type, source_id, points, reverses_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, event.UserID, event.ActorID, event.EventType, event.SourceType, event.SourceID, -event.Points, event.ID)
+		if err != nil {
+			return err
+		}
+		r.recordLeaderboardEvent(event.UserID, event.EventType, event.SourceType, event.SourceID, -event.Points, -1, &event.CreatedAt)
+		if event.Points != 0 {
+			if _, err := r.db.Exec("UPDATE users SET reputation = reputation - ? WHERE id = ?", event.Points, event.UserID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recordVoteReputation répercute un changement de vote (like, dislike ou "" pour aucun)
+// sur la réputation de l'auteur du contenu
+func (r *Repository) recordVoteReputation(sourceType string, sourceID, authorID, voterID int, previousVote, newVote string) error {
+	if previousVote == newVote {
+		return nil
+	}
+
+	likeEvent, likePoints := models.ReputationPostLiked, r.reputation.PostLike
+	dislikeEvent := models.ReputationPostDisliked
+	if sourceType == "comment" {
+		likeEvent, likePoints = models.ReputationCommentLiked, r.reputation.CommentLike
+		dislikeEvent = models.ReputationCommentDisliked
+	}
+
+	switch previousVote {
+	case models.VoteLike:
+		if err := r.reverseReputation(likeEvent, sourceType, sourceID, voterID); err != nil {
+			return err
+		}
+	case models.VoteDislike:
+		if err := r.reverseReputation(dislikeEvent, sourceType, sourceID, voterID); err != nil {
+			return err
+		}
+	}
+
+	switch newVote {
+	case models.VoteLike:
+		return r.awardReputation(authorID, voterID, likeEvent, sourceType, sourceID, likePoints)
+	case models.VoteDislike:
+		return r.awardReputation(authorID, voterID, dislikeEvent, sourceType, sourceID, r.reputation.DislikeReceived)
+	}
+	return nil
+}
+
+// PenalizeReputation retire des points à l'auteur d'un contenu supprimé par un modérateur
+func (r *Repository) PenalizeReputation(userID, moderatorID int, sourceType string, sourceID int) error {
+	return r.awardReputation(userID, moderatorID, models.ReputationModerationPenalty, sourceType, sourceID,
+		r.reputation.ModerationPenalty)
+}
+
+// GetReputationEvents récupère les derniers événements de réputation d'un utilisateur
+func (r *Repository) GetReputationEvents(userID, limit int) ([]models.ReputationEvent, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, actor_id, event_type, source_type, source_id, points, reverses_id, created_at
+		FROM reputation_events
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.ReputationEvent
+	for rows.Next() {
+		var event models.ReputationEvent
+		if err := rows.Scan(&event.ID, &event.UserID, &event.ActorID, &event.EventType, &event.SourceType,
+			&event.SourceID, &event.Points, &event.ReversesID, &event.CreatedAt); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"aide-devoir-forum/models"
+)
+
+// === PUBLICATION PROGRAMMÉE ===
+
+// publishedPostSQL exclut (posts sous l'alias "p") les posts programmés qui ne sont pas
+// encore publiés : seuls leur auteur et les modérateurs y accèdent, par leur lien
+const publishedPostSQL = "p.status != 'scheduled'"
+
+// GetScheduledPosts récupère les posts programmés d'un utilisateur, les prochains d'abord
+func (r *Repository) GetScheduledPosts(userID int) ([]models.Post, error) {
+	rows, err := r.db.Query(`
+		SELECT p.id, p.title, LEFT(p.content, 200) as content, p.user_id, p.category_id, c.name,
+		       p.status, p.created_at, p.level, p.due_at, p.publish_at
+		FROM posts p
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.user_id = ? AND p.status = ?
+		ORDER BY p.publish_at ASC
+	`, userID, models.PostStatusScheduled)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []models.Post
+	for rows.Next() {
+		var post models.Post
+		var level sql.NullString
+		var dueAt, publishAt sql.NullTime
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.CategoryID, &post.CategoryName,
+			&post.Status, &post.CreatedAt, &level, &dueAt, &publishAt)
+		if err != nil {
+			continue
+		}
+		scanPostContext(&post, level, dueAt)
+		if publishAt.Valid {
+			at := publishAt.Time
+			post.PublishAt = &at
+		}
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// UpdateScheduledPost modifie un post programmé de son auteur et remplace ses tags.
+// Retourne sql.ErrNoRows si le post n'est pas (ou plus) programmé.
+func (r *Repository) UpdateScheduledPost(post *models.Post, tags []string) error {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM posts WHERE id = ? AND user_id = ? AND status = ?",
+		post.ID, post.UserID, models.PostStatusScheduled).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+
+	var levelValue interface{}
+	if post.Level != "" {
+		levelValue = post.Level
+	}
+	_, err := r.db.Exec(`
+		UPDATE posts
+		SET title = ?, content = ?, category_id = ?, level = ?, due_at = ?, publish_at = ?, is_anonymous = ?
+		WHERE id = ? AND user_id = ? AND status = ?
+	`, post.Title, post.Content, post.CategoryID, levelValue, post.DueAt, post.PublishAt, post.IsAnonymous,
+		post.ID, post.UserID, models.PostStatusScheduled)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.db.Exec("DELETE FROM post_tags WHERE post_id = ?", post.ID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		r.AddTagToPost(post.ID, tag)
+	}
+	return nil
+}
+
+// CancelScheduledPost annule la publication d'un post programmé : le post est supprimé et
+// son contenu, images comprises, est conservé dans le brouillon fourni (son sondage est supprimé).
+// Retourne sql.ErrNoRows si le post n'est pas (ou plus) programmé.
+func (r *Repository) CancelScheduledPost(postID int, draft *models.Draft) error {
+	result, err := r.db.Exec("DELETE FROM posts WHERE id = ? AND user_id = ? AND status = ?",
+		postID, draft.UserID, models.PostStatusScheduled)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := r.SaveDraft(draft); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec("UPDATE images SET draft_id = ?, post_id = NULL WHERE post_id = ?", draft.ID, postID); err != nil {
+		return err
+	}
+	if _, err := r.db.Exec("DELETE FROM post_tags WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+	return r.deletePostPoll(postID)
+}
+
+// PublishScheduledPosts publie les posts programmés arrivés à échéance. L'auteur est
+// prévenu, ainsi que les élèves de la classe quand le post est publié dans une catégorie privée,
+// les utilisateurs mentionnés dans le contenu et les abonnés de la catégorie et des tags.
+func (r *Repository) PublishScheduledPosts() error {
+	rows, err := r.db.Query(`
+		SELECT p.id, p.title, p.content, p.user_id, u.username, p.is_anonymous, c.name, c.class_group_id
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.status = ? AND p.publish_at <= NOW()
+	`, models.PostStatusScheduled)
+	if err != nil {
+		return err
+	}
+
+	type duePost struct {
+		models.Post
+		classGroupID sql.NullInt64
+	}
+	var posts []duePost
+	for rows.Next() {
+		var post duePost
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.Username, &post.IsAnonymous,
+			&post.CategoryName, &post.classGroupID); err != nil {
+			continue
+		}
+		posts = append(posts, post)
+	}
+	rows.Close()
+
+	for _, post := range posts {
+		// La date de création devient celle de la publication, pour apparaître en tête des listes
+		result, err := r.db.Exec(`
+			UPDATE posts SET status = ?, created_at = NOW(), publish_at = NULL
+			WHERE id = ? AND status = ?
+		`, models.PostStatusOpen, post.ID, models.PostStatusScheduled)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			continue
+		}
+
+		link := fmt.Sprintf("/post/%d", post.ID)
+		title := shortTitle(post.Title)
+		r.CreateNotification(post.UserID, models.NotificationPostPublished,
+			fmt.Sprintf("Votre publication programmée « %s » est en ligne", title), link)
+
+		classNotified := make(map[int]bool)
+		if post.classGroupID.Valid {
+			members, _ := r.GetClassGroupMembers(int(post.classGroupID.Int64))
+			for _, member := range members {
+				if member.UserID == post.UserID {
+					continue
+				}
+				r.CreateNotification(member.UserID, models.NotificationClassPost,
+					fmt.Sprintf("Nouvelle publication dans %s : « %s »", post.CategoryName, title), link)
+				classNotified[member.UserID] = true
+			}
+		}
+
+		authorName := post.Username
+		if post.IsAnonymous {
+			authorName = models.AnonymousName
+		}
+		r.NotifyMentions(post.UserID, authorName, post.ID, nil, post.Content)
+		r.NotifyPostSubscribers(post.ID, classNotified)
+	}
+	return nil
+}
+
+// shortTitle abrège un titre pour le message d'une notification
+func shortTitle(title string) string {
+	const maxRunes = 100
+	if utf8.RuneCountInString(title) <= maxRunes {
+		return title
+	}
+	return strings.TrimSpace(string([]rune(title)[:maxRunes])) + "…"
+}
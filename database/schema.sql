@@ -19,6 +19,56 @@
 CREATE DATABASE IF NOT EXISTS `forum` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci */ /*!80016 DEFAULT ENCRYPTION='N' */;
 USE `forum`;
 
+-- Listage de la structure de la table forum. api_tokens
+CREATE TABLE IF NOT EXISTS `api_tokens` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `name` varchar(100) COLLATE utf8mb4_general_ci NOT NULL,
+  `token_prefix` varchar(16) COLLATE utf8mb4_general_ci NOT NULL,
+  `token_hash` char(64) COLLATE utf8mb4_general_ci NOT NULL,
+  `scopes` varchar(255) COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'read',
+  `expires_at` timestamp NULL DEFAULT NULL,
+  `last_used_at` timestamp NULL DEFAULT NULL,
+  `last_used_ip` varchar(45) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  `revoked_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `token_hash` (`token_hash`),
+  KEY `idx_api_tokens_user` (`user_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. bookmark_collections
+CREATE TABLE IF NOT EXISTS `bookmark_collections` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `name` varchar(100) COLLATE utf8mb4_general_ci NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unique_collection_name` (`user_id`,`name`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. bookmarks
+CREATE TABLE IF NOT EXISTS `bookmarks` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `post_id` int NOT NULL,
+  `comment_id` int NOT NULL DEFAULT '0',
+  `collection_id` int DEFAULT NULL,
+  `note` text COLLATE utf8mb4_general_ci,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unique_bookmark` (`user_id`,`post_id`,`comment_id`),
+  KEY `idx_bookmarks_collection` (`collection_id`),
+  KEY `idx_bookmarks_post` (`post_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
 -- Listage de la structure de la table forum. categories
 CREATE TABLE IF NOT EXISTS `categories` (
   `id` int NOT NULL AUTO_INCREMENT,
@@ -27,8 +77,19 @@ CREATE TABLE IF NOT EXISTS `categories` (
   `color` varchar(7) COLLATE utf8mb4_general_ci DEFAULT '#007bff',
   `icon` varchar(50) COLLATE utf8mb4_general_ci DEFAULT 'book',
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `class_group_id` int DEFAULT NULL,
+  `read_min_role` int NOT NULL DEFAULT '0',
+  `post_min_role` int NOT NULL DEFAULT '1',
+  `comment_min_role` int NOT NULL DEFAULT '1',
+  `is_announcement` tinyint(1) NOT NULL DEFAULT '0',
+  `allow_multiple_solutions` tinyint(1) NOT NULL DEFAULT '0',
+  `allow_anonymous` tinyint(1) NOT NULL DEFAULT '0',
+  `parent_id` int DEFAULT NULL,
+  `position` int NOT NULL DEFAULT '0',
   PRIMARY KEY (`id`),
-  UNIQUE KEY `name` (`name`)
+  UNIQUE KEY `name` (`name`),
+  KEY `idx_categories_class_group` (`class_group_id`),
+  KEY `idx_categories_parent` (`parent_id`,`position`)
 ) ENGINE=MyISAM AUTO_INCREMENT=8 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
 
 -- Listage des données de la table forum.categories : 7 rows
@@ -44,6 +105,45 @@ INSERT INTO `categories` (`id`, `name`, `description`, `color`, `icon`, `created
 	(7, 'Philosophie', 'Réflexions philosophiques', '#6c757d', 'lightbulb', '2025-06-14 10:09:43');
 /*!40000 ALTER TABLE `categories` ENABLE KEYS */;
 
+-- Listage de la structure de la table forum. category_moderators
+CREATE TABLE IF NOT EXISTS `category_moderators` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `category_id` int NOT NULL,
+  `user_id` int NOT NULL,
+  `appointed_by` int DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unique_category_moderator` (`category_id`,`user_id`),
+  KEY `idx_category_moderators_user` (`user_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. class_group_members
+CREATE TABLE IF NOT EXISTS `class_group_members` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `class_group_id` int NOT NULL,
+  `user_id` int NOT NULL,
+  `joined_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unique_group_member` (`class_group_id`,`user_id`),
+  KEY `idx_class_group_members_user` (`user_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. class_groups
+CREATE TABLE IF NOT EXISTS `class_groups` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) COLLATE utf8mb4_general_ci NOT NULL,
+  `owner_id` int NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_class_groups_owner` (`owner_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
 -- Listage de la structure de la table forum. comments
 CREATE TABLE IF NOT EXISTS `comments` (
   `id` int NOT NULL AUTO_INCREMENT,
@@ -52,12 +152,16 @@ CREATE TABLE IF NOT EXISTS `comments` (
   `user_id` int NOT NULL,
   `parent_id` int DEFAULT NULL,
   `is_solution` tinyint(1) DEFAULT '0',
+  `verified_by` int DEFAULT NULL,
+  `verified_at` timestamp NULL DEFAULT NULL,
+  `is_anonymous` tinyint(1) NOT NULL DEFAULT '0',
   `likes_count` int DEFAULT '0',
   `dislikes_count` int DEFAULT '0',
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   PRIMARY KEY (`id`),
   KEY `parent_id` (`parent_id`),
+  KEY `idx_comments_verified_by` (`verified_by`),
   KEY `idx_comments_post_id` (`post_id`),
   KEY `idx_comments_user_id` (`user_id`)
 ) ENGINE=MyISAM AUTO_INCREMENT=6 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -79,6 +183,46 @@ CREATE TABLE IF NOT EXISTS `comment_votes` (
 
 -- Les données exportées n'étaient pas sélectionnées.
 
+-- Listage de la structure de la table forum. conversations
+CREATE TABLE IF NOT EXISTS `conversations` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user1_id` int NOT NULL,
+  `user2_id` int NOT NULL,
+  `started_by` int NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `last_message_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unique_conversation` (`user1_id`,`user2_id`),
+  KEY `idx_conversations_user2` (`user2_id`),
+  KEY `idx_conversations_started_by` (`started_by`,`created_at`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. drafts
+CREATE TABLE IF NOT EXISTS `drafts` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `kind` enum('post','comment') COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'post',
+  `post_id` int DEFAULT NULL,
+  `parent_id` int DEFAULT NULL,
+  `title` varchar(255) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+  `content` text COLLATE utf8mb4_general_ci,
+  `category_id` int DEFAULT NULL,
+  `tags` varchar(255) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+  `level` varchar(20) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+  `due_at` varchar(16) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+  `publish_at` varchar(16) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_drafts_user` (`user_id`,`updated_at`),
+  KEY `idx_drafts_target` (`user_id`,`post_id`),
+  KEY `idx_drafts_updated` (`updated_at`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
 -- Listage de la structure de la table forum. images
 CREATE TABLE IF NOT EXISTS `images` (
   `id` int NOT NULL AUTO_INCREMENT,
@@ -90,24 +234,90 @@ CREATE TABLE IF NOT EXISTS `images` (
   `height` int DEFAULT '0',
   `post_id` int DEFAULT NULL,
   `comment_id` int DEFAULT NULL,
+  `draft_id` int DEFAULT NULL,
   `user_id` int NOT NULL,
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   PRIMARY KEY (`id`),
   UNIQUE KEY `filename` (`filename`),
   KEY `idx_post_images` (`post_id`),
   KEY `idx_comment_images` (`comment_id`),
+  KEY `idx_draft_images` (`draft_id`),
   KEY `idx_user_images` (`user_id`),
   KEY `idx_filename` (`filename`)
 ) ENGINE=MyISAM AUTO_INCREMENT=4 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
 
 -- Les données exportées n'étaient pas sélectionnées.
 
+-- Listage de la structure de la table forum. invite_codes
+CREATE TABLE IF NOT EXISTS `invite_codes` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `code` varchar(20) COLLATE utf8mb4_general_ci NOT NULL,
+  `created_by` int NOT NULL,
+  `role_id` int NOT NULL DEFAULT '1',
+  `class_group_id` int DEFAULT NULL,
+  `max_uses` int NOT NULL DEFAULT '1',
+  `uses` int NOT NULL DEFAULT '0',
+  `expires_at` timestamp NULL DEFAULT NULL,
+  `revoked_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `code` (`code`),
+  KEY `idx_invite_codes_creator` (`created_by`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. leaderboard_stats
+CREATE TABLE IF NOT EXISTS `leaderboard_stats` (
+  `user_id` int NOT NULL,
+  `category_id` int NOT NULL,
+  `day` date NOT NULL,
+  `solutions` int DEFAULT '0',
+  `likes` int DEFAULT '0',
+  `reputation` int DEFAULT '0',
+  PRIMARY KEY (`user_id`,`category_id`,`day`),
+  KEY `idx_leaderboard_day` (`day`),
+  KEY `idx_leaderboard_category_day` (`category_id`,`day`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. mentions
+CREATE TABLE IF NOT EXISTS `mentions` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `author_id` int NOT NULL,
+  `post_id` int NOT NULL,
+  `comment_id` int NOT NULL DEFAULT '0',
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unique_mention` (`user_id`,`post_id`,`comment_id`),
+  KEY `idx_mentions_author_date` (`author_id`,`created_at`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. messages
+CREATE TABLE IF NOT EXISTS `messages` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `conversation_id` int NOT NULL,
+  `sender_id` int NOT NULL,
+  `content` text COLLATE utf8mb4_general_ci NOT NULL,
+  `read_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_messages_conversation` (`conversation_id`,`created_at`),
+  KEY `idx_messages_unread` (`conversation_id`,`sender_id`,`read_at`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
 -- Listage de la structure de la table forum. moderation_logs
 CREATE TABLE IF NOT EXISTS `moderation_logs` (
   `id` int NOT NULL AUTO_INCREMENT,
   `moderator_id` int NOT NULL,
-  `action_type` enum('ban_user','unban_user','delete_post','delete_comment','lock_post','unlock_post','pin_post','unpin_post','close_post','reopen_post','archive_post','unarchive_post') COLLATE utf8mb4_general_ci NOT NULL,
-  `target_type` enum('user','post','comment') COLLATE utf8mb4_general_ci NOT NULL,
+  `action_type` varchar(50) COLLATE utf8mb4_general_ci NOT NULL,
+  `target_type` enum('user','post','comment','conversation') COLLATE utf8mb4_general_ci NOT NULL,
   `target_id` int NOT NULL,
   `reason` text COLLATE utf8mb4_general_ci,
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
@@ -117,6 +327,136 @@ CREATE TABLE IF NOT EXISTS `moderation_logs` (
 
 -- Les données exportées n'étaient pas sélectionnées.
 
+-- Listage de la structure de la table forum. notifications
+CREATE TABLE IF NOT EXISTS `notifications` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `type` varchar(50) COLLATE utf8mb4_general_ci NOT NULL,
+  `message` varchar(255) COLLATE utf8mb4_general_ci NOT NULL,
+  `link` varchar(255) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  `is_read` tinyint(1) DEFAULT '0',
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_notifications_user_read` (`user_id`,`is_read`),
+  KEY `idx_notifications_user_date` (`user_id`,`created_at`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. oauth_authorization_codes
+CREATE TABLE IF NOT EXISTS `oauth_authorization_codes` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `code_hash` char(64) COLLATE utf8mb4_general_ci NOT NULL,
+  `client_id` varchar(64) COLLATE utf8mb4_general_ci NOT NULL,
+  `user_id` int NOT NULL,
+  `redirect_uri` varchar(500) COLLATE utf8mb4_general_ci NOT NULL,
+  `scopes` varchar(255) COLLATE utf8mb4_general_ci NOT NULL,
+  `code_challenge` varchar(128) COLLATE utf8mb4_general_ci NOT NULL,
+  `code_challenge_method` varchar(10) COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'S256',
+  `nonce` varchar(255) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  `expires_at` timestamp NOT NULL,
+  `used_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `code_hash` (`code_hash`),
+  KEY `idx_oauth_codes_expires` (`expires_at`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. oauth_clients
+CREATE TABLE IF NOT EXISTS `oauth_clients` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `client_id` varchar(64) COLLATE utf8mb4_general_ci NOT NULL,
+  `client_secret_hash` char(64) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  `name` varchar(100) COLLATE utf8mb4_general_ci NOT NULL,
+  `redirect_uris` text COLLATE utf8mb4_general_ci NOT NULL,
+  `is_confidential` tinyint(1) NOT NULL DEFAULT '1',
+  `created_by` int NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `revoked_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `client_id` (`client_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. oauth_consents
+CREATE TABLE IF NOT EXISTS `oauth_consents` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `client_id` varchar(64) COLLATE utf8mb4_general_ci NOT NULL,
+  `scopes` varchar(255) COLLATE utf8mb4_general_ci NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unique_user_client` (`user_id`,`client_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. poll_options
+CREATE TABLE IF NOT EXISTS `poll_options` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `poll_id` int NOT NULL,
+  `label` varchar(200) COLLATE utf8mb4_general_ci NOT NULL,
+  `position` int NOT NULL DEFAULT '0',
+  `votes_count` int NOT NULL DEFAULT '0',
+  PRIMARY KEY (`id`),
+  KEY `idx_poll_options_poll` (`poll_id`,`position`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. poll_votes
+CREATE TABLE IF NOT EXISTS `poll_votes` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `poll_id` int NOT NULL,
+  `option_id` int NOT NULL,
+  `user_id` int NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unique_poll_vote` (`poll_id`,`user_id`,`option_id`),
+  KEY `idx_poll_votes_option` (`option_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. polls
+CREATE TABLE IF NOT EXISTS `polls` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `post_id` int NOT NULL,
+  `question` varchar(255) COLLATE utf8mb4_general_ci NOT NULL,
+  `is_multiple` tinyint(1) NOT NULL DEFAULT '0',
+  `is_anonymous` tinyint(1) NOT NULL DEFAULT '0',
+  `closes_at` datetime DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unique_post_poll` (`post_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. post_bounties
+CREATE TABLE IF NOT EXISTS `post_bounties` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `post_id` int NOT NULL,
+  `user_id` int NOT NULL,
+  `amount` int NOT NULL,
+  `status` enum('open','awarded','refunded','expired') COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'open',
+  `awarded_to` int DEFAULT NULL,
+  `awarded_comment_id` int DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  `expires_at` timestamp NOT NULL,
+  `resolved_at` timestamp NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_bounties_post_status` (`post_id`,`status`),
+  KEY `idx_bounties_status_expires` (`status`,`expires_at`),
+  KEY `idx_bounties_user` (`user_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
 -- Listage de la structure de la table forum. posts
 CREATE TABLE IF NOT EXISTS `posts` (
   `id` int NOT NULL AUTO_INCREMENT,
@@ -124,7 +464,7 @@ CREATE TABLE IF NOT EXISTS `posts` (
   `content` text COLLATE utf8mb4_general_ci NOT NULL,
   `user_id` int NOT NULL,
   `category_id` int NOT NULL,
-  `status` enum('open','closed','archived') COLLATE utf8mb4_general_ci DEFAULT 'open',
+  `status` enum('open','closed','archived','scheduled') COLLATE utf8mb4_general_ci DEFAULT 'open',
   `is_solved` tinyint(1) DEFAULT '0',
   `is_pinned` tinyint(1) DEFAULT '0',
   `is_locked` tinyint(1) DEFAULT '0',
@@ -133,8 +473,22 @@ CREATE TABLE IF NOT EXISTS `posts` (
   `dislikes_count` int DEFAULT '0',
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `merged_into_id` int DEFAULT NULL,
+  `duplicate_of_id` int DEFAULT NULL,
+  `duplicate_marked_by` int DEFAULT NULL,
+  `duplicate_previous_status` enum('open','closed','archived','scheduled') COLLATE utf8mb4_general_ci DEFAULT NULL,
+  `duplicate_contested` tinyint(1) NOT NULL DEFAULT '0',
+  `level` varchar(20) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  `due_at` datetime DEFAULT NULL,
+  `publish_at` datetime DEFAULT NULL,
+  `is_anonymous` tinyint(1) NOT NULL DEFAULT '0',
   PRIMARY KEY (`id`),
   KEY `idx_posts_category` (`category_id`),
+  KEY `idx_posts_level` (`level`),
+  KEY `idx_posts_due_at` (`due_at`),
+  KEY `idx_posts_publish_at` (`status`,`publish_at`),
+  KEY `idx_posts_merged_into` (`merged_into_id`),
+  KEY `idx_posts_duplicate_of` (`duplicate_of_id`),
   KEY `idx_posts_user_id` (`user_id`),
   KEY `idx_posts_created_at` (`created_at`),
   KEY `idx_posts_likes` (`likes_count`),
@@ -172,7 +526,7 @@ CREATE TABLE IF NOT EXISTS `post_votes` (
 CREATE TABLE IF NOT EXISTS `reports` (
   `id` int NOT NULL AUTO_INCREMENT,
   `reporter_id` int NOT NULL,
-  `reported_type` enum('post','comment','user') COLLATE utf8mb4_general_ci NOT NULL,
+  `reported_type` enum('post','comment','user','conversation') COLLATE utf8mb4_general_ci NOT NULL,
   `reported_id` int NOT NULL,
   `reason` varchar(255) COLLATE utf8mb4_general_ci NOT NULL,
   `description` text COLLATE utf8mb4_general_ci,
@@ -182,7 +536,27 @@ CREATE TABLE IF NOT EXISTS `reports` (
   `resolved_at` timestamp NULL DEFAULT NULL,
   PRIMARY KEY (`id`),
   KEY `reporter_id` (`reporter_id`),
-  KEY `moderator_id` (`moderator_id`)
+  KEY `moderator_id` (`moderator_id`),
+  KEY `idx_reports_target` (`reported_type`,`reported_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. reputation_events
+CREATE TABLE IF NOT EXISTS `reputation_events` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `actor_id` int DEFAULT NULL,
+  `event_type` varchar(50) COLLATE utf8mb4_general_ci NOT NULL,
+  `source_type` varchar(20) COLLATE utf8mb4_general_ci NOT NULL,
+  `source_id` int NOT NULL,
+  `points` int NOT NULL,
+  `reverses_id` int DEFAULT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_reputation_user_date` (`user_id`,`created_at`),
+  KEY `idx_reputation_source` (`source_type`,`source_id`),
+  KEY `idx_reputation_reverses` (`reverses_id`)
 ) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
 
 -- Les données exportées n'étaient pas sélectionnées.
@@ -207,6 +581,20 @@ INSERT INTO `roles` (`id`, `name`, `description`, `permissions`) VALUES
 	(4, 'administrateur', 'Administrateur', '["all"]');
 /*!40000 ALTER TABLE `roles` ENABLE KEYS */;
 
+-- Listage de la structure de la table forum. subscriptions
+CREATE TABLE IF NOT EXISTS `subscriptions` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `target_type` enum('post','category','tag') COLLATE utf8mb4_general_ci NOT NULL,
+  `target_id` int NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unique_subscription` (`user_id`,`target_type`,`target_id`),
+  KEY `idx_subscriptions_target` (`target_type`,`target_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
 -- Listage de la structure de la table forum. tags
 CREATE TABLE IF NOT EXISTS `tags` (
   `id` int NOT NULL AUTO_INCREMENT,
@@ -219,6 +607,32 @@ CREATE TABLE IF NOT EXISTS `tags` (
 
 -- Les données exportées n'étaient pas sélectionnées.
 
+-- Listage de la structure de la table forum. user_blocks
+CREATE TABLE IF NOT EXISTS `user_blocks` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `blocked_user_id` int NOT NULL,
+  `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unique_block` (`user_id`,`blocked_user_id`),
+  KEY `idx_user_blocks_blocked` (`blocked_user_id`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
+-- Listage de la structure de la table forum. user_badges
+CREATE TABLE IF NOT EXISTS `user_badges` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `badge_code` varchar(50) COLLATE utf8mb4_general_ci NOT NULL,
+  `awarded_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `unique_user_badge` (`user_id`,`badge_code`),
+  KEY `idx_user_badges_code` (`badge_code`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Les données exportées n'étaient pas sélectionnées.
+
 -- Listage de la structure de la table forum. users
 CREATE TABLE IF NOT EXISTS `users` (
   `id` int NOT NULL AUTO_INCREMENT,
@@ -237,12 +651,22 @@ CREATE TABLE IF NOT EXISTS `users` (
   `profile_visibility` enum('public','private') COLLATE utf8mb4_general_ci DEFAULT 'public',
   `date_inscription` date DEFAULT NULL,
   `location` varchar(100) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  `auth_provider` varchar(20) COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'local',
+  `external_id` varchar(255) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  `invite_code_id` int DEFAULT NULL,
+  `reputation` int NOT NULL DEFAULT '0',
+  `email_digest` tinyint(1) NOT NULL DEFAULT '0',
+  `digest_sent_at` timestamp NULL DEFAULT NULL,
+  `message_privacy` enum('everyone','contacts','nobody') COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'everyone',
   PRIMARY KEY (`id`),
   UNIQUE KEY `username` (`username`),
   UNIQUE KEY `email` (`email`),
+  UNIQUE KEY `external_identity` (`auth_provider`,`external_id`),
   KEY `idx_users_role` (`role_id`),
   KEY `idx_users_avatar` (`avatar_filename`),
-  KEY `idx_users_visibility` (`profile_visibility`)
+  KEY `idx_users_reputation` (`reputation`),
+  KEY `idx_users_visibility` (`profile_visibility`),
+  KEY `idx_users_invite_code` (`invite_code_id`)
 ) ENGINE=MyISAM AUTO_INCREMENT=5 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
 
 -- Les données exportées n'étaient pas sélectionnées.
@@ -257,6 +681,9 @@ CREATE TABLE IF NOT EXISTS `user_stats` (
   `likes_received_posts` int DEFAULT '0',
   `likes_received_comments` int DEFAULT '0',
   `total_views_posts` int DEFAULT '0',
+  `current_streak` int DEFAULT '0',
+  `longest_streak` int DEFAULT '0',
+  `last_active_date` date DEFAULT NULL,
   `created_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   PRIMARY KEY (`user_id`),
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"database/sql"
+
+	"aide-devoir-forum/models"
+)
+
+// === SOLUTIONS ACCEPTÉES ===
+
+// SetCommentSolution accepte ou retire une réponse comme solution de son post.
+// Hors mode multi-solutions de la catégorie, accepter une réponse remplace la solution
+// précédente en une seule requête. L'état résolu du post, ainsi que les statistiques et la
+// réputation des auteurs concernés, sont recalculés. Retourne sql.ErrNoRows si la réponse
+// était déjà dans cet état.
+func (r *Repository) SetCommentSolution(commentID int, solution bool) error {
+	var postID, postAuthorID int
+	var multiple bool
+	err := r.db.QueryRow(`
+		SELECT cm.post_id, p.user_id, c.allow_multiple_solutions
+		FROM comments cm
+		JOIN posts p ON cm.post_id = p.id
+		JOIN categories c ON p.category_id = c.id
+		WHERE cm.id = ?
+	`, commentID).Scan(&postID, &postAuthorID, &multiple)
+	if err != nil {
+		return err
+	}
+
+	// Réponses dont l'état de solution peut changer
+	candidates, err := r.solutionCandidates(postID, commentID)
+	if err != nil {
+		return err
+	}
+
+	var result sql.Result
+	switch {
+	case !solution:
+		result, err = r.db.Exec("UPDATE comments SET is_solution = FALSE WHERE id = ? AND is_solution = TRUE", commentID)
+	case multiple:
+		result, err = r.db.Exec("UPDATE comments SET is_solution = TRUE WHERE id = ? AND is_solution = FALSE", commentID)
+	default:
+		result, err = r.db.Exec(`
+			UPDATE comments SET is_solution = (id = ?)
+			WHERE post_id = ? AND (is_solution = TRUE OR id = ?)
+		`, commentID, postID, commentID)
+	}
+	if err != nil {
+		return err
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := r.RecomputePostSolved(postID); err != nil {
+		return err
+	}
+
+	// Réputation, statistiques, badges et prime des auteurs concernés
+	for _, candidate := range candidates {
+		switch {
+		case candidate.ID == commentID && solution:
+			r.awardReputation(candidate.UserID, postAuthorID, models.ReputationSolutionAccepted, "comment",
+				candidate.ID, r.reputation.SolutionAccepted)
+		case candidate.ID == commentID || (!multiple && candidate.IsSolution):
+			r.reverseReputation(models.ReputationSolutionAccepted, "comment", candidate.ID, 0)
+		default:
+			continue
+		}
+		r.UpdateUserStats(candidate.UserID)
+		if candidate.ID == commentID && solution {
+			r.EvaluateBadges(candidate.UserID, models.BadgeMetricSolutions, models.BadgeMetricCategoriesHelped)
+			r.awardPostBounty(postID, commentID)
+		}
+	}
+	r.UpdateUserStats(postAuthorID)
+	return nil
+}
+
+// solutionCandidates retourne les solutions actuelles d'un post et la réponse visée
+func (r *Repository) solutionCandidates(postID, commentID int) ([]models.Comment, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, is_solution FROM comments
+		WHERE post_id = ? AND (is_solution = TRUE OR id = ?)
+	`, postID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+	for rows.Next() {
+		var comment models.Comment
+		if err := rows.Scan(&comment.ID, &comment.UserID, &comment.IsSolution); err != nil {
+			continue
+		}
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
+
+// RecomputePostSolved aligne l'état résolu d'un post sur la présence d'au moins une solution
+func (r *Repository) RecomputePostSolved(postID int) error {
+	_, err := r.db.Exec(`
+		UPDATE posts
+		SET is_solved = (SELECT COUNT(*) FROM comments WHERE post_id = ? AND is_solution = TRUE) > 0
+		WHERE id = ?
+	`, postID, postID)
+	return err
+}
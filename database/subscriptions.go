@@ -0,0 +1,311 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"aide-devoir-forum/models"
+)
+
+// === ABONNEMENTS ===
+
+// Subscribe abonne un utilisateur à une question, une catégorie ou un tag (sans effet s'il l'est déjà)
+func (r *Repository) Subscribe(userID int, targetType string, targetID int) error {
+	_, err := r.db.Exec(`
+		INSERT IGNORE INTO subscriptions (user_id, target_type, target_id) VALUES (?, ?, ?)
+	`, userID, targetType, targetID)
+	return err
+}
+
+// Unsubscribe supprime un abonnement
+func (r *Repository) Unsubscribe(userID int, targetType string, targetID int) error {
+	_, err := r.db.Exec(`
+		DELETE FROM subscriptions WHERE user_id = ? AND target_type = ? AND target_id = ?
+	`, userID, targetType, targetID)
+	return err
+}
+
+// IsSubscribed vérifie qu'un utilisateur suit une question, une catégorie ou un tag
+func (r *Repository) IsSubscribed(userID int, targetType string, targetID int) bool {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM subscriptions WHERE user_id = ? AND target_type = ? AND target_id = ?
+	`, userID, targetType, targetID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// GetSubscriptions retourne les abonnements d'un utilisateur (questions, puis catégories et tags)
+func (r *Repository) GetSubscriptions(userID int) ([]models.Subscription, error) {
+	rows, err := r.db.Query(`
+		SELECT s.id, s.user_id, s.target_type, s.target_id, COALESCE(p.title, c.name, t.name), s.created_at
+		FROM subscriptions s
+		LEFT JOIN posts p ON s.target_type = 'post' AND p.id = s.target_id
+		LEFT JOIN categories c ON s.target_type = 'category' AND c.id = s.target_id
+		LEFT JOIN tags t ON s.target_type = 'tag' AND t.id = s.target_id
+		WHERE s.user_id = ? AND COALESCE(p.title, c.name, t.name) IS NOT NULL
+		ORDER BY FIELD(s.target_type, 'post', 'category', 'tag'), s.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []models.Subscription{}
+	for rows.Next() {
+		var subscription models.Subscription
+		if err := rows.Scan(&subscription.ID, &subscription.UserID, &subscription.TargetType, &subscription.TargetID,
+			&subscription.TargetName, &subscription.CreatedAt); err != nil {
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, nil
+}
+
+// GetTagID retourne l'identifiant d'un tag existant
+func (r *Repository) GetTagID(name string) (int, error) {
+	var id int
+	err := r.db.QueryRow("SELECT id FROM tags WHERE name = ?", name).Scan(&id)
+	return id, err
+}
+
+// GetSubscribedTags retourne les noms des tags suivis par un utilisateur
+func (r *Repository) GetSubscribedTags(userID int) map[string]bool {
+	tags := make(map[string]bool)
+	rows, err := r.db.Query(`
+		SELECT t.name FROM subscriptions s
+		JOIN tags t ON s.target_type = 'tag' AND t.id = s.target_id
+		WHERE s.user_id = ?
+	`, userID)
+	if err != nil {
+		return tags
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if rows.Scan(&name) == nil {
+			tags[strings.ToLower(name)] = true
+		}
+	}
+	return tags
+}
+
+// subscriber représente un abonné à prévenir et la raison de la notification
+type subscriber struct {
+	models.User
+	reason string // Catégorie ou tag suivi
+}
+
+// getSubscribers retourne les abonnés non bannis d'un élément
+func (r *Repository) getSubscribers(targetType string, targetID int) ([]models.User, error) {
+	rows, err := r.db.Query(`
+		SELECT u.id, u.username, u.role_id
+		FROM subscriptions s
+		JOIN users u ON s.user_id = u.id
+		WHERE s.target_type = ? AND s.target_id = ? AND NOT u.is_banned
+	`, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if rows.Scan(&user.ID, &user.Username, &user.RoleID) == nil {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// alreadyMentioned retourne les utilisateurs déjà notifiés d'une mention dans un contenu
+// (commentID 0 pour la question), pour ne pas les notifier une seconde fois
+func (r *Repository) alreadyMentioned(postID, commentID int) map[int]bool {
+	mentioned := make(map[int]bool)
+	rows, err := r.db.Query("SELECT user_id FROM mentions WHERE post_id = ? AND comment_id = ?", postID, commentID)
+	if err != nil {
+		return mentioned
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int
+		if rows.Scan(&userID) == nil {
+			mentioned[userID] = true
+		}
+	}
+	return mentioned
+}
+
+// NotifyAnswerSubscribers prévient les abonnés d'une question d'une nouvelle réponse.
+// L'auteur de la réponse et les utilisateurs qui y sont mentionnés ne sont pas notifiés.
+func (r *Repository) NotifyAnswerSubscribers(postID, commentID, authorID int, authorName string) error {
+	subscribers, err := r.getSubscribers(models.SubscriptionPost, postID)
+	if err != nil {
+		return err
+	}
+
+	skip := r.alreadyMentioned(postID, commentID)
+	link := fmt.Sprintf("/post/%d#comment-%d", postID, commentID)
+	for i := range subscribers {
+		user := &subscribers[i]
+		if user.ID == authorID || skip[user.ID] {
+			continue
+		}
+		title, ok := r.visiblePostTitle(postID, user)
+		if !ok {
+			continue
+		}
+		r.CreateNotification(user.ID, models.NotificationNewAnswer,
+			fmt.Sprintf("%s a répondu à « %s »", authorName, shortTitle(title)), link)
+	}
+	return nil
+}
+
+// NotifyPostSubscribers prévient les abonnés de la catégorie et des tags d'une question
+// publiée. Chaque abonné n'est notifié qu'une fois ; l'auteur, les utilisateurs mentionnés
+// et ceux de exclude (déjà prévenus autrement) ne sont pas notifiés.
+func (r *Repository) NotifyPostSubscribers(postID int, exclude map[int]bool) error {
+	var authorID, categoryID int
+	var categoryName string
+	err := r.db.QueryRow(`
+		SELECT p.user_id, p.category_id, c.name FROM posts p
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.id = ?
+	`, postID).Scan(&authorID, &categoryID, &categoryName)
+	if err != nil {
+		return err
+	}
+
+	var subscribers []subscriber
+	users, err := r.getSubscribers(models.SubscriptionCategory, categoryID)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		subscribers = append(subscribers, subscriber{User: user, reason: "dans " + categoryName})
+	}
+
+	tags, _ := r.GetPostTags(postID)
+	for _, tag := range tags {
+		users, err := r.getSubscribers(models.SubscriptionTag, tag.ID)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			subscribers = append(subscribers, subscriber{User: user, reason: "avec le tag #" + tag.Name})
+		}
+	}
+
+	notified := r.alreadyMentioned(postID, 0)
+	notified[authorID] = true
+	for userID := range exclude {
+		notified[userID] = true
+	}
+
+	link := fmt.Sprintf("/post/%d", postID)
+	for i := range subscribers {
+		user := &subscribers[i].User
+		if notified[user.ID] {
+			continue
+		}
+		notified[user.ID] = true
+
+		title, ok := r.visiblePostTitle(postID, user)
+		if !ok {
+			continue
+		}
+		r.CreateNotification(user.ID, models.NotificationNewPost,
+			fmt.Sprintf("Nouvelle question %s : « %s »", subscribers[i].reason, shortTitle(title)), link)
+	}
+	return nil
+}
+
+// === RÉSUMÉ PAR EMAIL ===
+
+// SetEmailDigest active ou désactive le résumé des abonnements par email
+func (r *Repository) SetEmailDigest(userID int, enabled bool) error {
+	_, err := r.db.Exec("UPDATE users SET email_digest = ? WHERE id = ?", enabled, userID)
+	return err
+}
+
+// subscriptionNotificationSQL filtre (notifications sous l'alias "n") celles des abonnements
+func subscriptionNotificationSQL() (string, []interface{}) {
+	placeholders := make([]string, len(models.SubscriptionNotificationTypes))
+	args := make([]interface{}, len(models.SubscriptionNotificationTypes))
+	for i, notificationType := range models.SubscriptionNotificationTypes {
+		placeholders[i] = "?"
+		args[i] = notificationType
+	}
+	return "n.type IN (" + strings.Join(placeholders, ", ") + ")", args
+}
+
+// GetDigestRecipients retourne les utilisateurs ayant activé le résumé par email, dont le
+// dernier résumé date d'au moins interval, et qui ont depuis des notifications d'abonnement non lues
+func (r *Repository) GetDigestRecipients(interval time.Duration) ([]models.User, error) {
+	typeFilter, args := subscriptionNotificationSQL()
+	rows, err := r.db.Query(`
+		SELECT u.id, u.username, u.email
+		FROM users u
+		WHERE u.email_digest AND NOT u.is_banned
+		  AND (u.digest_sent_at IS NULL OR u.digest_sent_at <= NOW() - INTERVAL ? SECOND)
+		  AND EXISTS (
+			SELECT 1 FROM notifications n
+			WHERE n.user_id = u.id AND n.is_read = FALSE AND `+typeFilter+`
+			  AND (u.digest_sent_at IS NULL OR n.created_at > u.digest_sent_at)
+		  )
+	`, append([]interface{}{int(interval.Seconds())}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if rows.Scan(&user.ID, &user.Username, &user.Email) == nil {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// GetDigestNotifications retourne les notifications d'abonnement non lues d'un utilisateur
+// arrivées depuis son dernier résumé
+func (r *Repository) GetDigestNotifications(userID, limit int) ([]models.Notification, error) {
+	typeFilter, args := subscriptionNotificationSQL()
+	rows, err := r.db.Query(`
+		SELECT n.id, n.user_id, n.type, n.message, n.link, n.is_read, n.created_at
+		FROM notifications n
+		JOIN users u ON n.user_id = u.id
+		WHERE n.user_id = ? AND n.is_read = FALSE AND `+typeFilter+`
+		  AND (u.digest_sent_at IS NULL OR n.created_at > u.digest_sent_at)
+		ORDER BY n.created_at DESC, n.id DESC
+		LIMIT ?
+	`, append(append([]interface{}{userID}, args...), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var notification models.Notification
+		var link sql.NullString
+		if err := rows.Scan(&notification.ID, &notification.UserID, &notification.Type, &notification.Message,
+			&link, &notification.IsRead, &notification.CreatedAt); err != nil {
+			continue
+		}
+		notification.Link = link.String
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+// MarkDigestSent enregistre l'envoi d'un résumé à un utilisateur
+func (r *Repository) MarkDigestSent(userID int) error {
+	_, err := r.db.Exec("UPDATE users SET digest_sent_at = NOW() WHERE id = ?", userID)
+	return err
+}
@@ -3,9 +3,15 @@ module aide-devoir-forum
 go 1.21
 
 require (
+	github.com/go-ldap/ldap/v3 v3.4.8
 	github.com/go-sql-driver/mysql v1.7.1
 	github.com/golang-jwt/jwt/v5 v5.2.0
-	golang.org/x/crypto v0.17.0
+	github.com/joho/godotenv v1.5.1
+	golang.org/x/crypto v0.21.0
 )
 
-require github.com/joho/godotenv v1.5.1 // indirect
+require (
+	github.com/Azure/go-ntlmssp v0.0.0-20221128193559-754e69321358 // indirect
+	github.com/go-asn1-ber/asn1-ber v1.5.5 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+)
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"html/template"
 	"net/http"
@@ -42,10 +43,21 @@ func (h *AdminHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 		users = []models.User{}
 	}
 
-	// Récupérer les catégories
-	categories, err := h.repo.GetCategories()
+	// Récupérer les catégories, sous-catégories à la suite de leur parent
+	categoryTree, err := h.repo.GetCategories(user)
 	if err != nil {
-		categories = []models.Category{}
+		categoryTree = []models.Category{}
+	}
+	categories := models.FlattenCategories(categoryTree)
+
+	// Rattacher les modérateurs nommés à leur catégorie
+	moderators, _ := h.repo.GetCategoryModerators(0)
+	for _, moderator := range moderators {
+		for i := range categories {
+			if categories[i].ID == moderator.CategoryID {
+				categories[i].Moderators = append(categories[i].Moderators, moderator)
+			}
+		}
 	}
 
 	// Récupérer les logs de modération
@@ -60,13 +72,27 @@ func (h *AdminHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 		stats = models.AdminStats{}
 	}
 
+	// Récupérer les applications OAuth
+	oauthClients, err := h.repo.GetOAuthClients()
+	if err != nil {
+		oauthClients = []models.OAuthClient{}
+	}
+
+	// Questions dont la date de rendu est dépassée depuis longtemps
+	archiveSuggestions, err := h.repo.GetArchiveSuggestions(50)
+	if err != nil {
+		archiveSuggestions = []models.Post{}
+	}
+
 	data := models.AdminPageData{
-		Users:      users,
-		Categories: categories,
-		Logs:       logs,
-		Stats:      stats,
-		User:       user,
-		Title:      "Administration",
+		Users:              users,
+		Categories:         categories,
+		Logs:               logs,
+		Stats:              stats,
+		OAuthClients:       oauthClients,
+		User:               user,
+		Title:              "Administration",
+		ArchiveSuggestions: archiveSuggestions,
 	}
 
 	if h.templates != nil {
@@ -88,8 +114,8 @@ func (h *AdminHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 					` + banStatus + `
 				</div>
 				<div class="user-actions">
-					<button onclick="banUser(` + strconv.Itoa(u.ID) + `)" class="btn btn-warning">Bannir</button>
-					<button onclick="promoteUser(` + strconv.Itoa(u.ID) + `)" class="btn btn-info">Promouvoir</button>
+					<button data-user-id="` + strconv.Itoa(u.ID) + `" class="btn btn-warning ban-user">Bannir</button>
+					<button data-user-id="` + strconv.Itoa(u.ID) + `" class="btn btn-info promote-user">Promouvoir</button>
 				</div>
 			</div>`
 		}
@@ -100,7 +126,7 @@ func (h *AdminHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 				<h2>Gestion des utilisateurs</h2>
 				<div class="users-list">` + usersHTML + `</div>
 			</div>
-			<script>
+			<script nonce="` + utils.CSPNonceMarker() + `">
 				function banUser(userId) {
 					const reason = prompt("Raison du bannissement :");
 					if (reason) {
@@ -121,6 +147,12 @@ func (h *AdminHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 						}).then(() => location.reload());
 					}
 				}
+				document.querySelectorAll('.ban-user').forEach(function(button) {
+					button.addEventListener('click', function() { banUser(this.dataset.userId); });
+				});
+				document.querySelectorAll('.promote-user').forEach(function(button) {
+					button.addEventListener('click', function() { promoteUser(this.dataset.userId); });
+				});
 			</script>
 		`
 
@@ -241,9 +273,9 @@ func (h *AdminHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := middleware.GetUserFromContext(r.Context())
-	if user == nil || !user.CanModerate() {
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Accès refusé"})
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Authentification requise"})
 		return
 	}
 
@@ -257,7 +289,18 @@ func (h *AdminHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Supprimer le post
+	// Modérateur global ou modérateur de la catégorie du post
+	permissions, err := h.repo.GetPostPermissions(postID, user)
+	if err != nil || !permissions.CanModerate {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Accès refusé"})
+		return
+	}
+
+	postAuthorID, _ := h.repo.GetPostAuthorID(postID)
+
+	// Rembourser la prime en cours puis supprimer le post
+	h.repo.RefundOpenBounty(postID)
 	err = h.repo.DeletePost(postID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -265,8 +308,9 @@ func (h *AdminHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Logger l'action
+	// Logger l'action et pénaliser l'auteur
 	h.repo.CreateModerationLog(user.ID, "delete", "post", postID, reason)
+	h.repo.PenalizeReputation(postAuthorID, user.ID, "post", postID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
@@ -280,9 +324,9 @@ func (h *AdminHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := middleware.GetUserFromContext(r.Context())
-	if user == nil || !user.CanModerate() {
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Accès refusé"})
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Authentification requise"})
 		return
 	}
 
@@ -296,6 +340,21 @@ func (h *AdminHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Modérateur global ou modérateur de la catégorie du post commenté
+	comment, err := h.repo.GetCommentByID(commentID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Commentaire non trouvé"})
+		return
+	}
+
+	permissions, err := h.repo.GetPostPermissions(comment.PostID, user)
+	if err != nil || !permissions.CanModerate {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Accès refusé"})
+		return
+	}
+
 	// Supprimer le commentaire
 	err = h.repo.DeleteComment(commentID)
 	if err != nil {
@@ -304,15 +363,28 @@ func (h *AdminHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Logger l'action
+	// Logger l'action et pénaliser l'auteur
 	h.repo.CreateModerationLog(user.ID, "delete", "comment", commentID, reason)
+	h.repo.PenalizeReputation(comment.UserID, user.ID, "comment", commentID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
-// POST /admin/mark-solution
+// POST /mark-solution
+// MarkSolution accepte une réponse comme solution (remplace la précédente hors mode multi-solutions)
 func (h *AdminHandler) MarkSolution(w http.ResponseWriter, r *http.Request) {
+	h.setSolution(w, r, true)
+}
+
+// POST /unmark-solution
+// UnmarkSolution retire une réponse des solutions acceptées du post
+func (h *AdminHandler) UnmarkSolution(w http.ResponseWriter, r *http.Request) {
+	h.setSolution(w, r, false)
+}
+
+// setSolution accepte ou retire une solution (auteur du post ou modérateur de la catégorie)
+func (h *AdminHandler) setSolution(w http.ResponseWriter, r *http.Request, solution bool) {
 	if r.Method != "POST" {
 		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
 		return
@@ -326,7 +398,6 @@ func (h *AdminHandler) MarkSolution(w http.ResponseWriter, r *http.Request) {
 	}
 
 	commentIDStr := r.FormValue("comment_id")
-	postIDStr := r.FormValue("post_id")
 
 	commentID, err := strconv.Atoi(commentIDStr)
 	if err != nil || commentID <= 0 {
@@ -335,48 +406,248 @@ func (h *AdminHandler) MarkSolution(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postID, err := strconv.Atoi(postIDStr)
-	if err != nil || postID <= 0 {
+	// Le post est déduit du commentaire (post_id n'est conservé que pour compatibilité)
+	comment, err := h.repo.GetCommentByID(commentID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Commentaire non trouvé"})
+		return
+	}
+	if postID := r.FormValue("post_id"); postID != "" && postID != strconv.Itoa(comment.PostID) {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "ID de post invalide"})
+		json.NewEncoder(w).Encode(map[string]string{"error": "Ce commentaire n'appartient pas à ce post"})
 		return
 	}
 
-	// Vérifier si l'utilisateur peut marquer comme solution
-	// (doit être l'auteur du post ou un modérateur)
-	postAuthorID, err := h.repo.GetPostAuthorID(postID)
+	// Vérifier si l'utilisateur peut choisir la solution
+	// (doit être l'auteur du post ou un modérateur de la catégorie)
+	postAuthorID, err := h.repo.GetPostAuthorID(comment.PostID)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Post non trouvé"})
 		return
 	}
 
-	if user.ID != postAuthorID && !user.CanModerate() {
+	permissions, _ := h.repo.GetPostPermissions(comment.PostID, user)
+	if user.ID != postAuthorID && !permissions.CanModerate {
 		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Seul l'auteur du post peut marquer une solution"})
+		json.NewEncoder(w).Encode(map[string]string{"error": "Seul l'auteur du post peut choisir la solution"})
 		return
 	}
 
-	// Marquer le commentaire comme solution
-	err = h.repo.MarkCommentAsSolution(commentID)
+	err = h.repo.SetCommentSolution(commentID, solution)
+	if err == sql.ErrNoRows {
+		message := "Ce commentaire est déjà la solution"
+		if !solution {
+			message = "Ce commentaire n'est pas une solution"
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Erreur lors du marquage"})
 		return
 	}
 
-	// Marquer le post comme résolu
-	err = h.repo.MarkPostAsSolved(postID)
-	if err != nil {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// POST /admin/pin-post
+func (h *AdminHandler) PinPost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Authentification requise"})
+		return
+	}
+
+	postID, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil || postID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ID de post invalide"})
+		return
+	}
+	pinned := r.FormValue("pinned") == "1"
+
+	// Modérateur global ou modérateur de la catégorie du post
+	permissions, err := h.repo.GetPostPermissions(postID, user)
+	if err != nil || !permissions.CanModerate {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Accès refusé"})
+		return
+	}
+
+	if err := h.repo.SetPostPinned(postID, pinned); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Erreur lors du marquage du post"})
+		json.NewEncoder(w).Encode(map[string]string{"error": "Erreur lors de l'épinglage"})
 		return
 	}
 
+	action := "pin_post"
+	if !pinned {
+		action = "unpin_post"
+	}
+	h.repo.CreateModerationLog(user.ID, action, "post", postID, "")
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
+// POST /admin/move-post
+func (h *AdminHandler) MovePost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Authentification requise"})
+		return
+	}
+
+	postID, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil || postID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ID de post invalide"})
+		return
+	}
+
+	categoryID, err := strconv.Atoi(r.FormValue("category_id"))
+	if err != nil || categoryID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ID de catégorie invalide"})
+		return
+	}
+
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	if reason == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "La raison est requise"})
+		return
+	}
+
+	// Modérateur des catégories d'origine et de destination
+	permissions, err := h.repo.GetPostPermissions(postID, user)
+	if err != nil || !permissions.CanModerate {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Accès refusé"})
+		return
+	}
+
+	post, err := h.repo.GetPostByID(postID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Post non trouvé"})
+		return
+	}
+
+	destination, err := h.repo.GetCategory(categoryID)
+	if err != nil || !h.repo.CanViewCategory(destination, user) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Catégorie non trouvée"})
+		return
+	}
+	if !h.repo.GetCategoryPermissions(destination, user).CanModerate {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Vous ne modérez pas la catégorie de destination"})
+		return
+	}
+
+	if destination.ID == post.CategoryID {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Le post est déjà dans cette catégorie"})
+		return
+	}
+
+	if err := h.repo.MovePost(postID, categoryID); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Erreur lors du déplacement"})
+		return
+	}
+
+	h.repo.CreateModerationLog(user.ID, "move_post", "post", postID,
+		reason+" ("+post.CategoryName+" → "+destination.Name+")")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":   "success",
+		"redirect": "/post/" + strconv.Itoa(postID) + "?success=moved",
+	})
+}
+
+// POST /admin/merge-post
+func (h *AdminHandler) MergePost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Authentification requise"})
+		return
+	}
+
+	postID, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil || postID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ID de post invalide"})
+		return
+	}
+
+	targetID, err := strconv.Atoi(r.FormValue("target_post_id"))
+	if err != nil || targetID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ID du post canonique invalide"})
+		return
+	}
+
+	reason := strings.TrimSpace(r.FormValue("reason"))
+
+	// Modérateur du doublon, post canonique visible
+	permissions, err := h.repo.GetPostPermissions(postID, user)
+	if err != nil || !permissions.CanModerate {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Accès refusé"})
+		return
+	}
+
+	if _, err := h.repo.GetPost(targetID, user); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Post canonique non trouvé"})
+		return
+	}
+
+	if err := h.repo.MergePost(postID, targetID); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Fusion impossible : " + err.Error()})
+		return
+	}
+
+	logReason := "Fusionné dans le post #" + strconv.Itoa(targetID)
+	if reason != "" {
+		logReason = reason + " (" + logReason + ")"
+	}
+	h.repo.CreateModerationLog(user.ID, "merge_post", "post", postID, logReason)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":   "success",
+		"redirect": "/post/" + strconv.Itoa(targetID) + "?merged=" + strconv.Itoa(postID),
+	})
+}
+
 // UnbanUser débannit un utilisateur
 func (h *AdminHandler) UnbanUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -414,6 +685,36 @@ func (h *AdminHandler) UnbanUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
+// validCategoryRules vérifie que les rôles minimums d'une catégorie existent
+func validCategoryRules(rules models.CategoryRules) bool {
+	for _, role := range []int{rules.ReadMinRole, rules.PostMinRole, rules.CommentMinRole} {
+		if role < models.RoleGuest || role > models.RoleAdministrator {
+			return false
+		}
+	}
+	return true
+}
+
+// validateCategoryParent vérifie le parent choisi pour une catégorie (id 0 = nouvelle catégorie)
+// et retourne un message d'erreur, ou une chaîne vide si le parent est acceptable
+func (h *AdminHandler) validateCategoryParent(id int, parentID *int) string {
+	if parentID == nil {
+		return ""
+	}
+
+	parent, err := h.repo.GetCategory(*parentID)
+	if err != nil {
+		return "Catégorie parente introuvable"
+	}
+	if parent.IsPrivate() {
+		return "Une catégorie de classe ne peut pas contenir de sous-catégories"
+	}
+	if id != 0 && h.repo.IsCategoryDescendant(*parentID, id) {
+		return "Une catégorie ne peut pas être placée sous elle-même ou sous une de ses sous-catégories"
+	}
+	return ""
+}
+
 // POST /admin/categories
 func (h *AdminHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -433,6 +734,8 @@ func (h *AdminHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 		Description string `json:"description"`
 		Color       string `json:"color"`
 		Icon        string `json:"icon"`
+		ParentID    *int   `json:"parent_id"`
+		models.CategoryRules
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -447,7 +750,19 @@ func (h *AdminHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.repo.CreateCategory(req.Name, req.Description, req.Color, req.Icon)
+	if !validCategoryRules(req.CategoryRules) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Rôles minimums invalides"})
+		return
+	}
+
+	if msg := h.validateCategoryParent(0, req.ParentID); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+		return
+	}
+
+	err := h.repo.CreateCategory(req.Name, req.Description, req.Color, req.Icon, req.ParentID, req.CategoryRules)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Erreur lors de la création"})
@@ -493,6 +808,8 @@ func (h *AdminHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 		Description string `json:"description"`
 		Color       string `json:"color"`
 		Icon        string `json:"icon"`
+		ParentID    *int   `json:"parent_id"`
+		models.CategoryRules
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -507,7 +824,19 @@ func (h *AdminHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.repo.UpdateCategory(id, req.Name, req.Description, req.Color, req.Icon)
+	if !validCategoryRules(req.CategoryRules) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Rôles minimums invalides"})
+		return
+	}
+
+	if msg := h.validateCategoryParent(id, req.ParentID); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+		return
+	}
+
+	err = h.repo.UpdateCategory(id, req.Name, req.Description, req.Color, req.Icon, req.ParentID, req.CategoryRules)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Erreur lors de la modification"})
@@ -558,3 +887,132 @@ func (h *AdminHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
+
+// POST /admin/categories/reorder
+func (h *AdminHandler) ReorderCategories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil || !user.IsAdmin() {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Accès refusé"})
+		return
+	}
+
+	var req struct {
+		ParentID    *int  `json:"parent_id"`
+		CategoryIDs []int `json:"category_ids"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.CategoryIDs) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Données invalides"})
+		return
+	}
+
+	if err := h.repo.ReorderCategories(req.ParentID, req.CategoryIDs); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Erreur lors du réordonnancement"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// POST /admin/category-moderators
+func (h *AdminHandler) AddCategoryModerator(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil || !user.IsAdmin() {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Accès refusé"})
+		return
+	}
+
+	categoryID, err := strconv.Atoi(r.FormValue("category_id"))
+	if err != nil || categoryID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ID de catégorie invalide"})
+		return
+	}
+
+	if _, err := h.repo.GetCategory(categoryID); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Catégorie non trouvée"})
+		return
+	}
+
+	moderator, err := h.repo.GetUserByUsername(strings.TrimSpace(r.FormValue("username")))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Utilisateur non trouvé"})
+		return
+	}
+
+	if moderator.IsBanned {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Un utilisateur banni ne peut pas modérer"})
+		return
+	}
+
+	if err := h.repo.AddCategoryModerator(categoryID, moderator.ID, user.ID); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Erreur lors de la nomination"})
+		return
+	}
+
+	h.repo.CreateModerationLog(user.ID, "appoint_category_moderator", "user", moderator.ID,
+		"Catégorie #"+strconv.Itoa(categoryID))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// POST /admin/category-moderators/remove
+func (h *AdminHandler) RemoveCategoryModerator(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil || !user.IsAdmin() {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Accès refusé"})
+		return
+	}
+
+	categoryID, err := strconv.Atoi(r.FormValue("category_id"))
+	if err != nil || categoryID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ID de catégorie invalide"})
+		return
+	}
+
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil || userID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ID utilisateur invalide"})
+		return
+	}
+
+	if err := h.repo.RemoveCategoryModerator(categoryID, userID); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Modérateur non trouvé"})
+		return
+	}
+
+	h.repo.CreateModerationLog(user.ID, "revoke_category_moderator", "user", userID,
+		"Catégorie #"+strconv.Itoa(categoryID))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+)
+
+// === PUBLICATION ANONYME ===
+
+// contentAuthorName retourne le nom affiché dans les notifications pour l'auteur d'un contenu,
+// masqué s'il a été publié anonymement
+func contentAuthorName(author *models.User, isAnonymous bool) string {
+	if isAnonymous {
+		return models.AnonymousName
+	}
+	return author.Username
+}
+
+// POST /admin/reveal-author
+// RevealAuthor lève l'anonymat d'un post ou d'une réponse (target_type "post" ou "comment")
+// pour un modérateur de la catégorie. Chaque levée est journalisée avec sa raison.
+func (h *AdminHandler) RevealAuthor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	targetType := r.FormValue("target_type")
+	targetID, _ := strconv.Atoi(r.FormValue("target_id"))
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	if reason == "" {
+		sendJSONError(w, "La raison est requise", http.StatusBadRequest)
+		return
+	}
+
+	postID, username, err := h.repo.GetAnonymousAuthor(targetType, targetID)
+	if err != nil {
+		sendJSONError(w, "Contenu anonyme non trouvé", http.StatusNotFound)
+		return
+	}
+
+	permissions, err := h.repo.GetPostPermissions(postID, user)
+	if err != nil || !permissions.CanModerate {
+		sendJSONError(w, "Accès refusé", http.StatusForbidden)
+		return
+	}
+
+	// L'identité n'est communiquée qu'une fois l'accès journalisé
+	if err := h.repo.CreateModerationLog(user.ID, "reveal_author", targetType, targetID, reason); err != nil {
+		log.Printf("Erreur journalisation de la levée d'anonymat (%s %d): %v", targetType, targetID, err)
+		sendJSONError(w, "Erreur lors de la levée d'anonymat", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":   "success",
+		"message":  "Levée d'anonymat enregistrée dans le journal de modération",
+		"username": username,
+	})
+}
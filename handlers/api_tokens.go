@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// Limites des tokens d'API personnels
+const (
+	maxActiveAPITokens   = 10
+	maxAPITokenValidDays = 365
+)
+
+// POST /settings/tokens
+// CreateAPIToken crée un token d'API personnel. Le token en clair n'est renvoyé qu'une seule fois.
+func (h *ProfileHandler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		sendJSONError(w, "Données invalides", http.StatusBadRequest)
+		return
+	}
+
+	name := utils.SanitizeInput(r.FormValue("name"))
+	if len(name) < 3 || len(name) > 100 {
+		sendJSONError(w, "Le nom du token doit faire entre 3 et 100 caractères", http.StatusBadRequest)
+		return
+	}
+
+	// Valider les portées demandées
+	allowed := availableScopes(user, h.repo.ModeratesAnyCategory(user))
+	var scopes []string
+	for _, scope := range r.Form["scopes"] {
+		if !utils.Contains(allowed, scope) {
+			sendJSONError(w, "Portée non autorisée: "+scope, http.StatusBadRequest)
+			return
+		}
+		scopes = append(scopes, scope)
+	}
+	scopes = utils.RemoveDuplicates(scopes)
+	if len(scopes) == 0 {
+		sendJSONError(w, "Sélectionnez au moins une portée", http.StatusBadRequest)
+		return
+	}
+
+	// Durée de validité en jours (0 = sans expiration)
+	var expiresAt *time.Time
+	if daysStr := r.FormValue("expires_in_days"); daysStr != "" && daysStr != "0" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > maxAPITokenValidDays {
+			sendJSONError(w, "Durée de validité invalide", http.StatusBadRequest)
+			return
+		}
+		expiry := time.Now().AddDate(0, 0, days)
+		expiresAt = &expiry
+	}
+
+	count, err := h.repo.CountActiveAPITokens(user.ID)
+	if err != nil {
+		sendJSONError(w, "Erreur lors de la création du token", http.StatusInternalServerError)
+		return
+	}
+	if count >= maxActiveAPITokens {
+		sendJSONError(w, "Nombre maximum de tokens actifs atteint, révoquez-en un d'abord", http.StatusBadRequest)
+		return
+	}
+
+	token, prefix, hash, err := utils.GenerateAPIToken()
+	if err != nil {
+		sendJSONError(w, "Erreur lors de la génération du token", http.StatusInternalServerError)
+		return
+	}
+
+	tokenID, err := h.repo.CreateAPIToken(user.ID, name, prefix, hash, scopes, expiresAt)
+	if err != nil {
+		sendJSONError(w, "Erreur lors de la création du token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "Token créé. Copiez-le maintenant, il ne sera plus affiché.",
+		"id":         tokenID,
+		"token":      token,
+		"scopes":     scopes,
+		"expires_at": expiresAt,
+	})
+}
+
+// POST /settings/tokens/revoke
+// RevokeAPIToken révoque un token d'API de l'utilisateur connecté
+func (h *ProfileHandler) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+
+	tokenID, err := strconv.Atoi(r.FormValue("token_id"))
+	if err != nil || tokenID <= 0 {
+		sendJSONError(w, "ID de token invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.RevokeAPIToken(tokenID, user.ID); err != nil {
+		sendJSONError(w, "Token introuvable ou déjà révoqué", http.StatusNotFound)
+		return
+	}
+
+	h.sendJSONSuccess(w, "Token révoqué")
+}
+
+// availableScopes retourne les portées qu'un utilisateur peut accorder à ses tokens.
+// La portée moderate est ouverte aux modérateurs de catégorie : les droits sur chaque
+// catégorie sont vérifiés par les handlers.
+func availableScopes(user *models.User, moderator bool) []string {
+	var scopes []string
+	for _, scope := range models.APIScopes {
+		if scope == models.ScopeModerate && !moderator {
+			continue
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes
+}
@@ -1,36 +1,50 @@
 package handlers
 
 import (
+	"errors"
+	"html"
 	"html/template"
+	"log"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strings"
 
+	"aide-devoir-forum/auth"
 	"aide-devoir-forum/config"
 	"aide-devoir-forum/database"
+	"aide-devoir-forum/models"
 	"aide-devoir-forum/utils"
 )
 
 type AuthHandler struct {
-	repo      *database.Repository
-	config    *config.Config
-	templates *template.Template
+	repo        *database.Repository
+	config      *config.Config
+	templates   *template.Template
+	provider    auth.Provider
+	provisioner *auth.Provisioner
 }
 
 func NewAuthHandler(repo *database.Repository, cfg *config.Config, tmpl *template.Template) *AuthHandler {
 	return &AuthHandler{
-		repo:      repo,
-		config:    cfg,
-		templates: tmpl,
+		repo:        repo,
+		config:      cfg,
+		templates:   tmpl,
+		provider:    auth.NewProvider(cfg.Auth, repo),
+		provisioner: auth.NewProvisioner(repo, cfg.Auth.LDAP),
 	}
 }
 
 // GET /login
 func (h *AuthHandler) LoginPage(w http.ResponseWriter, r *http.Request) {
+	next := utils.SafeRedirectPath(r.URL.Query().Get("next"))
+
 	if h.templates != nil {
-		utils.RenderTemplate(w, h.templates, "login.html", nil)
+		utils.RenderTemplate(w, h.templates, "login.html", models.LoginPageData{Next: next})
 	} else {
 		utils.RenderSimplePage(w, "Connexion", `
 			<form method="POST" action="/login" class="form-container">
+				<input type="hidden" name="next" value="`+html.EscapeString(next)+`">
 				<div class="form-group">
 					<label for="username">Nom d'utilisateur :</label>
 					<input type="text" id="username" name="username" required>
@@ -59,24 +73,37 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Chemin où revenir après connexion (ex: demande d'autorisation OAuth)
+	next := utils.SafeRedirectPath(r.FormValue("next"))
+	nextParam := ""
+	if next != "" {
+		nextParam = "&next=" + url.QueryEscape(next)
+	}
+
 	username := r.FormValue("username")
 	password := r.FormValue("password")
 
 	if username == "" || password == "" {
-		http.Redirect(w, r, "/login?error=missing", http.StatusSeeOther)
+		http.Redirect(w, r, "/login?error=missing"+nextParam, http.StatusSeeOther)
 		return
 	}
 
-	// Récupérer l'utilisateur
-	user, err := h.repo.GetUserByUsername(username)
+	// Vérifier les identifiants auprès des fournisseurs configurés (comptes locaux, LDAP...)
+	identity, err := h.provider.Authenticate(username, password)
 	if err != nil {
-		http.Redirect(w, r, "/login?error=invalid", http.StatusSeeOther)
+		http.Redirect(w, r, "/login?error=invalid"+nextParam, http.StatusSeeOther)
 		return
 	}
 
-	// Vérifier le mot de passe
-	if !utils.CheckPasswordHash(password, user.Password) {
-		http.Redirect(w, r, "/login?error=invalid", http.StatusSeeOther)
+	// Récupérer le compte du forum (créé au premier login pour une identité externe)
+	user, err := h.provisioner.ResolveUser(identity)
+	if err != nil {
+		if errors.Is(err, auth.ErrAccountConflict) {
+			http.Redirect(w, r, "/login?error=conflict"+nextParam, http.StatusSeeOther)
+		} else {
+			log.Printf("Erreur de rattachement du compte %s (%s): %v", identity.Username, identity.Provider, err)
+			http.Redirect(w, r, "/login?error=provisioning"+nextParam, http.StatusSeeOther)
+		}
 		return
 	}
 
@@ -90,21 +117,36 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	token, err := utils.GenerateJWTToken(user.ID, user.Username, user.RoleID,
 		h.config.JWT.SecretKey, h.config.JWT.ExpirationTime)
 	if err != nil {
-		http.Redirect(w, r, "/login?error=token", http.StatusSeeOther)
+		http.Redirect(w, r, "/login?error=token"+nextParam, http.StatusSeeOther)
 		return
 	}
 
 	// Définir le cookie
 	utils.SetHTTPOnlyCookie(w, "token", token, h.config.JWT.ExpirationTime)
 
+	if next != "" {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+
 	// Rediriger vers l'accueil avec message de succès
 	http.Redirect(w, r, "/?success=login", http.StatusSeeOther)
 }
 
 // GET /register
 func (h *AuthHandler) RegisterPage(w http.ResponseWriter, r *http.Request) {
+	data := models.RegisterPageData{
+		Mode:       h.config.Registration.Mode,
+		InviteCode: utils.SanitizeInput(r.URL.Query().Get("invite")),
+	}
+
 	if h.templates != nil {
-		utils.RenderTemplate(w, h.templates, "register.html", nil)
+		utils.RenderTemplate(w, h.templates, "register.html", data)
+	} else if data.Mode == config.RegistrationClosed {
+		utils.RenderSimplePage(w, "Inscription", `
+			<p>Les inscriptions sont fermées.</p>
+			<p><a href="/login">Déjà un compte ? Se connecter</a></p>
+		`)
 	} else {
 		utils.RenderSimplePage(w, "Inscription", `
 			<form method="POST" action="/register" class="form-container">
@@ -122,6 +164,10 @@ func (h *AuthHandler) RegisterPage(w http.ResponseWriter, r *http.Request) {
 					<input type="password" id="password" name="password" required minlength="6">
 					<small>Minimum 6 caractères</small>
 				</div>
+				<div class="form-group">
+					<label for="invite_code">Code d'invitation :</label>
+					<input type="text" id="invite_code" name="invite_code" value="`+html.EscapeString(data.InviteCode)+`" maxlength="20">
+				</div>
 				<button type="submit" class="btn btn-primary">S'inscrire</button>
 			</form>
 			<p><a href="/login">Déjà un compte ? Se connecter</a></p>
@@ -136,53 +182,101 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.config.Registration.Mode == config.RegistrationClosed {
+		http.Redirect(w, r, "/register?error=closed", http.StatusSeeOther)
+		return
+	}
+
 	username := utils.SanitizeInput(r.FormValue("username"))
 	email := utils.SanitizeInput(r.FormValue("email"))
 	password := r.FormValue("password")
+	inviteCode := strings.ToUpper(utils.SanitizeInput(r.FormValue("invite_code")))
+
+	// Le code saisi est conservé dans l'URL pour ne pas avoir à le retaper
+	fail := func(code string) {
+		target := "/register?error=" + code
+		if inviteCode != "" {
+			target += "&invite=" + url.QueryEscape(inviteCode)
+		}
+		http.Redirect(w, r, target, http.StatusSeeOther)
+	}
 
 	// Validation
 	if !utils.IsValidUsername(username) {
-		http.Redirect(w, r, "/register?error=username", http.StatusSeeOther)
+		fail("username")
 		return
 	}
 
 	if !utils.IsValidEmail(email) {
-		http.Redirect(w, r, "/register?error=email", http.StatusSeeOther)
+		fail("email")
 		return
 	}
 
 	if len(password) < 6 {
-		http.Redirect(w, r, "/register?error=password", http.StatusSeeOther)
+		fail("password")
 		return
 	}
 
 	specialCharPattern := regexp.MustCompile(`[!@#\$%\^&\*\(\)\-\+=\[\]\{\};:'",<\.>/\?\\|` + "`" + `~]`)
 	if !specialCharPattern.MatchString(password) {
-		http.Redirect(w, r, "/register?error=password_special", http.StatusSeeOther)
+		fail("password_special")
+		return
+	}
+
+	if inviteCode == "" && h.config.Registration.Mode == config.RegistrationInviteOnly {
+		fail("invite_required")
 		return
 	}
 
 	// Vérifier si l'utilisateur existe déjà
 	existingUser, _ := h.repo.GetUserByUsername(username)
 	if existingUser.ID != 0 {
-		http.Redirect(w, r, "/register?error=exists", http.StatusSeeOther)
+		fail("exists")
 		return
 	}
 
 	// Hasher le mot de passe
 	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
-		http.Redirect(w, r, "/register?error=hash", http.StatusSeeOther)
+		fail("hash")
 		return
 	}
 
+	// Réserver une utilisation du code d'invitation : le rôle et la classe viennent du code
+	roleID := models.RoleUser
+	var invite *models.InviteCode
+	if inviteCode != "" {
+		invite, err = h.repo.GetInviteCodeByCode(inviteCode)
+		if err != nil || h.repo.ConsumeInviteCode(invite.ID) != nil {
+			fail("invite_invalid")
+			return
+		}
+		roleID = invite.RoleID
+	}
+
 	// Créer l'utilisateur
-	err = h.repo.CreateUser(username, email, hashedPassword)
+	inviteID := 0
+	if invite != nil {
+		inviteID = invite.ID
+	}
+	userID, err := h.repo.CreateUserWithInvite(username, email, hashedPassword, roleID, inviteID)
 	if err != nil {
-		http.Redirect(w, r, "/register?error=create", http.StatusSeeOther)
+		if invite != nil {
+			h.repo.ReleaseInviteCode(invite.ID)
+		}
+		fail("create")
 		return
 	}
 
+	if invite != nil {
+		log.Printf("🎟️  Compte %s créé avec le code d'invitation %s", username, invite.Code)
+		if invite.ClassGroupID != nil {
+			if err := h.repo.AddClassGroupMember(*invite.ClassGroupID, int(userID)); err != nil {
+				log.Printf("Erreur inscription de %s dans la classe %d: %v", username, *invite.ClassGroupID, err)
+			}
+		}
+	}
+
 	// Rediriger vers la page de connexion avec succès
 	http.Redirect(w, r, "/login?success=register", http.StatusSeeOther)
 }
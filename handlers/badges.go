@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+)
+
+// === BADGES ET NOTIFICATIONS ===
+
+// Nombre maximum de notifications affichées
+const notificationsLimit = 50
+
+// GET /badges
+// Badges liste tous les badges, leur niveau et, pour un utilisateur connecté, ceux qu'il a obtenus
+func (h *ProfileHandler) Badges(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	viewerID := 0
+	if user != nil {
+		viewerID = user.ID
+	}
+
+	badges, err := h.repo.GetBadgeCatalog(viewerID)
+	if err != nil {
+		log.Printf("Erreur récupération des badges: %v", err)
+		badges = []models.BadgeCatalogEntry{}
+	}
+
+	data := models.BadgesPageData{
+		User:   user,
+		Title:  "Badges",
+		Badges: badges,
+	}
+
+	h.renderTemplate(w, "badges.html", data)
+}
+
+// GET /notifications
+// Notifications affiche les dernières notifications de l'utilisateur et les marque comme lues
+func (h *ProfileHandler) Notifications(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	notifications, err := h.repo.GetNotifications(user.ID, notificationsLimit)
+	if err != nil {
+		log.Printf("Erreur récupération des notifications: %v", err)
+		notifications = []models.Notification{}
+	}
+
+	// Les notifications gardent leur état "non lue" pour cet affichage
+	if user.UnreadNotifications > 0 {
+		if err := h.repo.MarkNotificationsRead(user.ID); err != nil {
+			log.Printf("Erreur marquage des notifications: %v", err)
+		} else {
+			user.UnreadNotifications = 0
+		}
+	}
+
+	data := models.NotificationsPageData{
+		User:          user,
+		Title:         "Notifications",
+		Notifications: notifications,
+	}
+
+	h.renderTemplate(w, "notifications.html", data)
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// === FAVORIS ===
+
+// GET /bookmarks
+// Bookmarks affiche les questions et réponses enregistrées par l'utilisateur, avec recherche
+// (q) et filtres par collection (collection, "none" = sans collection), type (kind) et catégorie
+func (h *ForumHandler) Bookmarks(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	filter := models.BookmarkFilter{
+		Query: utils.SanitizeInput(r.URL.Query().Get("q")),
+	}
+	if collection := r.URL.Query().Get("collection"); collection == "none" {
+		filter.Unsorted = true
+	} else {
+		filter.CollectionID, _ = strconv.Atoi(collection)
+	}
+	if kind := r.URL.Query().Get("kind"); kind == models.BookmarkKindPost || kind == models.BookmarkKindComment {
+		filter.Kind = kind
+	}
+	filter.CategoryID, _ = strconv.Atoi(r.URL.Query().Get("category"))
+
+	bookmarks, err := h.repo.GetBookmarks(user, filter)
+	if err != nil {
+		log.Printf("Erreur récupération des favoris: %v", err)
+		bookmarks = []models.Bookmark{}
+	}
+
+	collections, err := h.repo.GetBookmarkCollections(user.ID)
+	if err != nil {
+		log.Printf("Erreur récupération des collections: %v", err)
+		collections = []models.BookmarkCollection{}
+	}
+
+	categoryTree, _ := h.repo.GetCategories(user)
+
+	data := models.BookmarksPageData{
+		User:        user,
+		Title:       "Mes favoris",
+		Bookmarks:   bookmarks,
+		Collections: collections,
+		Categories:  models.FlattenCategories(categoryTree),
+		Filter:      filter,
+	}
+
+	if h.templates == nil {
+		http.Error(w, "Templates non disponibles", http.StatusInternalServerError)
+		return
+	}
+	if err := utils.ExecuteTemplate(w, h.templates, "bookmarks.html", data); err != nil {
+		http.Error(w, "Erreur de rendu", http.StatusInternalServerError)
+	}
+}
+
+// POST /bookmarks/toggle
+// ToggleBookmark ajoute ou retire (action "add" ou "remove") une question (post_id) ou une
+// réponse (comment_id) des favoris de l'utilisateur
+func (h *ForumHandler) ToggleBookmark(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	postID, _ := strconv.Atoi(r.FormValue("post_id"))
+	commentID, _ := strconv.Atoi(r.FormValue("comment_id"))
+	if commentID > 0 {
+		var err error
+		if postID, err = h.repo.GetCommentPostID(commentID); err != nil {
+			sendJSONError(w, "Réponse non trouvée", http.StatusNotFound)
+			return
+		}
+	} else {
+		commentID = 0
+	}
+	add := r.FormValue("action") != "remove"
+
+	// La question doit rester lisible pour être enregistrée (un retrait reste toujours possible)
+	if _, err := h.repo.GetPost(postID, user); err != nil && add {
+		sendJSONError(w, "Post non trouvé", http.StatusNotFound)
+		return
+	}
+
+	var err error
+	message := "Ajouté à vos favoris"
+	if add {
+		err = h.repo.AddBookmark(user.ID, postID, commentID)
+	} else {
+		err = h.repo.RemoveBookmark(user.ID, postID, commentID)
+		message = "Retiré de vos favoris"
+	}
+	if err != nil {
+		log.Printf("Erreur favori (post %d, réponse %d): %v", postID, commentID, err)
+		sendJSONError(w, "Erreur lors de la mise à jour des favoris", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "success",
+		"message":    message,
+		"bookmarked": add,
+	})
+}
+
+// POST /bookmarks/update
+// UpdateBookmark modifie la note et la collection (collection_id vide = aucune) d'un favori
+func (h *ForumHandler) UpdateBookmark(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	bookmarkID, err := strconv.Atoi(r.FormValue("bookmark_id"))
+	if err != nil {
+		sendJSONError(w, "ID de favori invalide", http.StatusBadRequest)
+		return
+	}
+
+	note := utils.SanitizeInput(r.FormValue("note"))
+	if utf8.RuneCountInString(note) > models.MaxBookmarkNoteLength {
+		sendJSONError(w, "La note ne peut pas dépasser 1000 caractères", http.StatusBadRequest)
+		return
+	}
+
+	var collectionID *int
+	if id, err := strconv.Atoi(r.FormValue("collection_id")); err == nil && id > 0 {
+		collectionID = &id
+	}
+
+	if err := h.repo.UpdateBookmark(bookmarkID, user.ID, note, collectionID); err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Favori ou collection non trouvé", http.StatusNotFound)
+			return
+		}
+		log.Printf("Erreur mise à jour du favori %d: %v", bookmarkID, err)
+		sendJSONError(w, "Erreur lors de la mise à jour du favori", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Favori mis à jour",
+	})
+}
+
+// bookmarkCollectionName lit et valide le nom d'une collection
+func bookmarkCollectionName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := utils.SanitizeInput(r.FormValue("name"))
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		sendJSONError(w, "Le nom de la collection doit contenir entre 1 et 100 caractères", http.StatusBadRequest)
+		return "", false
+	}
+	return name, true
+}
+
+// POST /bookmarks/collections
+// CreateBookmarkCollection crée une collection de favoris
+func (h *ForumHandler) CreateBookmarkCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	name, ok := bookmarkCollectionName(w, r)
+	if !ok {
+		return
+	}
+
+	collectionID, err := h.repo.CreateBookmarkCollection(user.ID, name)
+	if err != nil {
+		sendJSONError(w, "Création impossible : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":        "success",
+		"message":       "Collection créée",
+		"collection_id": collectionID,
+	})
+}
+
+// POST /bookmarks/collections/rename
+// RenameBookmarkCollection renomme une collection de favoris
+func (h *ForumHandler) RenameBookmarkCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	collectionID, err := strconv.Atoi(r.FormValue("collection_id"))
+	if err != nil {
+		sendJSONError(w, "ID de collection invalide", http.StatusBadRequest)
+		return
+	}
+	name, ok := bookmarkCollectionName(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.RenameBookmarkCollection(collectionID, user.ID, name); err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Collection non trouvée", http.StatusNotFound)
+			return
+		}
+		sendJSONError(w, "Renommage impossible : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Collection renommée",
+	})
+}
+
+// POST /bookmarks/collections/delete
+// DeleteBookmarkCollection supprime une collection ; ses favoris sont conservés sans collection
+func (h *ForumHandler) DeleteBookmarkCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	collectionID, err := strconv.Atoi(r.FormValue("collection_id"))
+	if err != nil {
+		sendJSONError(w, "ID de collection invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.DeleteBookmarkCollection(collectionID, user.ID); err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Collection non trouvée", http.StatusNotFound)
+			return
+		}
+		log.Printf("Erreur suppression de la collection %d: %v", collectionID, err)
+		sendJSONError(w, "Erreur lors de la suppression de la collection", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Collection supprimée",
+	})
+}
+
+// GET /bookmarks/export?collection=
+// ExportBookmarkCollection affiche une collection sous forme de fiche de révision imprimable :
+// chaque question ou réponse en entier, avec la note de l'utilisateur
+func (h *ForumHandler) ExportBookmarkCollection(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	collectionID, err := strconv.Atoi(r.URL.Query().Get("collection"))
+	if err != nil {
+		http.Error(w, "ID de collection invalide", http.StatusBadRequest)
+		return
+	}
+	collection, err := h.repo.GetBookmarkCollection(collectionID, user.ID)
+	if err != nil {
+		http.Error(w, "Collection non trouvée", http.StatusNotFound)
+		return
+	}
+
+	bookmarks, err := h.repo.GetBookmarks(user, models.BookmarkFilter{CollectionID: collection.ID})
+	if err != nil {
+		log.Printf("Erreur récupération de la collection %d: %v", collection.ID, err)
+		http.Error(w, "Erreur lors de l'export", http.StatusInternalServerError)
+		return
+	}
+	// Fiche de révision : les plus anciens d'abord, dans l'ordre où ils ont été rassemblés
+	for i, j := 0, len(bookmarks)-1; i < j; i, j = i+1, j-1 {
+		bookmarks[i], bookmarks[j] = bookmarks[j], bookmarks[i]
+	}
+	collection.BookmarksCount = len(bookmarks)
+
+	data := models.RevisionSheetPageData{
+		User:        user,
+		Title:       "Fiche de révision : " + collection.Name,
+		Collection:  *collection,
+		Bookmarks:   bookmarks,
+		GeneratedAt: time.Now(),
+	}
+
+	if h.templates == nil {
+		http.Error(w, "Templates non disponibles", http.StatusInternalServerError)
+		return
+	}
+	if err := utils.ExecuteTemplate(w, h.templates, "revision-sheet.html", data); err != nil {
+		http.Error(w, "Erreur de rendu", http.StatusInternalServerError)
+	}
+}
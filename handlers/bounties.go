@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"aide-devoir-forum/middleware"
+)
+
+// === PRIMES ===
+
+// Nombre de questions avec prime mises en avant sur l'accueil et les catégories
+const featuredBountiesLimit = 5
+
+// POST /offer-bounty
+// OfferBounty permet à l'auteur d'une question ouverte et non résolue d'offrir une prime
+// prélevée sur sa réputation, pour la durée configurée
+func (h *ForumHandler) OfferBounty(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non autorisé", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil || postID <= 0 {
+		sendJSONError(w, "ID de post invalide", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.repo.GetPost(postID, user)
+	if err != nil {
+		sendJSONError(w, "Post non trouvé", http.StatusNotFound)
+		return
+	}
+
+	if post.UserID != user.ID {
+		sendJSONError(w, "Seul l'auteur de la question peut offrir une prime", http.StatusForbidden)
+		return
+	}
+	if post.Status != "open" || post.IsSolved {
+		sendJSONError(w, "Une prime ne peut être offerte que sur une question ouverte et non résolue", http.StatusBadRequest)
+		return
+	}
+
+	rules := h.config.Bounty
+	amount, err := strconv.Atoi(r.FormValue("amount"))
+	if err != nil || amount < rules.MinAmount || amount > rules.MaxAmount {
+		sendJSONError(w, fmt.Sprintf("Le montant doit être compris entre %d et %d points", rules.MinAmount, rules.MaxAmount),
+			http.StatusBadRequest)
+		return
+	}
+	if user.Reputation < amount {
+		sendJSONError(w, "Réputation insuffisante pour offrir cette prime", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.OfferBounty(postID, user.ID, amount, rules.DurationDays); err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Une prime est déjà en cours sur cette question", http.StatusConflict)
+			return
+		}
+		log.Printf("Erreur création de prime: %v", err)
+		sendJSONError(w, "Erreur lors de la création de la prime", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": fmt.Sprintf("Prime de %d points offerte pour %d jours", amount, rules.DurationDays),
+	})
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"aide-devoir-forum/config"
+	"aide-devoir-forum/database"
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// Nombre maximum d'élèves inscrits en une seule fois
+const maxClassEnrollBatch = 100
+
+// Couleur (#rrggbb) et icône Font Awesome (ex: "calculator") des catégories de classe
+var (
+	categoryColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	categoryIconPattern  = regexp.MustCompile(`^[a-z0-9-]{1,50}$`)
+)
+
+type ClassHandler struct {
+	repo      *database.Repository
+	config    *config.Config
+	templates *template.Template
+}
+
+func NewClassHandler(repo *database.Repository, cfg *config.Config, tmpl *template.Template) *ClassHandler {
+	return &ClassHandler{
+		repo:      repo,
+		config:    cfg,
+		templates: tmpl,
+	}
+}
+
+// GET /classes
+// Page de gestion des classes du professeur (toutes pour un administrateur)
+func (h *ClassHandler) Page(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	ownerID := user.ID
+	if user.IsAdmin() {
+		ownerID = 0
+	}
+
+	groups, err := h.repo.GetClassGroupsByOwner(ownerID)
+	if err != nil {
+		log.Printf("Erreur récupération des classes: %v", err)
+	}
+
+	for i := range groups {
+		groups[i].Members, _ = h.repo.GetClassGroupMembers(groups[i].ID)
+		groups[i].Categories, _ = h.repo.GetClassGroupCategories(groups[i].ID)
+	}
+
+	data := models.ClassesPageData{
+		User:        user,
+		Title:       "Mes classes",
+		ClassGroups: groups,
+	}
+
+	if h.templates == nil {
+		http.Error(w, "Templates non disponibles", http.StatusInternalServerError)
+		return
+	}
+	if err := utils.ExecuteTemplate(w, h.templates, "classes.html", data); err != nil {
+		http.Error(w, "Erreur de rendu", http.StatusInternalServerError)
+	}
+}
+
+// POST /classes/create
+func (h *ClassHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+
+	name := utils.SanitizeInput(r.FormValue("name"))
+	if len(name) < 2 || len(name) > 100 {
+		sendJSONError(w, "Le nom de la classe doit faire entre 2 et 100 caractères", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.repo.CreateClassGroup(name, user.ID); err != nil {
+		log.Printf("Erreur création de la classe: %v", err)
+		sendJSONError(w, "Erreur lors de la création de la classe", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("🏫 Classe %q créée par %s", name, user.Username)
+	h.sendJSONSuccess(w, "Classe créée")
+}
+
+// POST /classes/delete
+func (h *ClassHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	group, ok := h.ownedClassGroup(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteClassGroup(group.ID); err != nil {
+		sendJSONError(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	log.Printf("🏫 Classe %q supprimée par %s", group.Name, user.Username)
+	h.sendJSONSuccess(w, "Classe supprimée")
+}
+
+// POST /classes/members/add
+// AddMembers inscrit des élèves par nom d'utilisateur (séparés par des virgules ou des retours à la ligne)
+func (h *ClassHandler) AddMembers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	group, ok := h.ownedClassGroup(w, r, user)
+	if !ok {
+		return
+	}
+
+	usernames := utils.RemoveDuplicates(strings.FieldsFunc(r.FormValue("usernames"), func(c rune) bool {
+		return c == ',' || c == ';' || c == '\n' || c == '\r' || c == ' ' || c == '\t'
+	}))
+	if len(usernames) == 0 || len(usernames) > maxClassEnrollBatch {
+		sendJSONError(w, "Indiquez entre 1 et 100 noms d'utilisateur", http.StatusBadRequest)
+		return
+	}
+
+	var added int
+	var unknown []string
+	for _, username := range usernames {
+		member, err := h.repo.GetUserByUsername(username)
+		if err != nil || member.ID == 0 {
+			unknown = append(unknown, username)
+			continue
+		}
+		if err := h.repo.AddClassGroupMember(group.ID, member.ID); err != nil {
+			log.Printf("Erreur inscription de %s dans la classe %d: %v", username, group.ID, err)
+			unknown = append(unknown, username)
+			continue
+		}
+		added++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": strconv.Itoa(added) + " élève(s) inscrit(s)",
+		"unknown": unknown,
+	})
+}
+
+// POST /classes/members/remove
+func (h *ClassHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	group, ok := h.ownedClassGroup(w, r, user)
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil || memberID <= 0 {
+		sendJSONError(w, "ID d'utilisateur invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.RemoveClassGroupMember(group.ID, memberID); err != nil {
+		sendJSONError(w, "Élève introuvable dans cette classe", http.StatusNotFound)
+		return
+	}
+
+	h.sendJSONSuccess(w, "Élève retiré de la classe")
+}
+
+// POST /classes/categories/create
+// CreateCategory crée une catégorie privée, visible uniquement des membres de la classe
+func (h *ClassHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	group, ok := h.ownedClassGroup(w, r, user)
+	if !ok {
+		return
+	}
+
+	name := utils.SanitizeInput(r.FormValue("name"))
+	if len(name) < 2 || len(name) > 100 {
+		sendJSONError(w, "Le nom de la catégorie doit faire entre 2 et 100 caractères", http.StatusBadRequest)
+		return
+	}
+
+	description := utils.SanitizeInput(r.FormValue("description"))
+
+	color := r.FormValue("color")
+	if !categoryColorPattern.MatchString(color) {
+		color = "#20c997"
+	}
+
+	icon := r.FormValue("icon")
+	if !categoryIconPattern.MatchString(icon) {
+		icon = "users"
+	}
+
+	if err := h.repo.CreateClassCategory(name, description, color, icon, group.ID); err != nil {
+		log.Printf("Erreur création de la catégorie de classe: %v", err)
+		sendJSONError(w, "Erreur lors de la création (une catégorie porte peut-être déjà ce nom)", http.StatusConflict)
+		return
+	}
+
+	log.Printf("🔒 Catégorie privée %q créée pour la classe %q par %s", name, group.Name, user.Username)
+	h.sendJSONSuccess(w, "Catégorie privée créée")
+}
+
+// ownedClassGroup charge la classe visée par la requête et vérifie que l'utilisateur
+// en est le professeur (ou administrateur). Écrit la réponse d'erreur sinon.
+func (h *ClassHandler) ownedClassGroup(w http.ResponseWriter, r *http.Request, user *models.User) (*models.ClassGroup, bool) {
+	if user == nil {
+		sendJSONError(w, "Non authentifié", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	groupID, err := strconv.Atoi(r.FormValue("class_group_id"))
+	if err != nil || groupID <= 0 {
+		sendJSONError(w, "ID de classe invalide", http.StatusBadRequest)
+		return nil, false
+	}
+
+	group, err := h.repo.GetClassGroup(groupID)
+	if err != nil || (group.OwnerID != user.ID && !user.IsAdmin()) {
+		sendJSONError(w, "Classe introuvable", http.StatusNotFound)
+		return nil, false
+	}
+
+	return group, true
+}
+
+// sendJSONSuccess envoie une réponse JSON de succès
+func (h *ClassHandler) sendJSONSuccess(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// === BROUILLONS ===
+
+// GET /drafts
+// Drafts affiche les brouillons de question et de réponse de l'utilisateur, ainsi que
+// ses publications programmées
+func (h *ForumHandler) Drafts(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	drafts, err := h.repo.GetUserDrafts(user.ID)
+	if err != nil {
+		log.Printf("Erreur récupération des brouillons: %v", err)
+		drafts = []models.Draft{}
+	}
+
+	scheduledPosts, err := h.repo.GetScheduledPosts(user.ID)
+	if err != nil {
+		log.Printf("Erreur récupération des posts programmés: %v", err)
+	}
+
+	data := models.DraftsPageData{
+		User:           user,
+		Title:          "Mes brouillons",
+		Drafts:         drafts,
+		ScheduledPosts: scheduledPosts,
+	}
+
+	if h.templates == nil {
+		http.Error(w, "Templates non disponibles", http.StatusInternalServerError)
+		return
+	}
+	if err := utils.ExecuteTemplate(w, h.templates, "drafts.html", data); err != nil {
+		http.Error(w, "Erreur de rendu", http.StatusInternalServerError)
+	}
+}
+
+// POST /drafts/save
+// SaveDraft enregistre automatiquement le contenu d'un formulaire de question ou de réponse
+func (h *ForumHandler) SaveDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non autorisé", http.StatusUnauthorized)
+		return
+	}
+
+	draft := &models.Draft{
+		UserID:  user.ID,
+		Kind:    r.FormValue("kind"),
+		Content: utils.SanitizeInput(r.FormValue("content")),
+	}
+	draft.ID, _ = strconv.Atoi(r.FormValue("draft_id"))
+
+	switch draft.Kind {
+	case models.DraftKindPost:
+		draft.Title = truncateRunes(utils.SanitizeInput(r.FormValue("title")), 255)
+		draft.Tags = truncateRunes(utils.SanitizeInput(r.FormValue("tags")), 255)
+		if categoryID, err := strconv.Atoi(r.FormValue("category_id")); err == nil && categoryID > 0 {
+			draft.CategoryID = &categoryID
+		}
+		if level := r.FormValue("level"); models.SchoolLevelLabel(level) != "" {
+			draft.Level = level
+		}
+		if dueAt, err := parseDateTimeInput(r.FormValue("due_at")); err == nil && dueAt != nil {
+			draft.DueAt = dueAt.Format(dateTimeInputLayout)
+		}
+		if publishAt, err := parseDateTimeInput(r.FormValue("publish_at")); err == nil && publishAt != nil {
+			draft.PublishAt = publishAt.Format(dateTimeInputLayout)
+		}
+
+	case models.DraftKindComment:
+		postID, err := strconv.Atoi(r.FormValue("post_id"))
+		if err != nil || postID <= 0 {
+			sendJSONError(w, "ID de post invalide", http.StatusBadRequest)
+			return
+		}
+		if _, err := h.repo.GetPost(postID, user); err != nil {
+			sendJSONError(w, "Post non trouvé", http.StatusNotFound)
+			return
+		}
+		draft.PostID = &postID
+		if parentID, err := strconv.Atoi(r.FormValue("parent_id")); err == nil && parentID > 0 {
+			draft.ParentID = &parentID
+		}
+
+	default:
+		sendJSONError(w, "Type de brouillon invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.SaveDraft(draft); err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Brouillon non trouvé", http.StatusNotFound)
+			return
+		}
+		log.Printf("Erreur enregistrement du brouillon: %v", err)
+		sendJSONError(w, "Erreur lors de l'enregistrement du brouillon", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"draft_id": draft.ID,
+	})
+}
+
+// POST /drafts/images
+// UploadDraftImage ajoute des images en attente à un brouillon
+func (h *ForumHandler) UploadDraftImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non autorisé", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		sendJSONError(w, "Erreur lors du parsing du formulaire", http.StatusBadRequest)
+		return
+	}
+
+	draftID, _ := strconv.Atoi(r.FormValue("draft_id"))
+	draft, err := h.repo.GetDraft(draftID, user.ID)
+	if err != nil {
+		sendJSONError(w, "Brouillon non trouvé", http.StatusNotFound)
+		return
+	}
+
+	maxImages := utils.MaxImagesCount
+	if draft.Kind == models.DraftKindComment {
+		maxImages = utils.MaxCommentImageCount
+	}
+	files := r.MultipartForm.File["images"]
+	if len(draft.Images)+len(files) > maxImages {
+		sendJSONError(w, fmt.Sprintf("Maximum %d images autorisées", maxImages), http.StatusBadRequest)
+		return
+	}
+
+	var uploadErrors []string
+	for _, fileHeader := range files {
+		file, err := fileHeader.Open()
+		if err != nil {
+			uploadErrors = append(uploadErrors, fmt.Sprintf("Erreur ouverture %s", fileHeader.Filename))
+			continue
+		}
+		imageInfo, err := utils.SaveImageFile(file, fileHeader)
+		file.Close()
+		if err != nil {
+			uploadErrors = append(uploadErrors, fmt.Sprintf("Erreur upload %s: %v", fileHeader.Filename, err))
+			continue
+		}
+
+		image := &models.Image{
+			Filename:     imageInfo.Filename,
+			OriginalName: imageInfo.OriginalName,
+			ContentType:  imageInfo.ContentType,
+			SizeBytes:    imageInfo.SizeBytes,
+			Width:        imageInfo.Width,
+			Height:       imageInfo.Height,
+			DraftID:      utils.IntPtr(draft.ID),
+			UserID:       user.ID,
+		}
+		if err := h.repo.AddDraftImage(image); err != nil {
+			utils.DeleteImageFile(imageInfo.Filename)
+			uploadErrors = append(uploadErrors, fmt.Sprintf("Erreur DB pour %s", fileHeader.Filename))
+		}
+	}
+
+	images, _ := h.repo.GetDraftImages(draft.ID)
+	if images == nil {
+		images = []models.Image{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := map[string]interface{}{"status": "success", "images": images}
+	if len(uploadErrors) > 0 {
+		response["upload_warnings"] = uploadErrors
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /drafts/images/delete
+// DeleteDraftImage retire une image en attente d'un brouillon
+func (h *ForumHandler) DeleteDraftImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non autorisé", http.StatusUnauthorized)
+		return
+	}
+
+	imageID, err := strconv.Atoi(r.FormValue("image_id"))
+	if err != nil || imageID <= 0 {
+		sendJSONError(w, "ID d'image invalide", http.StatusBadRequest)
+		return
+	}
+
+	filename, err := h.repo.DeleteDraftImage(imageID, user.ID)
+	if err != nil {
+		sendJSONError(w, "Image non trouvée", http.StatusNotFound)
+		return
+	}
+	utils.DeleteImageFile(filename)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// POST /drafts/delete
+// DeleteDraft supprime un brouillon et ses images en attente
+func (h *ForumHandler) DeleteDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non autorisé", http.StatusUnauthorized)
+		return
+	}
+
+	draftID, err := strconv.Atoi(r.FormValue("draft_id"))
+	if err != nil || draftID <= 0 {
+		sendJSONError(w, "ID de brouillon invalide", http.StatusBadRequest)
+		return
+	}
+
+	filenames, err := h.repo.DeleteDraft(draftID, user.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Brouillon non trouvé", http.StatusNotFound)
+			return
+		}
+		log.Printf("Erreur suppression du brouillon: %v", err)
+		sendJSONError(w, "Erreur lors de la suppression du brouillon", http.StatusInternalServerError)
+		return
+	}
+	for _, filename := range filenames {
+		utils.DeleteImageFile(filename)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Brouillon supprimé",
+	})
+}
+
+// publishDraft rattache les images en attente du brouillon indiqué dans le formulaire au
+// post ou au commentaire publié, puis supprime le brouillon
+func (h *ForumHandler) publishDraft(r *http.Request, userID int, postID, commentID *int) {
+	draftID, err := strconv.Atoi(r.FormValue("draft_id"))
+	if err != nil || draftID <= 0 {
+		return
+	}
+	if err := h.repo.PublishDraft(draftID, userID, postID, commentID); err != nil {
+		log.Printf("Erreur publication du brouillon %d: %v", draftID, err)
+	}
+}
+
+// pendingDraftImages compte les images en attente du brouillon indiqué dans le formulaire
+func (h *ForumHandler) pendingDraftImages(r *http.Request, userID int) int {
+	draftID, err := strconv.Atoi(r.FormValue("draft_id"))
+	if err != nil || draftID <= 0 {
+		return 0
+	}
+	draft, err := h.repo.GetDraft(draftID, userID)
+	if err != nil {
+		return 0
+	}
+	return len(draft.Images)
+}
+
+// truncateRunes limite une chaîne à max caractères
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+)
+
+// === DOUBLONS ===
+
+// duplicatePostFromRequest lit le post visé et vérifie la méthode et l'authentification
+func (h *ForumHandler) duplicatePostFromRequest(w http.ResponseWriter, r *http.Request) (*models.User, *models.Post, bool) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return nil, nil, false
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non autorisé", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	postID, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil || postID <= 0 {
+		sendJSONError(w, "ID de post invalide", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	post, err := h.repo.GetPost(postID, user)
+	if err != nil {
+		sendJSONError(w, "Post non trouvé", http.StatusNotFound)
+		return nil, nil, false
+	}
+	return user, post, true
+}
+
+// canMarkDuplicate : professeurs, modérateurs globaux et modérateurs de la catégorie
+func (h *ForumHandler) canMarkDuplicate(user *models.User, post *models.Post) bool {
+	if user.IsProfessor() {
+		return true
+	}
+	permissions, err := h.repo.GetPostPermissions(post.ID, user)
+	return err == nil && permissions.CanModerate
+}
+
+// POST /mark-duplicate
+// MarkDuplicate marque un post comme doublon d'une autre question, ce qui le ferme
+func (h *ForumHandler) MarkDuplicate(w http.ResponseWriter, r *http.Request) {
+	user, post, ok := h.duplicatePostFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.canMarkDuplicate(user, post) {
+		sendJSONError(w, "Permission refusée", http.StatusForbidden)
+		return
+	}
+
+	canonicalID, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(r.FormValue("duplicate_of")), "#"))
+	if err != nil || canonicalID <= 0 {
+		sendJSONError(w, "Numéro de la question d'origine invalide", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.repo.GetPost(canonicalID, user); err != nil {
+		sendJSONError(w, "Question d'origine non trouvée", http.StatusNotFound)
+		return
+	}
+
+	if err := h.repo.MarkPostDuplicate(post.ID, canonicalID, user.ID); err != nil {
+		sendJSONError(w, "Marquage impossible : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.repo.CreateModerationLog(user.ID, "mark_duplicate", "post", post.ID,
+		"Doublon du post #"+strconv.Itoa(canonicalID))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Post marqué comme doublon",
+	})
+}
+
+// POST /unmark-duplicate
+// UnmarkDuplicate retire le marquage de doublon (professeurs et modérateurs)
+func (h *ForumHandler) UnmarkDuplicate(w http.ResponseWriter, r *http.Request) {
+	user, post, ok := h.duplicatePostFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.canMarkDuplicate(user, post) {
+		sendJSONError(w, "Permission refusée", http.StatusForbidden)
+		return
+	}
+
+	if err := h.repo.UnmarkPostDuplicate(post.ID); err != nil {
+		sendJSONError(w, "Ce post n'est pas marqué comme doublon", http.StatusBadRequest)
+		return
+	}
+
+	h.repo.CreateModerationLog(user.ID, "unmark_duplicate", "post", post.ID, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Marquage de doublon retiré",
+	})
+}
+
+// POST /contest-duplicate
+// ContestDuplicate permet à l'auteur de contester une seule fois le marquage de son post.
+// Le post reste marqué jusqu'à ce qu'un modérateur retire le marquage.
+func (h *ForumHandler) ContestDuplicate(w http.ResponseWriter, r *http.Request) {
+	user, post, ok := h.duplicatePostFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if post.UserID != user.ID {
+		sendJSONError(w, "Seul l'auteur peut contester le marquage", http.StatusForbidden)
+		return
+	}
+
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	if reason == "" {
+		sendJSONError(w, "Expliquez en quoi votre question est différente", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.ContestPostDuplicate(post.ID); err != nil {
+		sendJSONError(w, "Le marquage a déjà été contesté", http.StatusBadRequest)
+		return
+	}
+
+	h.repo.CreateModerationLog(user.ID, "contest_duplicate", "post", post.ID, reason)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Contestation envoyée : un modérateur va réexaminer le marquage",
+	})
+}
@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"aide-devoir-forum/config"
 	"aide-devoir-forum/database"
@@ -34,22 +37,29 @@ func (h *ForumHandler) Home(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUserFromContext(r.Context())
 
 	// Récupérer les catégories
-	categories, err := h.repo.GetCategories()
+	categories, err := h.repo.GetCategories(user)
 	if err != nil {
 		categories = []models.Category{}
 	}
 
 	// Récupérer les posts récents
-	recentPosts, err := h.repo.GetRecentPosts(10)
+	recentPosts, err := h.repo.GetRecentPosts(10, user)
 	if err != nil {
 		recentPosts = []models.Post{}
 	}
 
+	// Questions avec une prime en cours
+	bountyPosts, err := h.repo.GetBountyPosts(0, user, featuredBountiesLimit)
+	if err != nil {
+		bountyPosts = []models.Post{}
+	}
+
 	data := models.HomePageData{
 		Categories:  categories,
 		RecentPosts: recentPosts,
 		User:        user,
 		Title:       "Accueil",
+		BountyPosts: bountyPosts,
 	}
 
 	if h.templates != nil {
@@ -97,24 +107,50 @@ func (h *ForumHandler) Category(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Récupérer la catégorie
+	// Récupérer la catégorie (une catégorie privée n'existe pas pour les non-membres)
 	category, err := h.repo.GetCategory(categoryID)
 	if err != nil {
 		http.Error(w, "Catégorie non trouvée", http.StatusNotFound)
 		return
 	}
 
-	// Récupérer les posts de la catégorie
-	posts, err := h.repo.GetPostsByCategory(categoryID)
+	permissions := h.repo.GetCategoryPermissions(category, user)
+	if !permissions.CanRead {
+		http.Error(w, "Catégorie non trouvée", http.StatusNotFound)
+		return
+	}
+
+	// Récupérer les posts de la catégorie, filtrés par niveau et date de rendu
+	filter := parsePostFilter(r)
+	posts, err := h.repo.GetPostsByCategory(categoryID, user, filter)
 	if err != nil {
 		posts = []models.Post{}
 	}
 
+	// Questions de la catégorie avec une prime en cours
+	bountyPosts, err := h.repo.GetBountyPosts(categoryID, user, featuredBountiesLimit)
+	if err != nil {
+		bountyPosts = []models.Post{}
+	}
+
+	// Fil d'Ariane et sous-catégories visibles
+	breadcrumbs, _ := h.repo.GetCategoryAncestors(categoryID, user)
+	subcategories, err := h.repo.GetSubcategories(categoryID, user)
+	if err != nil {
+		subcategories = []models.Category{}
+	}
+
 	data := models.CategoryPageData{
-		Category: *category,
-		Posts:    posts,
-		User:     user,
-		Title:    category.Name,
+		Category:      *category,
+		Breadcrumbs:   breadcrumbs,
+		Subcategories: subcategories,
+		Posts:         posts,
+		User:          user,
+		Title:         category.Name,
+		Permissions:   permissions,
+		BountyPosts:   bountyPosts,
+		Filter:        filter,
+		IsSubscribed:  user != nil && h.repo.IsSubscribed(user.ID, models.SubscriptionCategory, category.ID),
 	}
 
 	if h.templates != nil {
@@ -175,6 +211,12 @@ func (h *ForumHandler) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Un doublon fusionné redirige vers le post canonique
+	if post.MergedIntoID != nil {
+		http.Redirect(w, r, fmt.Sprintf("/post/%d?merged=%d", *post.MergedIntoID, post.ID), http.StatusMovedPermanently)
+		return
+	}
+
 	// Vérifier si l'utilisateur peut voir ce post (en particulier pour les posts archivés)
 	userID := 0
 	userRoleID := 0
@@ -183,13 +225,21 @@ func (h *ForumHandler) Post(w http.ResponseWriter, r *http.Request) {
 		userRoleID = user.RoleID
 	}
 
-	if !post.CanBeViewedBy(userID, userRoleID) {
+	permissions, err := h.repo.GetPostPermissions(postID, user)
+	if err != nil || !permissions.CanRead {
 		http.Error(w, "Post non trouvé", http.StatusNotFound)
 		return
 	}
 
-	// Incrémenter les vues
-	h.repo.IncrementPostViews(postID)
+	if !post.CanBeViewedBy(userID, userRoleID) && !permissions.CanModerate {
+		http.Error(w, "Post non trouvé", http.StatusNotFound)
+		return
+	}
+
+	// Incrémenter les vues (sauf avant la publication d'un post programmé)
+	if post.Status != models.PostStatusScheduled {
+		h.repo.IncrementPostViews(postID)
+	}
 
 	// Récupérer le paramètre de tri des commentaires
 	sortBy := r.URL.Query().Get("sort")
@@ -203,18 +253,71 @@ func (h *ForumHandler) Post(w http.ResponseWriter, r *http.Request) {
 		comments = []models.Comment{}
 	}
 
+	breadcrumbs, _ := h.repo.GetCategoryAncestors(post.CategoryID, user)
+
+	multiSolution, allowAnonymous := false, false
+	if category, err := h.repo.GetCategory(post.CategoryID); err == nil {
+		multiSolution = category.AllowMultipleSolutions
+		allowAnonymous = category.AllowAnonymous
+	}
+
+	// Prime en cours ou dernière prime attribuée
+	post.Bounty, _ = h.repo.GetOpenBounty(postID)
+	awardedBounty, _ := h.repo.GetLastAwardedBounty(postID)
+
+	// Sondage attaché au post
+	post.Poll, _ = h.repo.GetPostPoll(postID, userID)
+
+	// Catégories proposées pour le déplacement du post : celles que l'utilisateur modère
+	var moveCategories []models.Category
+	if permissions.CanModerate {
+		categoryTree, _ := h.repo.GetCategories(user)
+		for _, category := range models.FlattenCategories(categoryTree) {
+			if h.repo.GetCategoryPermissions(&category, user).CanModerate {
+				moveCategories = append(moveCategories, category)
+			}
+		}
+	}
+
+	// Brouillons de réponse de l'utilisateur sur cette question
+	var commentDrafts []models.Draft
+	if user != nil && permissions.CanComment {
+		commentDrafts, _ = h.repo.GetCommentDrafts(user.ID, postID)
+	}
+
+	// Date de rendu dépassée depuis longtemps : suggérer l'archivage à ceux qui peuvent changer le statut
+	suggestArchive := user != nil && post.ShouldSuggestArchive(time.Now()) &&
+		(post.CanChangeStatusBy(user.ID, user.RoleID) || permissions.CanModerate)
+
+	// Question anonyme : l'auteur n'est visible que de lui-même
+	post.HideAnonymousAuthor(userID)
+
 	data := models.PostPageData{
-		Post:        *post,
-		Comments:    comments,
-		User:        user,
-		Title:       post.Title,
-		CurrentSort: sortBy,
+		Post:           *post,
+		Breadcrumbs:    breadcrumbs,
+		Permissions:    permissions,
+		MultiSolution:  multiSolution,
+		AllowAnonymous: allowAnonymous,
+		MoveCategories: moveCategories,
+		Comments:       comments,
+		User:           user,
+		Title:          post.Title,
+		CurrentSort:    sortBy,
 		AvailableSorts: []models.SortOption{
 			{Value: "newest", Label: "Plus récents"},
 			{Value: "oldest", Label: "Plus anciens"},
 			{Value: "most_liked", Label: "Les plus likés"},
 			{Value: "solutions_first", Label: "Solutions d'abord"},
 		},
+		BountyRules:    h.config.Bounty,
+		AwardedBounty:  awardedBounty,
+		SuggestArchive: suggestArchive,
+		IsSubscribed:   user != nil && h.repo.IsSubscribed(user.ID, models.SubscriptionPost, postID),
+		CommentDrafts:  commentDrafts,
+	}
+	if user != nil {
+		data.IsBookmarked = h.repo.IsBookmarked(user.ID, postID, 0)
+		data.BookmarkedComments = h.repo.GetBookmarkedComments(user.ID, postID)
 	}
 
 	if h.templates != nil {
@@ -280,17 +383,27 @@ func (h *ForumHandler) CreatePostPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Récupérer les catégories pour le formulaire
-	categories, err := h.repo.GetCategories()
+	// Récupérer les catégories où l'utilisateur peut publier
+	categories, err := h.repo.GetCategories(user)
 	if err != nil {
 		categories = []models.Category{}
 	}
+	categories = h.postableCategories(models.FlattenCategories(categories), user)
+
+	// Reprendre un brouillon de question
+	var draft *models.Draft
+	if draftID, _ := utils.GetIntParam(r, "draft"); draftID > 0 {
+		if d, err := h.repo.GetDraft(draftID, user.ID); err == nil && d.Kind == models.DraftKindPost {
+			draft = d
+		}
+	}
 
 	if h.templates != nil {
 		data := map[string]interface{}{
 			"Categories": categories,
 			"User":       user,
 			"Title":      "Créer un post",
+			"Draft":      draft,
 		}
 		utils.RenderTemplate(w, h.templates, "create-post.html", data)
 	} else {
@@ -328,6 +441,17 @@ func (h *ForumHandler) CreatePostPage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// postableCategories filtre les catégories dans lesquelles l'utilisateur peut créer un post
+func (h *ForumHandler) postableCategories(categories []models.Category, user *models.User) []models.Category {
+	postable := []models.Category{}
+	for i := range categories {
+		if h.repo.GetCategoryPermissions(&categories[i], user).CanPost {
+			postable = append(postable, categories[i])
+		}
+	}
+	return postable
+}
+
 // POST /create-post
 func (h *ForumHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -348,41 +472,43 @@ func (h *ForumHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	title := utils.SanitizeInput(r.FormValue("title"))
-	content := utils.SanitizeInput(r.FormValue("content"))
-	categoryIDStr := r.FormValue("category_id")
-	tagsStr := r.FormValue("tags")
-
-	// Validation
-	if len(title) < 5 || len(title) > 255 {
-		http.Redirect(w, r, "/create-post?error=title", http.StatusSeeOther)
-		return
+	// En cas d'erreur, revenir au formulaire avec le brouillon en cours
+	formURL := "/create-post"
+	if draftID, err := strconv.Atoi(r.FormValue("draft_id")); err == nil && draftID > 0 {
+		formURL += "?draft=" + strconv.Itoa(draftID) + "&"
+	} else {
+		formURL += "?"
 	}
 
-	if len(content) < 20 {
-		http.Redirect(w, r, "/create-post?error=content", http.StatusSeeOther)
+	form, errorCode := h.parsePostForm(r, user)
+	if errorCode != "" {
+		http.Redirect(w, r, formURL+"error="+errorCode, http.StatusSeeOther)
 		return
 	}
 
-	categoryID, err := strconv.Atoi(categoryIDStr)
-	if err != nil || categoryID <= 0 {
-		http.Redirect(w, r, "/create-post?error=category", http.StatusSeeOther)
+	// Créer le post (programmé si une date de publication est indiquée)
+	postID, err := h.repo.CreatePost(form.Title, form.Content, user.ID, form.CategoryID, form.Level, form.DueAt, form.PublishAt, form.IsAnonymous)
+	if err != nil {
+		http.Redirect(w, r, formURL+"error=create", http.StatusSeeOther)
 		return
 	}
 
-	// Créer le post
-	postID, err := h.repo.CreatePost(title, content, user.ID, categoryID)
-	if err != nil {
-		http.Redirect(w, r, "/create-post?error=create", http.StatusSeeOther)
-		return
+	// Attacher le sondage
+	if form.Poll != nil {
+		form.Poll.PostID = int(postID)
+		if err := h.repo.CreatePoll(form.Poll); err != nil {
+			h.repo.DeletePost(int(postID))
+			http.Redirect(w, r, formURL+"error=create", http.StatusSeeOther)
+			return
+		}
 	}
 
 	// Traiter les images uploadées
 	files := r.MultipartForm.File["images"]
-	if len(files) > utils.MaxImagesCount {
+	if len(files)+h.pendingDraftImages(r, user.ID) > utils.MaxImagesCount {
 		// Nettoyer et retourner erreur
 		h.repo.DeletePost(int(postID))
-		http.Redirect(w, r, "/create-post?error=too_many_images", http.StatusSeeOther)
+		http.Redirect(w, r, formURL+"error=too_many_images", http.StatusSeeOther)
 		return
 	}
 
@@ -423,21 +549,110 @@ func (h *ForumHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Ajouter les tags
-	if tagsStr != "" {
-		tags := utils.ParseTags(tagsStr)
-		for _, tag := range tags {
-			h.repo.AddTagToPost(int(postID), tag)
-		}
+	for _, tag := range form.Tags {
+		h.repo.AddTagToPost(int(postID), tag)
 	}
 
+	// Rattacher les images du brouillon et le supprimer
+	h.publishDraft(r, user.ID, utils.IntPtr(int(postID)), nil)
+
+	// L'auteur suit sa question
+	h.repo.Subscribe(user.ID, models.SubscriptionPost, int(postID))
+
 	// Rediriger vers le post créé avec succès
 	successURL := "/post/" + strconv.FormatInt(postID, 10) + "?success=created"
+	if form.PublishAt != nil {
+		// Les mentions et abonnés d'un post programmé sont notifiés à sa publication
+		successURL = "/post/" + strconv.FormatInt(postID, 10) + "?success=scheduled"
+	} else {
+		if h.notifyMentions(user, form.IsAnonymous, int(postID), nil, form.Content) > 0 {
+			successURL += "&mentions_limited=1"
+		}
+		if err := h.repo.NotifyPostSubscribers(int(postID), nil); err != nil {
+			log.Printf("Erreur notification des abonnés (post %d): %v", postID, err)
+		}
+	}
 	if len(uploadErrors) > 0 {
 		successURL += "&upload_warnings=1"
 	}
 	http.Redirect(w, r, successURL, http.StatusSeeOther)
 }
 
+// postForm regroupe les champs validés du formulaire de question
+type postForm struct {
+	Title       string
+	Content     string
+	CategoryID  int
+	Level       string
+	DueAt       *time.Time
+	PublishAt   *time.Time // nil = publication immédiate
+	Tags        []string
+	Poll        *models.Poll // nil = pas de sondage
+	IsAnonymous bool
+}
+
+// parsePostForm valide le formulaire de question, à la création comme à la modification
+// d'un post programmé. Retourne le code d'erreur affiché par create-post.html si un champ est invalide.
+func (h *ForumHandler) parsePostForm(r *http.Request, user *models.User) (*postForm, string) {
+	form := &postForm{
+		Title:   utils.SanitizeInput(r.FormValue("title")),
+		Content: utils.SanitizeInput(r.FormValue("content")),
+		Level:   r.FormValue("level"),
+	}
+
+	// Validation
+	if len(form.Title) < 5 || len(form.Title) > 255 {
+		return nil, "title"
+	}
+
+	if len(form.Content) < 20 {
+		return nil, "content"
+	}
+
+	categoryID, err := strconv.Atoi(r.FormValue("category_id"))
+	if err != nil || categoryID <= 0 {
+		return nil, "category"
+	}
+	form.CategoryID = categoryID
+
+	// Respecter les règles de la catégorie (classe privée, rôle minimum, annonces)
+	category, err := h.repo.GetCategory(categoryID)
+	if err != nil || !h.repo.GetCategoryPermissions(category, user).CanPost {
+		return nil, "category"
+	}
+
+	// Publication anonyme, si la catégorie l'autorise
+	form.IsAnonymous = r.FormValue("is_anonymous") != ""
+	if form.IsAnonymous && !category.AllowAnonymous {
+		return nil, "anonymous"
+	}
+
+	// Niveau scolaire et date de rendu (facultatifs)
+	if form.Level != "" && models.SchoolLevelLabel(form.Level) == "" {
+		return nil, "level"
+	}
+	if form.DueAt, err = parseDateTimeInput(r.FormValue("due_at")); err != nil {
+		return nil, "due_at"
+	}
+
+	// Publication programmée (facultative), forcément dans le futur
+	if form.PublishAt, err = parseDateTimeInput(r.FormValue("publish_at")); err != nil ||
+		(form.PublishAt != nil && !form.PublishAt.After(time.Now())) {
+		return nil, "publish_at"
+	}
+
+	// Sondage (facultatif)
+	var errorCode string
+	if form.Poll, errorCode = parsePollForm(r, form.PublishAt); errorCode != "" {
+		return nil, errorCode
+	}
+
+	if tags := r.FormValue("tags"); tags != "" {
+		form.Tags = utils.ParseTags(tags)
+	}
+	return form, ""
+}
+
 // POST /comment
 func (h *ForumHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -486,6 +701,13 @@ func (h *ForumHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	permissions, err := h.repo.GetPostPermissions(postID, user)
+	if err != nil || !permissions.CanComment {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Vous ne pouvez pas répondre dans cette catégorie"})
+		return
+	}
+
 	if len(content) < 5 {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Le commentaire doit faire au moins 5 caractères"})
@@ -499,8 +721,18 @@ func (h *ForumHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Réponse anonyme, si la catégorie l'autorise
+	isAnonymous := r.FormValue("is_anonymous") != ""
+	if isAnonymous {
+		if category, err := h.repo.GetCategory(post.CategoryID); err != nil || !category.AllowAnonymous {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Les réponses anonymes ne sont pas autorisées dans cette catégorie"})
+			return
+		}
+	}
+
 	// Créer le commentaire et récupérer son ID
-	commentID, err := h.repo.CreateCommentWithID(postID, content, user.ID, parentID)
+	commentID, err := h.repo.CreateCommentWithID(postID, content, user.ID, parentID, isAnonymous)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Erreur lors de la création du commentaire"})
@@ -509,7 +741,7 @@ func (h *ForumHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 
 	// Traiter les images uploadées (max 3 pour les commentaires)
 	files := r.MultipartForm.File["images"]
-	if len(files) > 3 { // Limite réduite pour les commentaires
+	if len(files)+h.pendingDraftImages(r, user.ID) > utils.MaxCommentImageCount { // Limite réduite pour les commentaires
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Maximum 3 images autorisées pour un commentaire"})
 		return
@@ -551,11 +783,23 @@ func (h *ForumHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Rattacher les images du brouillon et le supprimer
+	h.publishDraft(r, user.ID, nil, utils.IntPtr(int(commentID)))
+
 	w.Header().Set("Content-Type", "application/json")
 	response := map[string]interface{}{"status": "success"}
 	if len(uploadErrors) > 0 {
 		response["upload_warnings"] = uploadErrors
 	}
+	if skipped := h.notifyMentions(user, isAnonymous, postID, utils.IntPtr(int(commentID)), content); skipped > 0 {
+		response["mentions_limited"] = skipped
+	}
+
+	// L'auteur de la réponse suit la question ; les autres abonnés sont prévenus
+	h.repo.Subscribe(user.ID, models.SubscriptionPost, postID)
+	if err := h.repo.NotifyAnswerSubscribers(postID, int(commentID), user.ID, contentAuthorName(user, isAnonymous)); err != nil {
+		log.Printf("Erreur notification des abonnés (post %d): %v", postID, err)
+	}
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -597,6 +841,23 @@ func (h *ForumHandler) Vote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Le post visé doit être visible (catégories privées des classes)
+	postID := targetID
+	if target == "comment" {
+		comment, err := h.repo.GetCommentByID(targetID)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Commentaire non trouvé"})
+			return
+		}
+		postID = comment.PostID
+	}
+	if post, err := h.repo.GetPost(postID, user); err != nil || post.Status == models.PostStatusScheduled {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Post non trouvé"})
+		return
+	}
+
 	// Appliquer le vote
 	if target == "post" {
 		err = h.repo.VotePost(targetID, user.ID, voteType)
@@ -620,13 +881,14 @@ func (h *ForumHandler) Search(w http.ResponseWriter, r *http.Request) {
 
 	query := utils.GetStringParam(r, "q")
 	categoryID, _ := utils.GetIntParam(r, "category")
+	filter := parsePostFilter(r)
 
 	var posts []models.Post
 	var searchInfo map[string]interface{}
 
-	if query != "" {
+	if query != "" || !filter.IsEmpty() {
 		var err error
-		posts, err = h.repo.SearchPosts(query, categoryID, 50)
+		posts, err = h.repo.SearchPosts(query, categoryID, 50, user, filter)
 		if err != nil {
 			posts = []models.Post{}
 		}
@@ -643,7 +905,8 @@ func (h *ForumHandler) Search(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Récupérer les catégories pour le formulaire
-	categories, _ := h.repo.GetCategories()
+	categoryTree, _ := h.repo.GetCategories(user)
+	categories := models.FlattenCategories(categoryTree)
 
 	// Récupérer les tags populaires pour les suggestions
 	popularTags, _ := h.repo.GetPopularTags(10)
@@ -657,6 +920,16 @@ func (h *ForumHandler) Search(w http.ResponseWriter, r *http.Request) {
 			"User":        user,
 			"Title":       "Recherche",
 			"CategoryID":  categoryID,
+			"Filter":      filter,
+		}
+		if user != nil && searchInfo != nil {
+			// Tags de la recherche déjà suivis (comparaison insensible à la casse)
+			subscribed := h.repo.GetSubscribedTags(user.ID)
+			followedTags := make(map[string]bool)
+			for _, tag := range searchInfo["Tags"].([]string) {
+				followedTags[tag] = subscribed[strings.ToLower(tag)]
+			}
+			data["FollowedTags"] = followedTags
 		}
 		utils.RenderTemplate(w, h.templates, "search.html", data)
 	} else {
@@ -679,7 +952,7 @@ func (h *ForumHandler) Search(w http.ResponseWriter, r *http.Request) {
 
 		var popularTagsHTML string
 		for _, tag := range popularTags {
-			popularTagsHTML += `<span class="tag-suggestion" onclick="addTag('` + tag.Name + `')">#` + tag.Name + `</span>`
+			popularTagsHTML += `<a class="tag-suggestion" href="/search?q=` + url.QueryEscape("#"+tag.Name) + `">#` + tag.Name + `</a>`
 		}
 
 		content := `
@@ -714,7 +987,7 @@ func (h *ForumHandler) SearchSuggestions(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	suggestions, err := h.repo.SearchSuggestions(query, 10)
+	suggestions, err := h.repo.SearchSuggestions(query, 10, middleware.GetUserFromContext(r.Context()))
 	if err != nil {
 		suggestions = []string{}
 	}
@@ -780,8 +1053,9 @@ func (h *ForumHandler) DeleteOwnComment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Vérifier les permissions : propriétaire du commentaire OU créateur du post OU modérateur+
-	canDelete := comment.UserID == user.ID || post.UserID == user.ID || user.RoleID >= models.RoleModerator
+	// Vérifier les permissions : propriétaire du commentaire OU créateur du post OU modérateur de la catégorie
+	permissions, _ := h.repo.GetPostPermissions(post.ID, user)
+	canDelete := comment.UserID == user.ID || post.UserID == user.ID || permissions.CanModerate
 	if !canDelete {
 		http.Error(w, "Permission refusée", http.StatusForbidden)
 		return
@@ -794,6 +1068,11 @@ func (h *ForumHandler) DeleteOwnComment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Suppression par un modérateur : pénaliser l'auteur
+	if comment.UserID != user.ID && post.UserID != user.ID {
+		h.repo.PenalizeReputation(comment.UserID, user.ID, "comment", commentID)
+	}
+
 	// Retourner une réponse de succès
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Commentaire supprimé avec succès"))
@@ -828,13 +1107,19 @@ func (h *ForumHandler) DeleteOwnPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Vérifier les permissions : propriétaire du post OU modérateur+
-	canDelete := post.UserID == user.ID || user.RoleID >= models.RoleModerator
+	// Vérifier les permissions : propriétaire du post OU modérateur de la catégorie
+	permissions, _ := h.repo.GetPostPermissions(postID, user)
+	canDelete := post.UserID == user.ID || permissions.CanModerate
 	if !canDelete {
 		http.Error(w, "Permission refusée", http.StatusForbidden)
 		return
 	}
 
+	// Suppression par un modérateur : la prime en cours est remboursée
+	if post.UserID != user.ID {
+		h.repo.RefundOpenBounty(postID)
+	}
+
 	// Supprimer le post (et ses commentaires en cascade)
 	err = h.repo.DeletePost(postID)
 	if err != nil {
@@ -842,6 +1127,11 @@ func (h *ForumHandler) DeleteOwnPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Suppression par un modérateur : pénaliser l'auteur
+	if post.UserID != user.ID {
+		h.repo.PenalizeReputation(post.UserID, user.ID, "post", postID)
+	}
+
 	// Rediriger vers la catégorie
 	http.Redirect(w, r, fmt.Sprintf("/category/%d", post.CategoryID), http.StatusSeeOther)
 }
@@ -895,8 +1185,17 @@ func (h *ForumHandler) ChangePostStatus(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Vérifier si l'utilisateur peut changer le statut
-	if !post.CanChangeStatusBy(user.ID, user.RoleID) {
+	// Un post programmé est publié par le planificateur, pas par un changement de statut
+	if post.Status == models.PostStatusScheduled {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Cette question n'est pas encore publiée"})
+		return
+	}
+
+	// Vérifier si l'utilisateur peut changer le statut (auteur ou modérateur de la catégorie)
+	permissions, _ := h.repo.GetPostPermissions(postID, user)
+	if !post.CanChangeStatusBy(user.ID, user.RoleID) && !permissions.CanModerate {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Permission refusée"})
@@ -934,13 +1233,14 @@ func (h *ForumHandler) ServeImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Vérifier que l'image existe en base de données
-	_, err := h.repo.GetImageByFilename(filename)
+	image, err := h.repo.GetImageByFilename(filename)
 	if err != nil {
 		http.Error(w, "Image non trouvée", http.StatusNotFound)
 		return
 	}
 
-	// Servir le fichier
+	// Servir le fichier avec le type enregistré à l'upload
+	utils.SetUploadHeaders(w, image.OriginalName, image.ContentType)
 	filePath := "./uploads/posts/" + filename
 	http.ServeFile(w, r, filePath)
 }
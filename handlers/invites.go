@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"aide-devoir-forum/config"
+	"aide-devoir-forum/database"
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// Limites des codes d'invitation
+const (
+	maxInviteUses          = 500
+	maxInviteValidDays     = 90
+	defaultInviteValidDays = 14
+)
+
+type InviteHandler struct {
+	repo      *database.Repository
+	config    *config.Config
+	templates *template.Template
+}
+
+func NewInviteHandler(repo *database.Repository, cfg *config.Config, tmpl *template.Template) *InviteHandler {
+	return &InviteHandler{
+		repo:      repo,
+		config:    cfg,
+		templates: tmpl,
+	}
+}
+
+// GET /invites
+// Page affichant les codes d'invitation de l'utilisateur (tous pour un administrateur)
+func (h *InviteHandler) Page(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	createdBy := user.ID
+	if user.IsAdmin() {
+		createdBy = 0
+	}
+
+	invites, err := h.repo.GetInviteCodes(createdBy)
+	if err != nil {
+		log.Printf("Erreur récupération des codes d'invitation: %v", err)
+	}
+
+	classGroups, err := h.repo.GetClassGroupsByOwner(user.ID)
+	if err != nil {
+		log.Printf("Erreur récupération des classes: %v", err)
+	}
+
+	data := models.InvitesPageData{
+		User:             user,
+		Title:            "Invitations",
+		Invites:          invites,
+		ClassGroups:      classGroups,
+		AssignableRoles:  h.assignableRoles(user),
+		RegistrationMode: h.config.Registration.Mode,
+	}
+
+	if h.templates == nil {
+		http.Error(w, "Templates non disponibles", http.StatusInternalServerError)
+		return
+	}
+	if err := utils.ExecuteTemplate(w, h.templates, "invites.html", data); err != nil {
+		http.Error(w, "Erreur de rendu", http.StatusInternalServerError)
+	}
+}
+
+// POST /invites/create
+// Create génère un code d'invitation (1 à N utilisations, expiration, rôle et classe cibles)
+func (h *InviteHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+
+	maxUses, err := strconv.Atoi(r.FormValue("max_uses"))
+	if err != nil || maxUses < 1 || maxUses > maxInviteUses {
+		sendJSONError(w, "Nombre d'utilisations invalide (1 à 500)", http.StatusBadRequest)
+		return
+	}
+
+	days := defaultInviteValidDays
+	if daysStr := r.FormValue("expires_in_days"); daysStr != "" {
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > maxInviteValidDays {
+			sendJSONError(w, "Durée de validité invalide (1 à 90 jours)", http.StatusBadRequest)
+			return
+		}
+	}
+	expiresAt := time.Now().AddDate(0, 0, days)
+
+	// Un professeur ne peut inviter que des élèves : seul un administrateur choisit un autre rôle
+	roleID := models.RoleUser
+	if roleStr := r.FormValue("role_id"); roleStr != "" {
+		roleID, err = strconv.Atoi(roleStr)
+		if err != nil || !h.canAssignRole(user, roleID) {
+			sendJSONError(w, "Rôle non autorisé", http.StatusForbidden)
+			return
+		}
+	}
+
+	// La classe doit appartenir à l'auteur de l'invitation
+	var classGroupID *int
+	if groupStr := r.FormValue("class_group_id"); groupStr != "" && groupStr != "0" {
+		groupID, err := strconv.Atoi(groupStr)
+		if err != nil {
+			sendJSONError(w, "Classe invalide", http.StatusBadRequest)
+			return
+		}
+		group, err := h.repo.GetClassGroup(groupID)
+		if err != nil || group.OwnerID != user.ID {
+			sendJSONError(w, "Classe introuvable", http.StatusNotFound)
+			return
+		}
+		classGroupID = &group.ID
+	}
+
+	// Collision de code très improbable : quelques essais suffisent
+	var code string
+	for attempt := 0; attempt < 3; attempt++ {
+		if code, err = utils.GenerateInviteCode(); err != nil {
+			break
+		}
+		if _, err = h.repo.CreateInviteCode(code, user.ID, roleID, classGroupID, maxUses, &expiresAt); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		log.Printf("Erreur création du code d'invitation: %v", err)
+		sendJSONError(w, "Erreur lors de la création du code", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("🎟️  Code d'invitation %s créé par %s (%d utilisation(s), rôle %d)", code, user.Username, maxUses, roleID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"code": code})
+}
+
+// POST /invites/revoke
+func (h *InviteHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+
+	inviteID, err := strconv.Atoi(r.FormValue("invite_id"))
+	if err != nil || inviteID <= 0 {
+		sendJSONError(w, "ID de code invalide", http.StatusBadRequest)
+		return
+	}
+
+	createdBy := user.ID
+	if user.IsAdmin() {
+		createdBy = 0
+	}
+
+	if err := h.repo.RevokeInviteCode(inviteID, createdBy); err != nil {
+		sendJSONError(w, "Code introuvable ou déjà révoqué", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Code révoqué"})
+}
+
+// canAssignRole vérifie qu'un utilisateur peut attribuer un rôle via une invitation
+func (h *InviteHandler) canAssignRole(user *models.User, roleID int) bool {
+	if roleID == models.RoleUser {
+		return true
+	}
+	return user.IsAdmin() && roleID >= models.RoleUser && roleID <= models.RoleAdministrator
+}
+
+// assignableRoles retourne les rôles proposés dans le formulaire d'invitation
+func (h *InviteHandler) assignableRoles(user *models.User) []models.Role {
+	roles, err := h.repo.GetRoles()
+	if err != nil {
+		log.Printf("Erreur récupération des rôles: %v", err)
+		return nil
+	}
+
+	var assignable []models.Role
+	for _, role := range roles {
+		if h.canAssignRole(user, role.ID) {
+			assignable = append(assignable, role)
+		}
+	}
+	return assignable
+}
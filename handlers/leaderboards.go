@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// === CLASSEMENTS ===
+
+// Nombre d'utilisateurs affichés par classement
+const leaderboardLimit = 20
+
+// GET /leaderboard?metric=solutions|likes|reputation&period=week|month|year|all&category=ID
+// Leaderboard affiche le classement des utilisateurs qui aident le plus
+func (h *ForumHandler) Leaderboard(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	metric := r.URL.Query().Get("metric")
+	switch metric {
+	case models.LeaderboardMetricSolutions, models.LeaderboardMetricLikes, models.LeaderboardMetricReputation:
+	default:
+		metric = models.LeaderboardMetricReputation
+	}
+
+	period := r.URL.Query().Get("period")
+	switch period {
+	case models.LeaderboardPeriodWeek, models.LeaderboardPeriodMonth, models.LeaderboardPeriodSchoolYear, models.LeaderboardPeriodAllTime:
+	default:
+		period = models.LeaderboardPeriodMonth
+	}
+
+	// Filtre par catégorie, limité aux catégories visibles
+	categoryID := 0
+	if raw := r.URL.Query().Get("category"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			http.Error(w, "Catégorie invalide", http.StatusBadRequest)
+			return
+		}
+		category, err := h.repo.GetCategory(id)
+		if err != nil || !h.repo.CanViewCategory(category, user) {
+			http.Error(w, "Catégorie non trouvée", http.StatusNotFound)
+			return
+		}
+		categoryID = id
+	}
+
+	entries, err := h.repo.GetLeaderboard(metric, period, categoryID, user, leaderboardLimit)
+	if err != nil {
+		log.Printf("Erreur récupération du classement: %v", err)
+		entries = []models.LeaderboardEntry{}
+	}
+
+	categoryTree, err := h.repo.GetCategories(user)
+	if err != nil {
+		categoryTree = []models.Category{}
+	}
+
+	data := models.LeaderboardPageData{
+		User:       user,
+		Title:      "Classements",
+		Metric:     metric,
+		Period:     period,
+		CategoryID: categoryID,
+		Categories: models.FlattenCategories(categoryTree),
+		Entries:    entries,
+	}
+
+	if h.templates == nil {
+		http.Error(w, "Templates non disponibles", http.StatusInternalServerError)
+		return
+	}
+	if err := utils.ExecuteTemplate(w, h.templates, "leaderboard.html", data); err != nil {
+		http.Error(w, "Erreur de rendu", http.StatusInternalServerError)
+	}
+}
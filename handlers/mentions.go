@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// === MENTIONS ===
+
+// Nombre de suggestions retournées par l'autocomplétion des mentions
+const mentionSuggestionsLimit = 8
+
+// GET /users/autocomplete?q=
+// MentionSuggestions retourne en JSON les utilisateurs dont le pseudo commence par q
+func (h *ForumHandler) MentionSuggestions(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimPrefix(strings.TrimSpace(r.URL.Query().Get("q")), "@")
+
+	suggestions := []models.MentionSuggestion{}
+	if utils.IsMentionPrefix(prefix) {
+		found, err := h.repo.SearchMentionableUsers(prefix, mentionSuggestionsLimit)
+		if err != nil {
+			log.Printf("Erreur autocomplétion des mentions: %v", err)
+		} else {
+			suggestions = found
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// notifyMentions notifie les utilisateurs mentionnés dans une question ou une réponse
+// (commentID nil pour la question) et retourne le nombre de mentions non notifiées
+// à cause des limites anti-spam
+func (h *ForumHandler) notifyMentions(author *models.User, isAnonymous bool, postID int, commentID *int, content string) int {
+	skipped, err := h.repo.NotifyMentions(author.ID, contentAuthorName(author, isAnonymous), postID, commentID, content)
+	if err != nil {
+		log.Printf("Erreur notification des mentions (post %d): %v", postID, err)
+	}
+	return skipped
+}
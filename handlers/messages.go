@@ -0,0 +1,464 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// === MESSAGERIE PRIVÉE ===
+
+// messageContent lit et valide le contenu d'un message privé
+func messageContent(w http.ResponseWriter, r *http.Request) (string, bool) {
+	content := utils.SanitizeInput(r.FormValue("content"))
+	if content == "" || utf8.RuneCountInString(content) > models.MaxMessageLength {
+		sendJSONError(w, fmt.Sprintf("Le message doit contenir entre 1 et %d caractères", models.MaxMessageLength), http.StatusBadRequest)
+		return "", false
+	}
+	return content, true
+}
+
+// GET /messages
+// Messages affiche les conversations privées de l'utilisateur et le formulaire de nouveau
+// message (destinataire pré-rempli par ?to=username)
+func (h *ForumHandler) Messages(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	recipient := utils.SanitizeInput(r.URL.Query().Get("to"))
+
+	// Une conversation existe déjà avec ce destinataire : on y va directement
+	if recipient != "" {
+		if other, err := h.repo.GetUserByUsername(recipient); err == nil {
+			if conversationID, err := h.repo.GetConversationBetween(user.ID, other.ID); err == nil {
+				http.Redirect(w, r, fmt.Sprintf("/messages/conversation?id=%d", conversationID), http.StatusSeeOther)
+				return
+			}
+		}
+	}
+
+	conversations, err := h.repo.GetConversations(user.ID)
+	if err != nil {
+		log.Printf("Erreur récupération des conversations: %v", err)
+		conversations = []models.Conversation{}
+	}
+
+	data := models.MessagesPageData{
+		User:          user,
+		Title:         "Messages privés",
+		Conversations: conversations,
+		Recipient:     recipient,
+	}
+
+	if h.templates == nil {
+		http.Error(w, "Templates non disponibles", http.StatusInternalServerError)
+		return
+	}
+	if err := utils.ExecuteTemplate(w, h.templates, "messages.html", data); err != nil {
+		http.Error(w, "Erreur de rendu", http.StatusInternalServerError)
+	}
+}
+
+// GET /messages/conversation?id=
+// Conversation affiche une conversation privée à l'un de ses participants, et marque comme lus
+// les messages reçus. Un modérateur peut la consulter en lecture seule tant qu'elle fait l'objet
+// d'un signalement non traité ; chaque consultation est journalisée.
+func (h *ForumHandler) Conversation(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	conversationID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "ID de conversation invalide", http.StatusBadRequest)
+		return
+	}
+	conversation, err := h.repo.GetConversation(conversationID)
+	if err != nil {
+		http.Error(w, "Conversation non trouvée", http.StatusNotFound)
+		return
+	}
+
+	data := models.ConversationPageData{
+		User:  user,
+		Title: "Conversation privée",
+	}
+
+	if conversation.HasParticipant(user.ID) {
+		otherID := conversation.User1ID
+		if otherID == user.ID {
+			otherID = conversation.User2ID
+		}
+		other, err := h.repo.GetUserByIDComplete(otherID)
+		if err != nil {
+			http.Error(w, "Conversation non trouvée", http.StatusNotFound)
+			return
+		}
+		conversation.OtherUserID = other.ID
+		conversation.OtherUsername = other.Username
+		conversation.OtherAvatarURL = other.AvatarURL
+		data.Title = "Conversation avec " + other.Username
+		data.HasBlocked = h.repo.HasBlocked(user.ID, other.ID)
+		data.ReplyRestriction = h.repo.ConversationRestriction(user, other)
+
+		if err := h.repo.MarkConversationRead(conversation.ID, user.ID); err != nil {
+			log.Printf("Erreur lecture de la conversation %d: %v", conversation.ID, err)
+		}
+	} else if user.CanModerate() && h.repo.HasOpenConversationReport(conversation.ID) {
+		// L'accès n'est accordé qu'une fois la consultation journalisée
+		if err := h.repo.CreateModerationLog(user.ID, "view_conversation", "conversation", conversation.ID,
+			"Consultation d'une conversation signalée"); err != nil {
+			log.Printf("Erreur journalisation de la consultation de la conversation %d: %v", conversation.ID, err)
+			http.Error(w, "Erreur lors de la consultation", http.StatusInternalServerError)
+			return
+		}
+		h.repo.MarkConversationReportsReviewed(conversation.ID, user.ID)
+
+		for _, participantID := range []int{conversation.User1ID, conversation.User2ID} {
+			if participant, err := h.repo.GetUserByID(participantID); err == nil {
+				data.Participants = append(data.Participants, *participant)
+			}
+		}
+		data.Reports, _ = h.repo.GetConversationReports(conversation.ID)
+		data.ModeratorView = true
+		data.Title = "Conversation signalée"
+	} else {
+		http.Error(w, "Conversation non trouvée", http.StatusNotFound)
+		return
+	}
+
+	messages, err := h.repo.GetMessages(conversation.ID)
+	if err != nil {
+		log.Printf("Erreur récupération des messages de la conversation %d: %v", conversation.ID, err)
+		messages = []models.PrivateMessage{}
+	}
+	data.Conversation = *conversation
+	data.Messages = messages
+
+	if h.templates == nil {
+		http.Error(w, "Templates non disponibles", http.StatusInternalServerError)
+		return
+	}
+	if err := utils.ExecuteTemplate(w, h.templates, "conversation.html", data); err != nil {
+		http.Error(w, "Erreur de rendu", http.StatusInternalServerError)
+	}
+}
+
+// POST /messages/start
+// StartConversation envoie un premier message à un utilisateur (recipient = nom d'utilisateur),
+// en créant la conversation si besoin, dans le respect de ses réglages de confidentialité
+func (h *ForumHandler) StartConversation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	username := strings.TrimPrefix(strings.TrimSpace(r.FormValue("recipient")), "@")
+	recipientByUsername, err := h.repo.GetUserByUsername(username)
+	if err != nil {
+		sendJSONError(w, "Utilisateur non trouvé", http.StatusNotFound)
+		return
+	}
+	recipient, err := h.repo.GetUserByIDComplete(recipientByUsername.ID)
+	if err != nil {
+		sendJSONError(w, "Utilisateur non trouvé", http.StatusNotFound)
+		return
+	}
+
+	content, ok := messageContent(w, r)
+	if !ok {
+		return
+	}
+
+	if restriction := h.repo.ConversationRestriction(user, recipient); restriction != "" {
+		sendJSONError(w, restriction, http.StatusForbidden)
+		return
+	}
+
+	conversationID, err := h.repo.GetOrCreateConversation(user.ID, recipient.ID)
+	if err != nil {
+		log.Printf("Erreur création de la conversation avec %d: %v", recipient.ID, err)
+		sendJSONError(w, "Erreur lors de l'envoi du message", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.repo.SendMessage(conversationID, user, recipient.ID, content); err != nil {
+		log.Printf("Erreur envoi de message (conversation %d): %v", conversationID, err)
+		sendJSONError(w, "Erreur lors de l'envoi du message", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":          "success",
+		"message":         "Message envoyé",
+		"conversation_id": conversationID,
+	})
+}
+
+// POST /messages/send
+// SendMessage répond dans une conversation existante (conversation_id)
+func (h *ForumHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	conversationID, _ := strconv.Atoi(r.FormValue("conversation_id"))
+	conversation, err := h.repo.GetConversation(conversationID)
+	if err != nil || !conversation.HasParticipant(user.ID) {
+		sendJSONError(w, "Conversation non trouvée", http.StatusNotFound)
+		return
+	}
+
+	content, ok := messageContent(w, r)
+	if !ok {
+		return
+	}
+
+	recipientID := conversation.User1ID
+	if recipientID == user.ID {
+		recipientID = conversation.User2ID
+	}
+	recipient, err := h.repo.GetUserByIDComplete(recipientID)
+	if err != nil {
+		sendJSONError(w, "Conversation non trouvée", http.StatusNotFound)
+		return
+	}
+	if restriction := h.repo.ConversationRestriction(user, recipient); restriction != "" {
+		sendJSONError(w, restriction, http.StatusForbidden)
+		return
+	}
+
+	messageID, err := h.repo.SendMessage(conversation.ID, user, recipient.ID, content)
+	if err != nil {
+		log.Printf("Erreur envoi de message (conversation %d): %v", conversation.ID, err)
+		sendJSONError(w, "Erreur lors de l'envoi du message", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "success",
+		"message":    "Message envoyé",
+		"message_id": messageID,
+	})
+}
+
+// POST /messages/block
+// BlockUser bloque ou débloque (action "block" ou "unblock") un utilisateur (user_id) :
+// tant que le blocage dure, aucun des deux ne peut écrire à l'autre
+func (h *ForumHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	blockedID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil || blockedID == user.ID {
+		sendJSONError(w, "ID utilisateur invalide", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.repo.GetUserByID(blockedID); err != nil {
+		sendJSONError(w, "Utilisateur non trouvé", http.StatusNotFound)
+		return
+	}
+
+	block := r.FormValue("action") != "unblock"
+	message := "Utilisateur bloqué"
+	if block {
+		err = h.repo.BlockUser(user.ID, blockedID)
+	} else {
+		err = h.repo.UnblockUser(user.ID, blockedID)
+		message = "Utilisateur débloqué"
+	}
+	if err != nil {
+		log.Printf("Erreur blocage de l'utilisateur %d par %d: %v", blockedID, user.ID, err)
+		sendJSONError(w, "Erreur lors de la mise à jour des blocages", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": message,
+		"blocked": block,
+	})
+}
+
+// POST /messages/report
+// ReportConversation signale une conversation (conversation_id) aux modérateurs, qui peuvent
+// alors la consulter
+func (h *ForumHandler) ReportConversation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	conversationID, _ := strconv.Atoi(r.FormValue("conversation_id"))
+	conversation, err := h.repo.GetConversation(conversationID)
+	if err != nil || !conversation.HasParticipant(user.ID) {
+		sendJSONError(w, "Conversation non trouvée", http.StatusNotFound)
+		return
+	}
+
+	reason := utils.SanitizeInput(r.FormValue("reason"))
+	if reason == "" || utf8.RuneCountInString(reason) > 255 {
+		sendJSONError(w, "La raison doit contenir entre 1 et 255 caractères", http.StatusBadRequest)
+		return
+	}
+	description := utils.SanitizeInput(r.FormValue("description"))
+
+	if err := h.repo.ReportConversation(conversation.ID, user.ID, reason, description); err != nil {
+		sendJSONError(w, "Signalement impossible : "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Conversation signalée aux modérateurs",
+	})
+}
+
+// GET /admin/message-reports
+// MessageReports liste les conversations signalées en attente de traitement (modérateurs)
+func (h *ForumHandler) MessageReports(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil || !user.CanModerate() {
+		http.Error(w, "Accès réservé aux modérateurs", http.StatusForbidden)
+		return
+	}
+
+	reports, err := h.repo.GetOpenConversationReports()
+	if err != nil {
+		log.Printf("Erreur récupération des conversations signalées: %v", err)
+		reports = []models.ConversationReport{}
+	}
+
+	data := models.MessageReportsPageData{
+		User:    user,
+		Title:   "Conversations signalées",
+		Reports: reports,
+	}
+
+	if h.templates == nil {
+		http.Error(w, "Templates non disponibles", http.StatusInternalServerError)
+		return
+	}
+	if err := utils.ExecuteTemplate(w, h.templates, "message-reports.html", data); err != nil {
+		http.Error(w, "Erreur de rendu", http.StatusInternalServerError)
+	}
+}
+
+// POST /admin/message-reports/resolve
+// ResolveMessageReport clôt un signalement de conversation (report_id), comme traité
+// (action "resolve") ou rejeté (action "dismiss"). La clôture est journalisée.
+func (h *AdminHandler) ResolveMessageReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil || !user.CanModerate() {
+		sendJSONError(w, "Accès refusé", http.StatusForbidden)
+		return
+	}
+
+	reportID, err := strconv.Atoi(r.FormValue("report_id"))
+	if err != nil {
+		sendJSONError(w, "ID de signalement invalide", http.StatusBadRequest)
+		return
+	}
+
+	status, action, message := models.ReportResolved, "resolve_report", "Signalement traité"
+	if r.FormValue("action") == "dismiss" {
+		status, action, message = models.ReportDismissed, "dismiss_report", "Signalement rejeté"
+	}
+
+	conversationID, err := h.repo.CloseConversationReport(reportID, user.ID, status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Signalement non trouvé ou déjà clos", http.StatusNotFound)
+			return
+		}
+		log.Printf("Erreur clôture du signalement %d: %v", reportID, err)
+		sendJSONError(w, "Erreur lors de la clôture du signalement", http.StatusInternalServerError)
+		return
+	}
+	h.repo.CreateModerationLog(user.ID, action, "conversation", conversationID,
+		fmt.Sprintf("Signalement #%d", reportID))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": message,
+	})
+}
+
+// POST /settings/messages
+// UpdateMessagePrivacy modifie qui peut démarrer une conversation privée avec l'utilisateur
+func (h *ProfileHandler) UpdateMessagePrivacy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+
+	privacy := r.FormValue("message_privacy")
+	switch privacy {
+	case models.MessagePrivacyEveryone, models.MessagePrivacyContacts, models.MessagePrivacyNobody:
+	default:
+		sendJSONError(w, "Réglage de confidentialité invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.SetMessagePrivacy(user.ID, privacy); err != nil {
+		sendJSONError(w, "Erreur lors de la mise à jour", http.StatusInternalServerError)
+		return
+	}
+	h.sendJSONSuccess(w, "Confidentialité des messages mise à jour")
+}
@@ -0,0 +1,530 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"aide-devoir-forum/config"
+	"aide-devoir-forum/database"
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OAuthHandler implémente le fournisseur OAuth2 / OpenID Connect du forum
+// (code d'autorisation + PKCE) pour les outils internes de l'établissement.
+type OAuthHandler struct {
+	repo       *database.Repository
+	config     *config.Config
+	templates  *template.Template
+	signingKey *utils.OAuthSigningKey
+}
+
+func NewOAuthHandler(repo *database.Repository, cfg *config.Config, tmpl *template.Template, key *utils.OAuthSigningKey) *OAuthHandler {
+	return &OAuthHandler{
+		repo:       repo,
+		config:     cfg,
+		templates:  tmpl,
+		signingKey: key,
+	}
+}
+
+// oauthError représente une erreur OAuth2 (RFC 6749 §4.1.2.1 et §5.2)
+type oauthError struct {
+	Code        string
+	Description string
+}
+
+// authorizeRequest regroupe les paramètres validés d'une demande d'autorisation
+type authorizeRequest struct {
+	client        *models.OAuthClient
+	redirectURI   string
+	state         string
+	scopes        []string
+	nonce         string
+	codeChallenge string
+	prompt        string
+}
+
+// GET /.well-known/openid-configuration
+func (h *OAuthHandler) Discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := h.config.OAuth.Issuer
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"userinfo_endpoint":                     issuer + "/oauth/userinfo",
+		"jwks_uri":                              issuer + "/oauth/jwks",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      models.OAuthScopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"prompt_values_supported":               []string{"none", "consent"},
+		"claims_supported": []string{"sub", "iss", "aud", "exp", "iat", "nonce",
+			"preferred_username", "name", "picture", "role", "email", "email_verified"},
+	})
+}
+
+// GET /oauth/jwks
+func (h *OAuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{h.signingKey.JWK()},
+	})
+}
+
+// GET/POST /oauth/authorize
+// Authorize affiche l'écran de consentement (GET) puis délivre un code d'autorisation
+// (POST, ou directement si l'utilisateur a déjà accordé les portées demandées).
+// Le POST n'a pas besoin de jeton anti-CSRF : le cookie de session (SameSite=Lax)
+// n'est pas envoyé lors d'un POST provenant d'un autre site.
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		utils.RenderErrorPage(w, h.templates, http.StatusBadRequest, "Demande d'autorisation invalide", "Paramètres illisibles")
+		return
+	}
+
+	req, oauthErr := h.parseAuthorizeRequest(r)
+	if req == nil {
+		// Client ou URI de redirection invalide : on ne redirige surtout pas
+		utils.RenderErrorPage(w, h.templates, http.StatusBadRequest, "Demande d'autorisation invalide", oauthErr.Description)
+		return
+	}
+	if oauthErr != nil {
+		h.redirectWithError(w, r, req, oauthErr)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		if req.prompt == "none" {
+			h.redirectWithError(w, r, req, &oauthError{"login_required", "Connexion requise"})
+			return
+		}
+		if r.Method == http.MethodPost {
+			utils.RenderErrorPage(w, h.templates, http.StatusUnauthorized, "Session expirée", "Reconnectez-vous puis relancez la connexion depuis l'application.")
+			return
+		}
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+		return
+	}
+
+	// Décision prise sur l'écran de consentement
+	if r.Method == http.MethodPost {
+		if r.PostFormValue("decision") != "approve" {
+			h.redirectWithError(w, r, req, &oauthError{"access_denied", "L'utilisateur a refusé l'accès"})
+			return
+		}
+
+		granted, _ := h.repo.GetOAuthConsent(user.ID, req.client.ClientID)
+		if err := h.repo.SaveOAuthConsent(user.ID, req.client.ClientID, utils.RemoveDuplicates(append(granted, req.scopes...))); err != nil {
+			h.redirectWithError(w, r, req, &oauthError{"server_error", "Impossible d'enregistrer le consentement"})
+			return
+		}
+
+		h.issueCode(w, r, req, user)
+		return
+	}
+
+	// Consentement déjà accordé pour toutes les portées demandées
+	granted, _ := h.repo.GetOAuthConsent(user.ID, req.client.ClientID)
+	if req.prompt != "consent" && coversScopes(granted, req.scopes) {
+		h.issueCode(w, r, req, user)
+		return
+	}
+	if req.prompt == "none" {
+		h.redirectWithError(w, r, req, &oauthError{"consent_required", "Consentement requis"})
+		return
+	}
+
+	data := models.OAuthConsentPageData{
+		User:   user,
+		Title:  "Autoriser " + req.client.Name,
+		Client: *req.client,
+		Scopes: req.scopes,
+		Params: map[string]string{
+			"response_type":         "code",
+			"client_id":             req.client.ClientID,
+			"redirect_uri":          req.redirectURI,
+			"scope":                 strings.Join(req.scopes, " "),
+			"state":                 req.state,
+			"nonce":                 req.nonce,
+			"code_challenge":        req.codeChallenge,
+			"code_challenge_method": "S256",
+		},
+	}
+
+	// Le formulaire de consentement redirige vers l'application cliente
+	if redirect, err := url.Parse(req.redirectURI); err == nil {
+		middleware.AllowFormAction(w, redirect.Scheme+"://"+redirect.Host)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	if h.templates == nil {
+		http.Error(w, "Templates non disponibles", http.StatusInternalServerError)
+		return
+	}
+	if err := utils.ExecuteTemplate(w, h.templates, "oauth-consent.html", data); err != nil {
+		http.Error(w, "Erreur de rendu", http.StatusInternalServerError)
+	}
+}
+
+// parseAuthorizeRequest valide une demande d'autorisation. Sans client ou URI de
+// redirection valides, la demande retournée est nil : l'erreur doit être affichée
+// à l'utilisateur. Les autres erreurs sont renvoyées à l'application cliente.
+func (h *OAuthHandler) parseAuthorizeRequest(r *http.Request) (*authorizeRequest, *oauthError) {
+	clientID := r.Form.Get("client_id")
+	if clientID == "" {
+		return nil, &oauthError{"invalid_request", "Identifiant d'application manquant"}
+	}
+
+	client, err := h.repo.GetOAuthClientByClientID(clientID)
+	if err != nil || !client.IsActive() {
+		return nil, &oauthError{"invalid_client", "Application inconnue ou révoquée"}
+	}
+
+	redirectURI := r.Form.Get("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !client.HasRedirectURI(redirectURI) {
+		return nil, &oauthError{"invalid_request", "URI de redirection non enregistrée pour cette application"}
+	}
+
+	req := &authorizeRequest{
+		client:        client,
+		redirectURI:   redirectURI,
+		state:         r.Form.Get("state"),
+		nonce:         r.Form.Get("nonce"),
+		codeChallenge: r.Form.Get("code_challenge"),
+		prompt:        r.Form.Get("prompt"),
+	}
+
+	if r.Form.Get("response_type") != "code" {
+		return req, &oauthError{"unsupported_response_type", "Seul le flux par code d'autorisation est supporté"}
+	}
+
+	scope := r.Form.Get("scope")
+	if scope == "" {
+		scope = models.OAuthScopeOpenID
+	}
+	for _, s := range strings.Fields(scope) {
+		if !utils.Contains(models.OAuthScopes, s) {
+			return req, &oauthError{"invalid_scope", "Portée inconnue: " + s}
+		}
+		req.scopes = append(req.scopes, s)
+	}
+	req.scopes = utils.RemoveDuplicates(req.scopes)
+
+	// PKCE obligatoire pour toutes les applications, méthode S256 uniquement
+	if req.codeChallenge == "" {
+		return req, &oauthError{"invalid_request", "code_challenge requis (PKCE)"}
+	}
+	if r.Form.Get("code_challenge_method") != "S256" {
+		return req, &oauthError{"invalid_request", "Seule la méthode PKCE S256 est supportée"}
+	}
+	if len(req.codeChallenge) != 43 {
+		return req, &oauthError{"invalid_request", "code_challenge invalide"}
+	}
+
+	if len(req.nonce) > 255 || len(req.state) > 500 {
+		return req, &oauthError{"invalid_request", "Paramètre state ou nonce trop long"}
+	}
+
+	return req, nil
+}
+
+// issueCode délivre un code d'autorisation et redirige vers l'application cliente
+func (h *OAuthHandler) issueCode(w http.ResponseWriter, r *http.Request, req *authorizeRequest, user *models.User) {
+	code, hash, err := utils.GenerateOAuthCode()
+	if err != nil {
+		h.redirectWithError(w, r, req, &oauthError{"server_error", "Erreur lors de la génération du code"})
+		return
+	}
+
+	err = h.repo.CreateOAuthCode(&models.OAuthAuthorizationCode{
+		CodeHash:            hash,
+		ClientID:            req.client.ClientID,
+		UserID:              user.ID,
+		RedirectURI:         req.redirectURI,
+		Scopes:              req.scopes,
+		CodeChallenge:       req.codeChallenge,
+		CodeChallengeMethod: "S256",
+		Nonce:               req.nonce,
+		ExpiresAt:           time.Now().Add(h.config.OAuth.CodeTTL),
+	})
+	if err != nil {
+		h.redirectWithError(w, r, req, &oauthError{"server_error", "Erreur lors de l'enregistrement du code"})
+		return
+	}
+
+	h.redirectToClient(w, r, req, url.Values{"code": {code}})
+}
+
+// redirectWithError renvoie une erreur OAuth à l'application cliente
+func (h *OAuthHandler) redirectWithError(w http.ResponseWriter, r *http.Request, req *authorizeRequest, oauthErr *oauthError) {
+	h.redirectToClient(w, r, req, url.Values{
+		"error":             {oauthErr.Code},
+		"error_description": {oauthErr.Description},
+	})
+}
+
+// redirectToClient redirige vers l'URI de redirection enregistrée en conservant le state
+func (h *OAuthHandler) redirectToClient(w http.ResponseWriter, r *http.Request, req *authorizeRequest, params url.Values) {
+	redirect, err := url.Parse(req.redirectURI)
+	if err != nil {
+		utils.RenderErrorPage(w, h.templates, http.StatusBadRequest, "Demande d'autorisation invalide", "URI de redirection invalide")
+		return
+	}
+
+	query := redirect.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if req.state != "" {
+		query.Set("state", req.state)
+	}
+	query.Set("iss", h.config.OAuth.Issuer) // RFC 9207
+	redirect.RawQuery = query.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusSeeOther)
+}
+
+// POST /oauth/token
+// Token échange un code d'autorisation contre un access token (et un id_token si "openid")
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if r.Method != http.MethodPost {
+		h.sendOAuthError(w, http.StatusMethodNotAllowed, "invalid_request", "Méthode non autorisée")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		h.sendOAuthError(w, http.StatusBadRequest, "invalid_request", "Paramètres illisibles")
+		return
+	}
+
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		h.sendOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "Seul authorization_code est supporté")
+		return
+	}
+
+	client := h.authenticateClient(r)
+	if client == nil {
+		if _, _, ok := r.BasicAuth(); ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		h.sendOAuthError(w, http.StatusUnauthorized, "invalid_client", "Authentification de l'application échouée")
+		return
+	}
+
+	code, err := h.repo.ConsumeOAuthCode(utils.HashAPIToken(r.PostFormValue("code")))
+	if err != nil {
+		h.sendOAuthError(w, http.StatusBadRequest, "invalid_grant", "Code d'autorisation invalide, expiré ou déjà utilisé")
+		return
+	}
+
+	if code.ClientID != client.ClientID || code.RedirectURI != r.PostFormValue("redirect_uri") {
+		h.sendOAuthError(w, http.StatusBadRequest, "invalid_grant", "Code d'autorisation émis pour une autre application")
+		return
+	}
+	if !utils.VerifyPKCE(r.PostFormValue("code_verifier"), code.CodeChallenge) {
+		h.sendOAuthError(w, http.StatusBadRequest, "invalid_grant", "code_verifier invalide")
+		return
+	}
+
+	user, err := h.repo.GetUserByIDComplete(code.UserID)
+	if err != nil || user.IsBanned {
+		h.sendOAuthError(w, http.StatusBadRequest, "invalid_grant", "Compte indisponible")
+		return
+	}
+
+	now := time.Now()
+	ttl := h.config.OAuth.AccessTokenTTL
+	subject := strconv.Itoa(user.ID)
+
+	accessToken, err := h.signingKey.Sign(&models.OAuthAccessClaims{
+		ClientID: client.ClientID,
+		Scope:    strings.Join(code.Scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    h.config.OAuth.Issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{client.ClientID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}, "at+jwt")
+	if err != nil {
+		h.sendOAuthError(w, http.StatusInternalServerError, "server_error", "Erreur lors de la génération du token")
+		return
+	}
+
+	response := map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(ttl.Seconds()),
+		"scope":        strings.Join(code.Scopes, " "),
+	}
+
+	if utils.Contains(code.Scopes, models.OAuthScopeOpenID) {
+		claims := h.userClaims(user, code.Scopes)
+		claims.Nonce = code.Nonce
+		claims.Issuer = h.config.OAuth.Issuer
+		claims.Audience = jwt.ClaimStrings{client.ClientID}
+		claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+		claims.IssuedAt = jwt.NewNumericDate(now)
+
+		idToken, err := h.signingKey.Sign(claims, "JWT")
+		if err != nil {
+			h.sendOAuthError(w, http.StatusInternalServerError, "server_error", "Erreur lors de la génération du token")
+			return
+		}
+		response["id_token"] = idToken
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// authenticateClient identifie l'application (client_secret_basic, client_secret_post,
+// ou aucun secret pour une application publique protégée par PKCE)
+func (h *OAuthHandler) authenticateClient(r *http.Request) *models.OAuthClient {
+	clientID, secret, ok := r.BasicAuth()
+	if ok {
+		// RFC 6749 §2.3.1 : identifiants encodés en application/x-www-form-urlencoded
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.PostFormValue("client_id")
+		secret = r.PostFormValue("client_secret")
+	}
+	if clientID == "" {
+		return nil
+	}
+
+	client, err := h.repo.GetOAuthClientByClientID(clientID)
+	if err != nil || !client.IsActive() {
+		return nil
+	}
+
+	if !client.IsConfidential {
+		if secret != "" {
+			return nil
+		}
+		return client
+	}
+
+	if secret == "" || subtle.ConstantTimeCompare([]byte(utils.HashAPIToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil
+	}
+	return client
+}
+
+// GET/POST /oauth/userinfo
+func (h *OAuthHandler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	claims, err := h.signingKey.ParseAccessToken(utils.GetBearerToken(r), h.config.OAuth.Issuer)
+	if err != nil {
+		h.sendBearerError(w, http.StatusUnauthorized, "invalid_token", "Access token invalide ou expiré")
+		return
+	}
+
+	scopes := strings.Fields(claims.Scope)
+	if !utils.Contains(scopes, models.OAuthScopeOpenID) {
+		h.sendBearerError(w, http.StatusForbidden, "insufficient_scope", "Portée openid requise")
+		return
+	}
+
+	// Révoquer l'application ou bannir l'utilisateur coupe l'accès immédiatement
+	client, err := h.repo.GetOAuthClientByClientID(claims.ClientID)
+	if err != nil || !client.IsActive() {
+		h.sendBearerError(w, http.StatusUnauthorized, "invalid_token", "Application révoquée")
+		return
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		h.sendBearerError(w, http.StatusUnauthorized, "invalid_token", "Access token invalide")
+		return
+	}
+	user, err := h.repo.GetUserByIDComplete(userID)
+	if err != nil || user.IsBanned {
+		h.sendBearerError(w, http.StatusUnauthorized, "invalid_token", "Compte indisponible")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(h.userClaims(user, scopes))
+}
+
+// userClaims construit les informations d'identité communiquées selon les portées accordées
+func (h *OAuthHandler) userClaims(user *models.User, scopes []string) *models.IDTokenClaims {
+	claims := &models.IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: strconv.Itoa(user.ID)},
+	}
+
+	if utils.Contains(scopes, models.OAuthScopeProfile) {
+		claims.PreferredUsername = user.Username
+		claims.Name = user.Username
+		claims.Role = user.RoleName
+		if user.AvatarURL != "" {
+			claims.Picture = h.config.OAuth.Issuer + user.AvatarURL
+		}
+	}
+
+	if utils.Contains(scopes, models.OAuthScopeEmail) {
+		// Les adresses ne sont pas vérifiées à l'inscription
+		verified := false
+		claims.Email = user.Email
+		claims.EmailVerified = &verified
+	}
+
+	return claims
+}
+
+// sendOAuthError envoie une erreur du endpoint token (RFC 6749 §5.2)
+func (h *OAuthHandler) sendOAuthError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+// sendBearerError envoie une erreur d'accès à une ressource protégée (RFC 6750 §3)
+func (h *OAuthHandler) sendBearerError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="`+code+`"`)
+	h.sendOAuthError(w, status, code, description)
+}
+
+// coversScopes vérifie que les portées demandées ont toutes déjà été accordées
+func coversScopes(granted, requested []string) bool {
+	for _, scope := range requested {
+		if !utils.Contains(granted, scope) {
+			return false
+		}
+	}
+	return len(granted) > 0
+}
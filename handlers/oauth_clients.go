@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/utils"
+)
+
+// Nombre maximum d'URI de redirection par application OAuth
+const maxOAuthRedirectURIs = 10
+
+// POST /admin/oauth-clients
+// CreateOAuthClient enregistre une application cliente OAuth. Le secret n'est renvoyé qu'une seule fois.
+func (h *AdminHandler) CreateOAuthClient(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil || !user.IsAdmin() {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Accès refusé"})
+		return
+	}
+
+	var req struct {
+		Name         string `json:"name"`
+		RedirectURIs string `json:"redirect_uris"` // Une URI par ligne
+		Confidential bool   `json:"confidential"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Données invalides"})
+		return
+	}
+
+	name := utils.SanitizeInput(req.Name)
+	if len(name) < 3 || len(name) > 100 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Le nom doit faire entre 3 et 100 caractères"})
+		return
+	}
+
+	redirectURIs := utils.RemoveDuplicates(strings.Fields(req.RedirectURIs))
+	if len(redirectURIs) == 0 || len(redirectURIs) > maxOAuthRedirectURIs {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Indiquez entre 1 et 10 URI de redirection"})
+		return
+	}
+	for _, uri := range redirectURIs {
+		if !isValidRedirectURI(uri) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "URI de redirection invalide (HTTPS requis hors localhost): " + uri})
+			return
+		}
+	}
+
+	clientID, secret, secretHash, err := utils.GenerateOAuthClientCredentials()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Erreur lors de la génération des identifiants"})
+		return
+	}
+	if !req.Confidential {
+		secret, secretHash = "", ""
+	}
+
+	if _, err := h.repo.CreateOAuthClient(clientID, secretHash, name, redirectURIs, req.Confidential, user.ID); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Erreur lors de la création"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":        "success",
+		"client_id":     clientID,
+		"client_secret": secret,
+	})
+}
+
+// POST /admin/oauth-clients/revoke
+func (h *AdminHandler) RevokeOAuthClient(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil || !user.IsAdmin() {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Accès refusé"})
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil || id <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "ID d'application invalide"})
+		return
+	}
+
+	if err := h.repo.RevokeOAuthClient(id); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Application introuvable ou déjà révoquée"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// isValidRedirectURI vérifie qu'une URI de redirection est absolue, sans fragment,
+// et en HTTPS (HTTP toléré pour localhost, utile en développement)
+func isValidRedirectURI(uri string) bool {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Host == "" || parsed.Fragment != "" || len(uri) > 500 {
+		return false
+	}
+
+	switch parsed.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := parsed.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// === SONDAGES ===
+
+// parsePollForm lit le sondage facultatif du formulaire de question (aucune option = pas de
+// sondage). Il doit se clore après la publication du post (publishAt, nil = immédiate).
+// Retourne le code d'erreur affiché par create-post.html si le sondage est invalide.
+func parsePollForm(r *http.Request, publishAt *time.Time) (*models.Poll, string) {
+	question := utils.SanitizeInput(r.FormValue("poll_question"))
+
+	var options []models.PollOption
+	for _, label := range r.Form["poll_options"] {
+		if label = strings.TrimSpace(utils.SanitizeInput(label)); label != "" {
+			options = append(options, models.PollOption{Label: truncateRunes(label, 200)})
+		}
+	}
+	if question == "" && len(options) == 0 {
+		return nil, ""
+	}
+	if len(question) < 3 || len(question) > 255 ||
+		len(options) < models.MinPollOptions || len(options) > models.MaxPollOptions {
+		return nil, "poll"
+	}
+
+	closesAt, err := parseDateTimeInput(r.FormValue("poll_closes_at"))
+	if err != nil {
+		return nil, "poll_closes_at"
+	}
+	if closesAt != nil {
+		opensAt := time.Now()
+		if publishAt != nil {
+			opensAt = *publishAt
+		}
+		if !closesAt.After(opensAt) {
+			return nil, "poll_closes_at"
+		}
+	}
+
+	return &models.Poll{
+		Question:    question,
+		IsMultiple:  r.FormValue("poll_multiple") != "",
+		IsAnonymous: r.FormValue("poll_anonymous") != "",
+		ClosesAt:    closesAt,
+		Options:     options,
+	}, ""
+}
+
+// GET /polls?post_id=
+// PollResults retourne le sondage d'un post et ses résultats
+func (h *ForumHandler) PollResults(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	postID, _ := utils.GetIntParam(r, "post_id")
+	_, poll, err := h.visiblePoll(postID, user)
+	if err != nil {
+		sendJSONError(w, "Sondage non trouvé", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"poll":   poll,
+	})
+}
+
+// POST /polls/vote
+// VotePoll enregistre le vote de l'utilisateur (option_id, répété pour un choix multiple) ;
+// il remplace son vote précédent, et aucun choix retire le vote
+func (h *ForumHandler) VotePoll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	postID, _ := strconv.Atoi(r.FormValue("post_id"))
+	post, poll, err := h.visiblePoll(postID, user)
+	if err != nil {
+		sendJSONError(w, "Sondage non trouvé", http.StatusNotFound)
+		return
+	}
+	if post.Status == models.PostStatusScheduled {
+		sendJSONError(w, "Cette question n'est pas encore publiée", http.StatusBadRequest)
+		return
+	}
+	if poll.IsClosed || post.Status == models.PostStatusArchived {
+		sendJSONError(w, "Ce sondage est clos", http.StatusBadRequest)
+		return
+	}
+
+	// Les options doivent appartenir au sondage
+	known := make(map[int]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		known[option.ID] = true
+	}
+	var optionIDs []int
+	chosen := map[int]bool{}
+	for _, value := range r.Form["option_id"] {
+		optionID, err := strconv.Atoi(value)
+		if err != nil || !known[optionID] {
+			sendJSONError(w, "Option invalide", http.StatusBadRequest)
+			return
+		}
+		if !chosen[optionID] {
+			chosen[optionID] = true
+			optionIDs = append(optionIDs, optionID)
+		}
+	}
+	if !poll.IsMultiple && len(optionIDs) > 1 {
+		sendJSONError(w, "Un seul choix possible pour ce sondage", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.VotePoll(poll.ID, user.ID, optionIDs); err != nil {
+		log.Printf("Erreur vote au sondage %d: %v", poll.ID, err)
+		sendJSONError(w, "Erreur lors du vote", http.StatusInternalServerError)
+		return
+	}
+
+	message := "Vote enregistré"
+	if len(optionIDs) == 0 {
+		message = "Vote retiré"
+	}
+	poll, _ = h.repo.GetPostPoll(postID, user.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": message,
+		"poll":    poll,
+	})
+}
+
+// visiblePoll récupère un post visible par l'utilisateur et son sondage (sql.ErrNoRows sinon)
+func (h *ForumHandler) visiblePoll(postID int, user *models.User) (*models.Post, *models.Poll, error) {
+	post, err := h.repo.GetPost(postID, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userID, userRoleID := 0, 0
+	if user != nil {
+		userID, userRoleID = user.ID, user.RoleID
+	}
+	permissions, err := h.repo.GetPostPermissions(postID, user)
+	if err != nil || !permissions.CanRead || (!post.CanBeViewedBy(userID, userRoleID) && !permissions.CanModerate) {
+		return nil, nil, sql.ErrNoRows
+	}
+
+	poll, err := h.repo.GetPostPoll(postID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return post, poll, nil
+}
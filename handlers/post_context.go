@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// === NIVEAU SCOLAIRE ET DATE DE RENDU ===
+
+// Format du champ datetime-local des formulaires
+const dateTimeInputLayout = "2006-01-02T15:04"
+
+// parsePostFilter lit les filtres ?level=, ?due= et ?sort= d'une liste de posts.
+// Les valeurs inconnues sont ignorées.
+func parsePostFilter(r *http.Request) models.PostFilter {
+	filter := models.PostFilter{Sort: models.PostSortRecent}
+
+	if level := utils.GetStringParam(r, "level"); models.SchoolLevelLabel(level) != "" {
+		filter.Level = level
+	}
+
+	switch due := utils.GetStringParam(r, "due"); due {
+	case models.DueFilterUpcoming, models.DueFilterWeek, models.DueFilterPast:
+		filter.Due = due
+	}
+
+	if utils.GetStringParam(r, "sort") == models.PostSortUrgent {
+		filter.Sort = models.PostSortUrgent
+	}
+
+	return filter
+}
+
+// parseDateTimeInput lit une date saisie dans un champ datetime-local ("" = aucune)
+func parseDateTimeInput(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	at, err := time.ParseInLocation(dateTimeInputLayout, value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &at, nil
+}
@@ -81,7 +81,7 @@ func (h *ProfileHandler) Profile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Récupérer l'activité récente
-	recentActivity, err := h.repo.GetUserActivity(profileUser.ID, 10)
+	recentActivity, err := h.repo.GetUserActivity(profileUser.ID, 10, currentUser)
 	if err != nil {
 		recentActivity = []models.UserActivity{}
 	}
@@ -89,12 +89,31 @@ func (h *ProfileHandler) Profile(w http.ResponseWriter, r *http.Request) {
 	// Mettre à jour les statistiques
 	h.repo.UpdateUserStats(profileUser.ID)
 
+	// Historique de réputation, visible uniquement par l'intéressé
+	var reputationEvents []models.ReputationEvent
+	if isOwnProfile {
+		reputationEvents, _ = h.repo.GetReputationEvents(profileUser.ID, 20)
+	}
+
+	// Badges obtenus
+	badges, err := h.repo.GetUserBadges(profileUser.ID)
+	if err != nil {
+		badges = []models.UserBadge{}
+	}
+
+	// Bouton de message privé, selon les réglages de confidentialité du destinataire
+	canMessage := currentUser != nil && !isOwnProfile && h.repo.ConversationRestriction(currentUser, profileUser) == ""
+
 	data := models.ProfilePageData{
 		ProfileUser:    *profileUser,
 		IsOwnProfile:   isOwnProfile,
 		CanViewProfile: canViewProfile,
 		RecentActivity: recentActivity,
 		User:           currentUser,
+
+		ReputationEvents: reputationEvents,
+		Badges:           badges,
+		CanMessage:       canMessage,
 		Title:          fmt.Sprintf("Profil de %s", profileUser.Username),
 	}
 
@@ -116,9 +135,32 @@ func (h *ProfileHandler) Settings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Récupérer les tokens d'API personnels
+	apiTokens, err := h.repo.GetUserAPITokens(user.ID)
+	if err != nil {
+		apiTokens = []models.APIToken{}
+	}
+
+	// Questions, catégories et tags suivis
+	subscriptions, err := h.repo.GetSubscriptions(user.ID)
+	if err != nil {
+		subscriptions = []models.Subscription{}
+	}
+
+	// Utilisateurs bloqués dans la messagerie privée
+	blockedUsers, err := h.repo.GetBlockedUsers(user.ID)
+	if err != nil {
+		blockedUsers = []models.BlockedUser{}
+	}
+
 	data := models.SettingsPageData{
-		User:  fullUser,
-		Title: "Paramètres du profil",
+		User:            fullUser,
+		Title:           "Paramètres du profil",
+		APITokens:       apiTokens,
+		AvailableScopes: availableScopes(fullUser, h.repo.ModeratesAnyCategory(fullUser)),
+		Subscriptions:   subscriptions,
+		MailEnabled:     h.config.MailEnabled(),
+		BlockedUsers:    blockedUsers,
 	}
 
 	h.renderTemplate(w, "settings.html", data)
@@ -133,7 +175,7 @@ func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 
 	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
 	if !ok {
-		h.sendJSONError(w, "Non authentifié", http.StatusUnauthorized)
+		sendJSONError(w, "Non authentifié", http.StatusUnauthorized)
 		return
 	}
 
@@ -141,7 +183,7 @@ func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 
 	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10MB max
 		fmt.Printf("DEBUG UpdateProfile: Erreur ParseMultipartForm: %v\n", err)
-		h.sendJSONError(w, "Données invalides", http.StatusBadRequest)
+		sendJSONError(w, "Données invalides", http.StatusBadRequest)
 		return
 	}
 
@@ -153,12 +195,12 @@ func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("DEBUG UpdateProfile: bio='%s', location='%s', visibility='%s'\n", bio, location, visibility)
 
 	if len(bio) > 500 {
-		h.sendJSONError(w, "La bio ne peut pas dépasser 500 caractères", http.StatusBadRequest)
+		sendJSONError(w, "La bio ne peut pas dépasser 500 caractères", http.StatusBadRequest)
 		return
 	}
 
 	if len(location) > 100 {
-		h.sendJSONError(w, "La localisation ne peut pas dépasser 100 caractères", http.StatusBadRequest)
+		sendJSONError(w, "La localisation ne peut pas dépasser 100 caractères", http.StatusBadRequest)
 		return
 	}
 
@@ -172,7 +214,7 @@ func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	err := h.repo.UpdateUserProfile(user.ID, bio, location, visibility)
 	if err != nil {
 		fmt.Printf("DEBUG UpdateProfile: Erreur UpdateUserProfile: %v\n", err)
-		h.sendJSONError(w, "Erreur lors de la mise à jour", http.StatusInternalServerError)
+		sendJSONError(w, "Erreur lors de la mise à jour", http.StatusInternalServerError)
 		return
 	}
 
@@ -190,35 +232,35 @@ func (h *ProfileHandler) UpdateAvatar(w http.ResponseWriter, r *http.Request) {
 
 	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
 	if !ok {
-		h.sendJSONError(w, "Non authentifié", http.StatusUnauthorized)
+		sendJSONError(w, "Non authentifié", http.StatusUnauthorized)
 		return
 	}
 
 	// Parser le formulaire multipart
 	err := r.ParseMultipartForm(10 << 20) // 10 MB max
 	if err != nil {
-		h.sendJSONError(w, "Fichier trop volumineux", http.StatusBadRequest)
+		sendJSONError(w, "Fichier trop volumineux", http.StatusBadRequest)
 		return
 	}
 
 	// Récupérer le fichier uploadé
 	file, header, err := r.FormFile("avatar")
 	if err != nil {
-		h.sendJSONError(w, "Aucun fichier sélectionné", http.StatusBadRequest)
+		sendJSONError(w, "Aucun fichier sélectionné", http.StatusBadRequest)
 		return
 	}
 	defer file.Close()
 
 	// Valider le fichier
 	if err := utils.ValidateImageFile(file, header); err != nil {
-		h.sendJSONError(w, err.Error(), http.StatusBadRequest)
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Lire le contenu du fichier
 	fileBytes, err := io.ReadAll(file)
 	if err != nil {
-		h.sendJSONError(w, "Erreur de lecture du fichier", http.StatusInternalServerError)
+		sendJSONError(w, "Erreur de lecture du fichier", http.StatusInternalServerError)
 		return
 	}
 
@@ -231,13 +273,13 @@ func (h *ProfileHandler) UpdateAvatar(w http.ResponseWriter, r *http.Request) {
 
 	// Créer le dossier si nécessaire
 	if err := os.MkdirAll(filepath.Dir(uploadPath), 0755); err != nil {
-		h.sendJSONError(w, "Erreur de création du dossier", http.StatusInternalServerError)
+		sendJSONError(w, "Erreur de création du dossier", http.StatusInternalServerError)
 		return
 	}
 
 	// Sauvegarder le fichier
 	if err := os.WriteFile(uploadPath, fileBytes, 0644); err != nil {
-		h.sendJSONError(w, "Erreur de sauvegarde", http.StatusInternalServerError)
+		sendJSONError(w, "Erreur de sauvegarde", http.StatusInternalServerError)
 		return
 	}
 
@@ -253,7 +295,7 @@ func (h *ProfileHandler) UpdateAvatar(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// Supprimer le fichier si la BDD a échoué
 		os.Remove(uploadPath)
-		h.sendJSONError(w, "Erreur de mise à jour en base", http.StatusInternalServerError)
+		sendJSONError(w, "Erreur de mise à jour en base", http.StatusInternalServerError)
 		return
 	}
 
@@ -286,6 +328,8 @@ func (h *ProfileHandler) ServeAvatar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Le type de contenu est déduit de l'extension, jamais du contenu du fichier
+	utils.SetUploadHeaders(w, filename, utils.ContentTypeFromExtension(filename))
 	http.ServeFile(w, r, filePath)
 }
 
@@ -298,7 +342,7 @@ func (h *ProfileHandler) renderTemplate(w http.ResponseWriter, templateName stri
 		return
 	}
 
-	if err := h.templates.ExecuteTemplate(w, templateName, data); err != nil {
+	if err := utils.ExecuteTemplate(w, h.templates, templateName, data); err != nil {
 		http.Error(w, "Erreur de rendu", http.StatusInternalServerError)
 		return
 	}
@@ -316,13 +360,6 @@ func (h *ProfileHandler) renderError(w http.ResponseWriter, title, message strin
 	h.renderTemplate(w, "error.html", data)
 }
 
-// sendJSONError envoie une réponse JSON d'erreur
-func (h *ProfileHandler) sendJSONError(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
-}
-
 // sendJSONSuccess envoie une réponse JSON de succès
 func (h *ProfileHandler) sendJSONSuccess(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
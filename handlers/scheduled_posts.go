@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// === PUBLICATION PROGRAMMÉE ===
+
+// GET /scheduled-posts/edit?id=
+// EditScheduledPostPage affiche le formulaire de question pré-rempli avec un post programmé
+func (h *ForumHandler) EditScheduledPostPage(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	postID, _ := utils.GetIntParam(r, "id")
+	post, err := h.scheduledPost(postID, user)
+	if err != nil {
+		http.Error(w, "Publication programmée non trouvée", http.StatusNotFound)
+		return
+	}
+
+	categories, err := h.repo.GetCategories(user)
+	if err != nil {
+		categories = []models.Category{}
+	}
+	categories = h.postableCategories(models.FlattenCategories(categories), user)
+
+	if h.templates == nil {
+		http.Error(w, "Templates non disponibles", http.StatusInternalServerError)
+		return
+	}
+	data := map[string]interface{}{
+		"Categories": categories,
+		"User":       user,
+		"Title":      "Modifier la publication programmée",
+		"Draft":      scheduledPostDraft(post),
+		"EditPost":   post,
+	}
+	utils.RenderTemplate(w, h.templates, "create-post.html", data)
+}
+
+// POST /scheduled-posts/edit
+// UpdateScheduledPost enregistre les modifications d'un post programmé (ses images sont conservées)
+func (h *ForumHandler) UpdateScheduledPost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	postID, _ := strconv.Atoi(r.FormValue("post_id"))
+	if _, err := h.scheduledPost(postID, user); err != nil {
+		http.Error(w, "Publication programmée non trouvée", http.StatusNotFound)
+		return
+	}
+	formURL := "/scheduled-posts/edit?id=" + strconv.Itoa(postID) + "&"
+
+	form, errorCode := h.parsePostForm(r, user)
+	if errorCode == "" && form.PublishAt == nil {
+		errorCode = "publish_at"
+	}
+	if errorCode != "" {
+		http.Redirect(w, r, formURL+"error="+errorCode, http.StatusSeeOther)
+		return
+	}
+
+	post := &models.Post{
+		ID:          postID,
+		UserID:      user.ID,
+		Title:       form.Title,
+		Content:     form.Content,
+		CategoryID:  form.CategoryID,
+		Level:       form.Level,
+		DueAt:       form.DueAt,
+		PublishAt:   form.PublishAt,
+		IsAnonymous: form.IsAnonymous,
+	}
+	if err := h.repo.UpdateScheduledPost(post, form.Tags); err != nil {
+		if err == sql.ErrNoRows {
+			// Publié entre-temps
+			http.Redirect(w, r, "/post/"+strconv.Itoa(postID), http.StatusSeeOther)
+			return
+		}
+		log.Printf("Erreur modification du post programmé %d: %v", postID, err)
+		http.Redirect(w, r, formURL+"error=update", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/post/"+strconv.Itoa(postID)+"?success=schedule_updated", http.StatusSeeOther)
+}
+
+// POST /scheduled-posts/cancel
+// CancelScheduledPost annule une publication programmée : la question redevient un brouillon
+func (h *ForumHandler) CancelScheduledPost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non autorisé", http.StatusUnauthorized)
+		return
+	}
+
+	postID, _ := strconv.Atoi(r.FormValue("post_id"))
+	post, err := h.scheduledPost(postID, user)
+	if err != nil {
+		sendJSONError(w, "Publication programmée non trouvée", http.StatusNotFound)
+		return
+	}
+
+	draft := scheduledPostDraft(post)
+	draft.PublishAt = ""
+	if err := h.repo.CancelScheduledPost(post.ID, draft); err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Cette question a déjà été publiée", http.StatusConflict)
+			return
+		}
+		log.Printf("Erreur annulation du post programmé %d: %v", post.ID, err)
+		sendJSONError(w, "Erreur lors de l'annulation de la publication", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"message":  "Publication annulée : la question a été replacée dans vos brouillons",
+		"draft_id": draft.ID,
+	})
+}
+
+// scheduledPost récupère un post programmé de l'utilisateur (sql.ErrNoRows sinon)
+func (h *ForumHandler) scheduledPost(postID int, user *models.User) (*models.Post, error) {
+	post, err := h.repo.GetPost(postID, user)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID != user.ID || post.Status != models.PostStatusScheduled {
+		return nil, sql.ErrNoRows
+	}
+	return post, nil
+}
+
+// scheduledPostDraft convertit un post programmé en brouillon, pour pré-remplir le
+// formulaire de question ou le conserver après annulation
+func scheduledPostDraft(post *models.Post) *models.Draft {
+	draft := &models.Draft{
+		UserID:     post.UserID,
+		Kind:       models.DraftKindPost,
+		Title:      post.Title,
+		Content:    post.Content,
+		CategoryID: utils.IntPtr(post.CategoryID),
+		Level:      post.Level,
+		Images:     []models.Image{},
+	}
+
+	tags := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		tags = append(tags, tag.Name)
+	}
+	draft.Tags = strings.Join(tags, ", ")
+
+	if post.DueAt != nil {
+		draft.DueAt = post.DueAt.Format(dateTimeInputLayout)
+	}
+	if post.PublishAt != nil {
+		draft.PublishAt = post.PublishAt.Format(dateTimeInputLayout)
+	}
+	return draft
+}
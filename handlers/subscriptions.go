@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// === ABONNEMENTS ===
+
+// Cible d'un lien de désabonnement qui désactive le résumé par email
+const unsubscribeDigest = "digest"
+
+// POST /subscriptions
+// ToggleSubscription abonne ou désabonne (action "subscribe" ou "unsubscribe") l'utilisateur
+// d'une question ou d'une catégorie (target_type et target_id) ou d'un tag (target_type et tag)
+func (h *ForumHandler) ToggleSubscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	targetType := r.FormValue("target_type")
+	targetID, _ := strconv.Atoi(r.FormValue("target_id"))
+	subscribe := r.FormValue("action") != "unsubscribe"
+
+	// L'élément suivi doit exister et être visible (un désabonnement reste toujours possible)
+	switch targetType {
+	case models.SubscriptionPost:
+		if _, err := h.repo.GetPost(targetID, user); err != nil && subscribe {
+			sendJSONError(w, "Post non trouvé", http.StatusNotFound)
+			return
+		}
+	case models.SubscriptionCategory:
+		category, err := h.repo.GetCategory(targetID)
+		if (err != nil || !h.repo.CanViewCategory(category, user)) && subscribe {
+			sendJSONError(w, "Catégorie non trouvée", http.StatusNotFound)
+			return
+		}
+	case models.SubscriptionTag:
+		tagID, err := h.repo.GetTagID(strings.TrimPrefix(strings.TrimSpace(r.FormValue("tag")), "#"))
+		if err != nil {
+			sendJSONError(w, "Tag non trouvé", http.StatusNotFound)
+			return
+		}
+		targetID = tagID
+	default:
+		sendJSONError(w, "Type d'abonnement invalide", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	message := "Abonnement enregistré"
+	if subscribe {
+		err = h.repo.Subscribe(user.ID, targetType, targetID)
+	} else {
+		err = h.repo.Unsubscribe(user.ID, targetType, targetID)
+		message = "Abonnement supprimé"
+	}
+	if err != nil {
+		log.Printf("Erreur abonnement (%s %d): %v", targetType, targetID, err)
+		sendJSONError(w, "Erreur lors de la mise à jour de l'abonnement", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "success",
+		"message":    message,
+		"subscribed": subscribe,
+	})
+}
+
+// GET, POST /subscriptions/unsubscribe?token=
+// UnsubscribeLink traite un lien de désabonnement reçu par email, sans connexion : la page
+// demande confirmation, le formulaire envoyé supprime l'abonnement ou désactive le résumé
+func (h *ForumHandler) UnsubscribeLink(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	userID, targetType, targetID, ok := utils.ParseUnsubscribeToken(h.config.JWT.SecretKey, token)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		utils.RenderSimpleErrorPage(w, http.StatusBadRequest, "Lien invalide", "Ce lien de désabonnement est invalide.")
+		return
+	}
+
+	subject := "le résumé des abonnements par email"
+	if targetType != unsubscribeDigest {
+		subject = "cet abonnement"
+	}
+
+	if r.Method != http.MethodPost {
+		utils.RenderSimplePage(w, "Désabonnement", `
+			<form method="POST" action="/subscriptions/unsubscribe">
+				<input type="hidden" name="token" value="`+template.HTMLEscapeString(token)+`">
+				<p>Confirmez-vous le désabonnement de `+subject+` ?</p>
+				<button type="submit" class="btn btn-primary"><i class="fas fa-bell-slash"></i> Me désabonner</button>
+			</form>`)
+		return
+	}
+
+	var err error
+	if targetType == unsubscribeDigest {
+		err = h.repo.SetEmailDigest(userID, false)
+	} else {
+		err = h.repo.Unsubscribe(userID, targetType, targetID)
+	}
+	if err != nil {
+		log.Printf("Erreur désabonnement par lien (utilisateur %d): %v", userID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		utils.RenderSimpleErrorPage(w, http.StatusInternalServerError, "Erreur", "Le désabonnement a échoué, veuillez réessayer.")
+		return
+	}
+
+	utils.RenderSimplePage(w, "Désabonnement", `<p>Vous êtes désabonné de `+subject+`. `+
+		`Vos autres abonnements se gèrent depuis la page <a href="/settings#subscriptions">Paramètres</a>.</p>`)
+}
+
+// POST /settings/digest
+// UpdateEmailDigest active ou désactive le résumé des abonnements par email
+func (h *ProfileHandler) UpdateEmailDigest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non authentifié", http.StatusUnauthorized)
+		return
+	}
+
+	enabled := r.FormValue("email_digest") != ""
+	if err := h.repo.SetEmailDigest(user.ID, enabled); err != nil {
+		sendJSONError(w, "Erreur lors de la mise à jour", http.StatusInternalServerError)
+		return
+	}
+
+	if enabled {
+		h.sendJSONSuccess(w, "Résumé par email activé")
+	} else {
+		h.sendJSONSuccess(w, "Résumé par email désactivé")
+	}
+}
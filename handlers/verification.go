@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
+	"aide-devoir-forum/utils"
+)
+
+// === VALIDATION DES RÉPONSES PAR LES PROFESSEURS ===
+
+// Nombre maximum de questions affichées dans la file de relecture
+const reviewQueueLimit = 50
+
+// POST /verify-answer
+// VerifyAnswer valide (verified=true) ou retire la validation (verified=false) d'une réponse.
+// Indépendant de la solution choisie par l'auteur du post.
+func (h *ForumHandler) VerifyAnswer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		sendJSONError(w, "Non autorisé", http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.FormValue("comment_id"))
+	if err != nil || commentID <= 0 {
+		sendJSONError(w, "ID de commentaire invalide", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.repo.GetCommentByID(commentID)
+	if err != nil {
+		sendJSONError(w, "Commentaire non trouvé", http.StatusNotFound)
+		return
+	}
+
+	permissions, err := h.repo.GetPostPermissions(comment.PostID, user)
+	if err != nil || !permissions.CanVerify {
+		sendJSONError(w, "Seuls les professeurs peuvent valider une réponse", http.StatusForbidden)
+		return
+	}
+
+	// Un professeur ne valide pas ses propres réponses
+	if comment.UserID == user.ID {
+		sendJSONError(w, "Vous ne pouvez pas valider votre propre réponse", http.StatusForbidden)
+		return
+	}
+
+	verified := r.FormValue("verified") != "false"
+	if err := h.repo.SetCommentVerified(commentID, user.ID, verified); err != nil {
+		if verified {
+			sendJSONError(w, "Cette réponse est déjà validée", http.StatusBadRequest)
+		} else {
+			sendJSONError(w, "Cette réponse n'est pas validée", http.StatusBadRequest)
+		}
+		return
+	}
+
+	message := "Réponse validée"
+	if !verified {
+		message = "Validation retirée"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": message,
+	})
+}
+
+// GET /review
+// ReviewQueue liste les questions non résolues qui attendent encore la relecture d'un professeur
+func (h *ForumHandler) ReviewQueue(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if !h.repo.RoleHasPermission(user.RoleID, models.PermissionVerifyAnswers) {
+		http.Error(w, "Accès réservé aux professeurs", http.StatusForbidden)
+		return
+	}
+
+	posts, err := h.repo.GetPostsNeedingReview(reviewQueueLimit, user)
+	if err != nil {
+		log.Printf("Erreur récupération de la file de relecture: %v", err)
+		posts = []models.Post{}
+	}
+
+	data := models.ReviewPageData{
+		User:  user,
+		Title: "Questions à relire",
+		Posts: posts,
+	}
+
+	if h.templates == nil {
+		http.Error(w, "Templates non disponibles", http.StatusInternalServerError)
+		return
+	}
+	if err := utils.ExecuteTemplate(w, h.templates, "review.html", data); err != nil {
+		http.Error(w, "Erreur de rendu", http.StatusInternalServerError)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"log"
 	"net/http"
 	"path/filepath"
+	"strings"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 
@@ -14,6 +16,7 @@ import (
 	"aide-devoir-forum/database"
 	"aide-devoir-forum/handlers"
 	"aide-devoir-forum/middleware"
+	"aide-devoir-forum/models"
 	"aide-devoir-forum/utils"
 )
 
@@ -37,6 +40,10 @@ func main() {
 
 	// Créer le repository
 	repo := database.NewRepository(db)
+	repo.SetReputationRules(cfg.Reputation)
+	if err := repo.EnsureLeaderboardStats(); err != nil {
+		log.Printf("⚠️  Erreur initialisation des classements: %v", err)
+	}
 
 	// Charger les templates
 	var templates *template.Template
@@ -45,6 +52,7 @@ func main() {
 	// Créer les fonctions personnalisées pour les templates
 	funcMap := template.FuncMap{
 		"formatContent": utils.FormatContent,
+		"cspNonce":      utils.CSPNonceMarker, // Remplacé à chaque rendu (voir utils.ExecuteTemplate)
 		"mul":           func(a, b int) int { return a * b },
 		"add":           func(a, b int) int { return a + b },
 		"dict": func(values ...interface{}) map[string]interface{} {
@@ -68,10 +76,109 @@ func main() {
 				return "Suppression de post"
 			case "delete_comment":
 				return "Suppression de commentaire"
+			case "pin_post":
+				return "Épinglage de post"
+			case "unpin_post":
+				return "Désépinglage de post"
+			case "move_post":
+				return "Déplacement de post"
+			case "merge_post":
+				return "Fusion de doublon"
+			case "mark_duplicate":
+				return "Marquage comme doublon"
+			case "unmark_duplicate":
+				return "Retrait du marquage de doublon"
+			case "contest_duplicate":
+				return "Contestation de doublon"
+			case "appoint_category_moderator":
+				return "Nomination d'un modérateur de catégorie"
+			case "revoke_category_moderator":
+				return "Retrait d'un modérateur de catégorie"
+			case "reveal_author":
+				return "Levée d'anonymat"
+			case "view_conversation":
+				return "Consultation d'une conversation signalée"
+			case "resolve_report":
+				return "Signalement traité"
+			case "dismiss_report":
+				return "Signalement rejeté"
 			default:
 				return "Action " + action
 			}
 		},
+		"indent": func(depth int) string {
+			return strings.Repeat("— ", depth)
+		},
+		"formatMinRole": func(role int) string {
+			switch role {
+			case models.RoleGuest:
+				return "Tout le monde"
+			case models.RoleUser:
+				return "Utilisateurs"
+			case models.RoleProfessor:
+				return "Professeurs"
+			case models.RoleModerator:
+				return "Modérateurs"
+			default:
+				return "Administrateurs"
+			}
+		},
+		"formatReputationEvent": func(eventType string) string {
+			switch eventType {
+			case models.ReputationPostLiked:
+				return "Like reçu sur un post"
+			case models.ReputationCommentLiked:
+				return "Like reçu sur une réponse"
+			case models.ReputationPostDisliked:
+				return "Dislike reçu sur un post"
+			case models.ReputationCommentDisliked:
+				return "Dislike reçu sur une réponse"
+			case models.ReputationSolutionAccepted:
+				return "Réponse acceptée comme solution"
+			case models.ReputationAnswerVerified:
+				return "Réponse validée par un professeur"
+			case models.ReputationModerationPenalty:
+				return "Contenu supprimé par la modération"
+			case models.ReputationBountyOffered:
+				return "Prime offerte sur une question"
+			case models.ReputationBountyAwarded:
+				return "Prime remportée"
+			case models.ReputationBountyRefunded:
+				return "Prime remboursée"
+			default:
+				return eventType
+			}
+		},
+		"formatLevel":  models.SchoolLevelLabel,
+		"schoolLevels": func() []models.SchoolLevel { return models.SchoolLevels },
+		"formatScope": func(scope string) string {
+			switch scope {
+			case models.ScopeRead:
+				return "Lecture"
+			case models.ScopePost:
+				return "Publier des posts"
+			case models.ScopeComment:
+				return "Commenter"
+			case models.ScopeVote:
+				return "Voter"
+			case models.ScopeModerate:
+				return "Modérer"
+			default:
+				return scope
+			}
+		},
+		"formatOAuthScope": func(scope string) string {
+			switch scope {
+			case models.OAuthScopeOpenID:
+				return "Vous identifier avec votre compte du forum"
+			case models.OAuthScopeProfile:
+				return "Voir votre nom d'utilisateur, votre rôle et votre avatar"
+			case models.OAuthScopeEmail:
+				return "Voir votre adresse email"
+			default:
+				return scope
+			}
+		},
 	}
 
 	templates, err = template.New("").Funcs(funcMap).ParseGlob(templatePath)
@@ -82,11 +189,20 @@ func main() {
 		fmt.Println("✅ Templates chargés")
 	}
 
+	// Charger la clé de signature des tokens OAuth / OpenID Connect
+	oauthKey, err := utils.LoadOrCreateOAuthSigningKey(cfg.OAuth.SigningKeyFile)
+	if err != nil {
+		log.Fatal("Erreur clé de signature OAuth:", err)
+	}
+
 	// Créer les handlers
 	authHandler := handlers.NewAuthHandler(repo, cfg, templates)
 	forumHandler := handlers.NewForumHandler(repo, cfg, templates)
 	adminHandler := handlers.NewAdminHandler(repo, cfg, templates)
 	profileHandler := handlers.NewProfileHandler(repo, cfg, templates)
+	oauthHandler := handlers.NewOAuthHandler(repo, cfg, templates, oauthKey)
+	inviteHandler := handlers.NewInviteHandler(repo, cfg, templates)
+	classHandler := handlers.NewClassHandler(repo, cfg, templates)
 
 	// Créer le serveur HTTP
 	mux := http.NewServeMux()
@@ -99,9 +215,9 @@ func main() {
 	mux.HandleFunc("/uploads/avatars/", profileHandler.ServeAvatar)
 
 	// Routes publiques avec middleware optionnel
-	mux.HandleFunc("/", middleware.OptionalAuthWithRepo(cfg, repo)(http.HandlerFunc(forumHandler.Home)).ServeHTTP)
-	mux.HandleFunc("/search", middleware.OptionalAuthWithRepo(cfg, repo)(http.HandlerFunc(forumHandler.Search)).ServeHTTP)
-	mux.HandleFunc("/search-suggestions", middleware.OptionalAuthWithRepo(cfg, repo)(http.HandlerFunc(forumHandler.SearchSuggestions)).ServeHTTP)
+	mux.HandleFunc("/", middleware.OptionalAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeRead)(http.HandlerFunc(forumHandler.Home))).ServeHTTP)
+	mux.HandleFunc("/search", middleware.OptionalAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeRead)(http.HandlerFunc(forumHandler.Search))).ServeHTTP)
+	mux.HandleFunc("/search-suggestions", middleware.OptionalAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeRead)(http.HandlerFunc(forumHandler.SearchSuggestions))).ServeHTTP)
 
 	// Routes d'authentification (gèrent GET et POST)
 	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
@@ -120,44 +236,134 @@ func main() {
 	})
 	mux.HandleFunc("/logout", authHandler.Logout)
 
+	// Fournisseur OAuth2 / OpenID Connect
+	mux.HandleFunc("/.well-known/openid-configuration", oauthHandler.Discovery)
+	mux.HandleFunc("/oauth/jwks", oauthHandler.JWKS)
+	mux.HandleFunc("/oauth/authorize", middleware.OptionalAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(oauthHandler.Authorize))).ServeHTTP)
+	mux.HandleFunc("/oauth/token", oauthHandler.Token)
+	mux.HandleFunc("/oauth/userinfo", oauthHandler.UserInfo)
+
 	// Routes du forum avec middleware optionnel
-	mux.HandleFunc("/category/", middleware.OptionalAuthWithRepo(cfg, repo)(http.HandlerFunc(forumHandler.Category)).ServeHTTP)
-	mux.HandleFunc("/post/", middleware.OptionalAuthWithRepo(cfg, repo)(http.HandlerFunc(forumHandler.Post)).ServeHTTP)
+	mux.HandleFunc("/category/", middleware.OptionalAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeRead)(http.HandlerFunc(forumHandler.Category))).ServeHTTP)
+	mux.HandleFunc("/post/", middleware.OptionalAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeRead)(http.HandlerFunc(forumHandler.Post))).ServeHTTP)
+	mux.HandleFunc("/leaderboard", middleware.OptionalAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeRead)(http.HandlerFunc(forumHandler.Leaderboard))).ServeHTTP)
 
 	// Routes des profils avec middleware optionnel
-	mux.HandleFunc("/profile/", middleware.OptionalAuthWithRepo(cfg, repo)(http.HandlerFunc(profileHandler.Profile)).ServeHTTP)
+	mux.HandleFunc("/profile/", middleware.OptionalAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeRead)(http.HandlerFunc(profileHandler.Profile))).ServeHTTP)
+	mux.HandleFunc("/badges", middleware.OptionalAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeRead)(http.HandlerFunc(profileHandler.Badges))).ServeHTTP)
+	mux.HandleFunc("/notifications", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeRead)(http.HandlerFunc(profileHandler.Notifications))).ServeHTTP)
 
 	// Routes nécessitant une authentification
-	mux.HandleFunc("/create-post", middleware.RequireAuthWithRepo(cfg, repo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/create-post", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopePost)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
 			forumHandler.CreatePostPage(w, r)
 		} else {
 			forumHandler.CreatePost(w, r)
 		}
-	})).ServeHTTP)
-	mux.HandleFunc("/comment", middleware.RequireAuthWithRepo(cfg, repo)(http.HandlerFunc(forumHandler.CreateComment)).ServeHTTP)
-	mux.HandleFunc("/vote", middleware.RequireAuthWithRepo(cfg, repo)(http.HandlerFunc(forumHandler.Vote)).ServeHTTP)
-	mux.HandleFunc("/change-post-status", middleware.RequireAuthWithRepo(cfg, repo)(http.HandlerFunc(forumHandler.ChangePostStatus)).ServeHTTP)
-	mux.HandleFunc("/mark-solution", middleware.RequireAuthWithRepo(cfg, repo)(http.HandlerFunc(adminHandler.MarkSolution)).ServeHTTP)
-	mux.HandleFunc("/delete-own-comment", middleware.RequireAuthWithRepo(cfg, repo)(http.HandlerFunc(forumHandler.DeleteOwnComment)).ServeHTTP)
-	mux.HandleFunc("/delete-own-post", middleware.RequireAuthWithRepo(cfg, repo)(http.HandlerFunc(forumHandler.DeleteOwnPost)).ServeHTTP)
+	}))).ServeHTTP)
+	mux.HandleFunc("/comment", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeComment)(http.HandlerFunc(forumHandler.CreateComment))).ServeHTTP)
+	mux.HandleFunc("/vote", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeVote)(http.HandlerFunc(forumHandler.Vote))).ServeHTTP)
+	mux.HandleFunc("/change-post-status", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopePost)(http.HandlerFunc(forumHandler.ChangePostStatus))).ServeHTTP)
+	mux.HandleFunc("/mark-solution", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopePost)(http.HandlerFunc(adminHandler.MarkSolution))).ServeHTTP)
+	mux.HandleFunc("/unmark-solution", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopePost)(http.HandlerFunc(adminHandler.UnmarkSolution))).ServeHTTP)
+	mux.HandleFunc("/mark-duplicate", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopePost)(http.HandlerFunc(forumHandler.MarkDuplicate))).ServeHTTP)
+	mux.HandleFunc("/unmark-duplicate", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopePost)(http.HandlerFunc(forumHandler.UnmarkDuplicate))).ServeHTTP)
+	mux.HandleFunc("/contest-duplicate", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopePost)(http.HandlerFunc(forumHandler.ContestDuplicate))).ServeHTTP)
+	mux.HandleFunc("/offer-bounty", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopePost)(http.HandlerFunc(forumHandler.OfferBounty))).ServeHTTP)
+	mux.HandleFunc("/verify-answer", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeComment)(http.HandlerFunc(forumHandler.VerifyAnswer))).ServeHTTP)
+	mux.HandleFunc("/delete-own-comment", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeComment)(http.HandlerFunc(forumHandler.DeleteOwnComment))).ServeHTTP)
+	mux.HandleFunc("/delete-own-post", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopePost)(http.HandlerFunc(forumHandler.DeleteOwnPost))).ServeHTTP)
+
+	// Brouillons (sauvegarde automatique depuis les formulaires du site)
+	mux.HandleFunc("/drafts", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.Drafts))).ServeHTTP)
+	mux.HandleFunc("/drafts/save", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.SaveDraft))).ServeHTTP)
+	mux.HandleFunc("/drafts/delete", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.DeleteDraft))).ServeHTTP)
+	mux.HandleFunc("/drafts/images", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.UploadDraftImage))).ServeHTTP)
+	mux.HandleFunc("/drafts/images/delete", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.DeleteDraftImage))).ServeHTTP)
+
+	// Favoris et collections de révision (site web uniquement)
+	mux.HandleFunc("/bookmarks", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.Bookmarks))).ServeHTTP)
+	mux.HandleFunc("/bookmarks/toggle", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.ToggleBookmark))).ServeHTTP)
+	mux.HandleFunc("/bookmarks/update", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.UpdateBookmark))).ServeHTTP)
+	mux.HandleFunc("/bookmarks/export", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.ExportBookmarkCollection))).ServeHTTP)
+	mux.HandleFunc("/bookmarks/collections", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.CreateBookmarkCollection))).ServeHTTP)
+	mux.HandleFunc("/bookmarks/collections/rename", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.RenameBookmarkCollection))).ServeHTTP)
+	mux.HandleFunc("/bookmarks/collections/delete", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.DeleteBookmarkCollection))).ServeHTTP)
+
+	// Messagerie privée (site web uniquement)
+	mux.HandleFunc("/messages", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.Messages))).ServeHTTP)
+	mux.HandleFunc("/messages/conversation", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.Conversation))).ServeHTTP)
+	mux.HandleFunc("/messages/start", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.StartConversation))).ServeHTTP)
+	mux.HandleFunc("/messages/send", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.SendMessage))).ServeHTTP)
+	mux.HandleFunc("/messages/block", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.BlockUser))).ServeHTTP)
+	mux.HandleFunc("/messages/report", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.ReportConversation))).ServeHTTP)
+
+	// Publications programmées (site web uniquement)
+	mux.HandleFunc("/scheduled-posts/edit", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			forumHandler.EditScheduledPostPage(w, r)
+		} else {
+			forumHandler.UpdateScheduledPost(w, r)
+		}
+	}))).ServeHTTP)
+	mux.HandleFunc("/scheduled-posts/cancel", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.CancelScheduledPost))).ServeHTTP)
+
+	// Sondages attachés aux posts
+	mux.HandleFunc("/polls", middleware.OptionalAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeRead)(http.HandlerFunc(forumHandler.PollResults))).ServeHTTP)
+	mux.HandleFunc("/polls/vote", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeVote)(http.HandlerFunc(forumHandler.VotePoll))).ServeHTTP)
+
+	// Autocomplétion des mentions @pseudo
+	mux.HandleFunc("/users/autocomplete", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeRead)(http.HandlerFunc(forumHandler.MentionSuggestions))).ServeHTTP)
+
+	// Abonnements aux questions, catégories et tags (site web uniquement)
+	mux.HandleFunc("/subscriptions", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.ToggleSubscription))).ServeHTTP)
+	// Lien de désabonnement des emails, signé : utilisable sans connexion
+	mux.HandleFunc("/subscriptions/unsubscribe", forumHandler.UnsubscribeLink)
 
 	// Routes de gestion du profil (authentifiées)
-	mux.HandleFunc("/settings", middleware.RequireAuthWithRepo(cfg, repo)(http.HandlerFunc(profileHandler.Settings)).ServeHTTP)
-	mux.HandleFunc("/profile/update", middleware.RequireAuthWithRepo(cfg, repo)(http.HandlerFunc(profileHandler.UpdateProfile)).ServeHTTP)
-	mux.HandleFunc("/profile/avatar", middleware.RequireAuthWithRepo(cfg, repo)(http.HandlerFunc(profileHandler.UpdateAvatar)).ServeHTTP)
+	mux.HandleFunc("/settings", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(profileHandler.Settings))).ServeHTTP)
+	mux.HandleFunc("/profile/update", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(profileHandler.UpdateProfile))).ServeHTTP)
+	mux.HandleFunc("/profile/avatar", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(profileHandler.UpdateAvatar))).ServeHTTP)
+	mux.HandleFunc("/settings/tokens", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(profileHandler.CreateAPIToken))).ServeHTTP)
+	mux.HandleFunc("/settings/tokens/revoke", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(profileHandler.RevokeAPIToken))).ServeHTTP)
+	mux.HandleFunc("/settings/digest", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(profileHandler.UpdateEmailDigest))).ServeHTTP)
+	mux.HandleFunc("/settings/messages", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(profileHandler.UpdateMessagePrivacy))).ServeHTTP)
+
+	// File des questions à relire (professeurs)
+	mux.HandleFunc("/review", middleware.RequireRoleWithRepo(cfg, repo, models.RoleProfessor)(middleware.RequireScope(models.ScopeRead)(http.HandlerFunc(forumHandler.ReviewQueue))).ServeHTTP)
+
+	// Codes d'invitation (professeurs et au-delà)
+	mux.HandleFunc("/invites", middleware.RequireRoleWithRepo(cfg, repo, models.RoleProfessor)(middleware.RequireSession()(http.HandlerFunc(inviteHandler.Page))).ServeHTTP)
+	mux.HandleFunc("/invites/create", middleware.RequireRoleWithRepo(cfg, repo, models.RoleProfessor)(middleware.RequireSession()(http.HandlerFunc(inviteHandler.Create))).ServeHTTP)
+	mux.HandleFunc("/invites/revoke", middleware.RequireRoleWithRepo(cfg, repo, models.RoleProfessor)(middleware.RequireSession()(http.HandlerFunc(inviteHandler.Revoke))).ServeHTTP)
+
+	// Classes et catégories privées (professeurs et au-delà)
+	mux.HandleFunc("/classes", middleware.RequireRoleWithRepo(cfg, repo, models.RoleProfessor)(middleware.RequireSession()(http.HandlerFunc(classHandler.Page))).ServeHTTP)
+	mux.HandleFunc("/classes/create", middleware.RequireRoleWithRepo(cfg, repo, models.RoleProfessor)(middleware.RequireSession()(http.HandlerFunc(classHandler.Create))).ServeHTTP)
+	mux.HandleFunc("/classes/delete", middleware.RequireRoleWithRepo(cfg, repo, models.RoleProfessor)(middleware.RequireSession()(http.HandlerFunc(classHandler.Delete))).ServeHTTP)
+	mux.HandleFunc("/classes/members/add", middleware.RequireRoleWithRepo(cfg, repo, models.RoleProfessor)(middleware.RequireSession()(http.HandlerFunc(classHandler.AddMembers))).ServeHTTP)
+	mux.HandleFunc("/classes/members/remove", middleware.RequireRoleWithRepo(cfg, repo, models.RoleProfessor)(middleware.RequireSession()(http.HandlerFunc(classHandler.RemoveMember))).ServeHTTP)
+	mux.HandleFunc("/classes/categories/create", middleware.RequireRoleWithRepo(cfg, repo, models.RoleProfessor)(middleware.RequireSession()(http.HandlerFunc(classHandler.CreateCategory))).ServeHTTP)
 
 	// Routes d'administration
-	mux.HandleFunc("/admin", middleware.RequireAdminWithRepo(cfg, repo)(http.HandlerFunc(adminHandler.Dashboard)).ServeHTTP)
-	mux.HandleFunc("/admin/ban", middleware.RequireModeratorWithRepo(cfg, repo)(http.HandlerFunc(adminHandler.BanUser)).ServeHTTP)
-	mux.HandleFunc("/admin/unban", middleware.RequireModeratorWithRepo(cfg, repo)(http.HandlerFunc(adminHandler.UnbanUser)).ServeHTTP)
-	mux.HandleFunc("/admin/promote", middleware.RequireAdminWithRepo(cfg, repo)(http.HandlerFunc(adminHandler.PromoteUser)).ServeHTTP)
-	mux.HandleFunc("/admin/delete-post", middleware.RequireModeratorWithRepo(cfg, repo)(http.HandlerFunc(adminHandler.DeletePost)).ServeHTTP)
-	mux.HandleFunc("/admin/delete-comment", middleware.RequireModeratorWithRepo(cfg, repo)(http.HandlerFunc(adminHandler.DeleteComment)).ServeHTTP)
+	mux.HandleFunc("/admin", middleware.RequireAdminWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.Dashboard))).ServeHTTP)
+	mux.HandleFunc("/admin/ban", middleware.RequireModeratorWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.BanUser))).ServeHTTP)
+	mux.HandleFunc("/admin/unban", middleware.RequireModeratorWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.UnbanUser))).ServeHTTP)
+	mux.HandleFunc("/admin/promote", middleware.RequireAdminWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.PromoteUser))).ServeHTTP)
+	// Suppression et épinglage : modérateurs globaux ou de la catégorie (vérifié dans le handler)
+	mux.HandleFunc("/admin/delete-post", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.DeletePost))).ServeHTTP)
+	mux.HandleFunc("/admin/delete-comment", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.DeleteComment))).ServeHTTP)
+	mux.HandleFunc("/admin/pin-post", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.PinPost))).ServeHTTP)
+	mux.HandleFunc("/admin/move-post", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.MovePost))).ServeHTTP)
+	mux.HandleFunc("/admin/merge-post", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.MergePost))).ServeHTTP)
+	mux.HandleFunc("/admin/reveal-author", middleware.RequireAuthWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.RevealAuthor))).ServeHTTP)
+	// Conversations privées signalées (modérateurs, site web uniquement)
+	mux.HandleFunc("/admin/message-reports", middleware.RequireModeratorWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(forumHandler.MessageReports))).ServeHTTP)
+	mux.HandleFunc("/admin/message-reports/resolve", middleware.RequireModeratorWithRepo(cfg, repo)(middleware.RequireSession()(http.HandlerFunc(adminHandler.ResolveMessageReport))).ServeHTTP)
 
 	// Routes de gestion des catégories
-	mux.HandleFunc("/admin/categories", middleware.RequireAdminWithRepo(cfg, repo)(http.HandlerFunc(adminHandler.CreateCategory)).ServeHTTP)
-	mux.HandleFunc("/admin/categories/", middleware.RequireAdminWithRepo(cfg, repo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/admin/categories", middleware.RequireAdminWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.CreateCategory))).ServeHTTP)
+	mux.HandleFunc("/admin/categories/", middleware.RequireAdminWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "PUT" {
 			adminHandler.UpdateCategory(w, r)
 		} else if r.Method == "DELETE" {
@@ -165,12 +371,23 @@ func main() {
 		} else {
 			http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
 		}
-	})).ServeHTTP)
+	}))).ServeHTTP)
+	mux.HandleFunc("/admin/categories/reorder", middleware.RequireAdminWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.ReorderCategories))).ServeHTTP)
+	mux.HandleFunc("/admin/category-moderators", middleware.RequireAdminWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.AddCategoryModerator))).ServeHTTP)
+	mux.HandleFunc("/admin/category-moderators/remove", middleware.RequireAdminWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.RemoveCategoryModerator))).ServeHTTP)
+
+	// Routes de gestion des applications OAuth
+	mux.HandleFunc("/admin/oauth-clients", middleware.RequireAdminWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.CreateOAuthClient))).ServeHTTP)
+	mux.HandleFunc("/admin/oauth-clients/revoke", middleware.RequireAdminWithRepo(cfg, repo)(middleware.RequireScope(models.ScopeModerate)(http.HandlerFunc(adminHandler.RevokeOAuthClient))).ServeHTTP)
 
 	// Appliquer les middlewares globaux
 	handler := middleware.Logging()(mux)
 	handler = middleware.CORS()(handler)
 	handler = middleware.RateLimit()(handler)
+	handler = middleware.SecurityHeaders(cfg)(handler)
+
+	// Tâches périodiques en arrière-plan
+	go runPeriodicTasks(repo, cfg)
 
 	// Configuration du serveur
 	server := &http.Server{
@@ -181,18 +398,108 @@ func main() {
 	}
 
 	// Démarrer le serveur
-	fmt.Printf("🚀 Serveur démarré sur http://localhost:%s\n", cfg.Server.Port)
+	scheme := "http"
+	if cfg.TLSEnabled() {
+		scheme = "https"
+	}
+	fmt.Printf("🚀 Serveur démarré sur %s://localhost:%s\n", scheme, cfg.Server.Port)
 	fmt.Println("📚 Forum d'aide aux devoirs prêt !")
 	fmt.Println("\n🔗 Liens utiles :")
-	fmt.Printf("   Accueil: http://localhost:%s\n", cfg.Server.Port)
-	fmt.Printf("   Admin:   http://localhost:%s/admin\n", cfg.Server.Port)
+	fmt.Printf("   Accueil: %s://localhost:%s\n", scheme, cfg.Server.Port)
+	fmt.Printf("   Admin:   %s://localhost:%s/admin\n", scheme, cfg.Server.Port)
+	fmt.Printf("   OIDC:    %s/.well-known/openid-configuration\n", cfg.OAuth.Issuer)
 	fmt.Println("\n👤 Comptes de test :")
 	fmt.Println("   admin / admin123 (Administrateur)")
 	fmt.Println("   prof_martin / prof123 (Professeur)")
 	fmt.Println("   eleve_sarah / eleve123 (Élève)")
 	fmt.Println()
 
-	if err := server.ListenAndServe(); err != nil {
+	if cfg.TLSEnabled() {
+		err = server.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil {
 		log.Fatal("Erreur serveur:", err)
 	}
 }
+
+// runPeriodicTasks exécute chaque minute les traitements différés (expiration des primes,
+// publication des posts programmés, nettoyage des brouillons abandonnés, résumés par email)
+func runPeriodicTasks(repo *database.Repository, cfg *config.Config) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	var mailer *utils.Mailer
+	if cfg.MailEnabled() {
+		mailer = &utils.Mailer{
+			Host:     cfg.Mail.SMTPHost,
+			Port:     cfg.Mail.SMTPPort,
+			Username: cfg.Mail.Username,
+			Password: cfg.Mail.Password,
+			From:     cfg.Mail.From,
+		}
+	}
+
+	for range ticker.C {
+		if err := repo.ExpireBounties(); err != nil {
+			log.Printf("Erreur expiration des primes: %v", err)
+		}
+
+		if err := repo.PublishScheduledPosts(); err != nil {
+			log.Printf("Erreur publication des posts programmés: %v", err)
+		}
+
+		filenames, err := repo.DeleteStaleDrafts()
+		if err != nil {
+			log.Printf("Erreur nettoyage des brouillons: %v", err)
+		}
+		for _, filename := range filenames {
+			utils.DeleteImageFile(filename)
+		}
+
+		if mailer != nil {
+			sendSubscriptionDigests(repo, cfg, mailer)
+		}
+	}
+}
+
+// Nombre maximum de notifications reprises dans un résumé par email
+const digestNotificationsLimit = 30
+
+// sendSubscriptionDigests envoie le résumé des notifications d'abonnement non lues aux
+// utilisateurs qui l'ont activé, au plus une fois par cfg.Mail.DigestInterval
+func sendSubscriptionDigests(repo *database.Repository, cfg *config.Config, mailer *utils.Mailer) {
+	recipients, err := repo.GetDigestRecipients(cfg.Mail.DigestInterval)
+	if err != nil {
+		log.Printf("Erreur récupération des destinataires des résumés: %v", err)
+		return
+	}
+
+	baseURL := cfg.OAuth.Issuer
+	for _, user := range recipients {
+		notifications, err := repo.GetDigestNotifications(user.ID, digestNotificationsLimit)
+		if err != nil || len(notifications) == 0 {
+			continue
+		}
+
+		var body strings.Builder
+		fmt.Fprintf(&body, "Bonjour %s,\n\nVoici les nouveautés de vos abonnements sur le forum :\n\n", user.Username)
+		for _, notification := range notifications {
+			fmt.Fprintf(&body, "- %s\n", notification.Message)
+			if notification.Link != "" {
+				fmt.Fprintf(&body, "  %s%s\n", baseURL, notification.Link)
+			}
+		}
+		fmt.Fprintf(&body, "\nGérer vos abonnements : %s/settings#subscriptions\n", baseURL)
+		fmt.Fprintf(&body, "Ne plus recevoir ce résumé : %s/subscriptions/unsubscribe?token=%s\n",
+			baseURL, utils.UnsubscribeToken(cfg.JWT.SecretKey, user.ID, "digest", 0))
+
+		subject := fmt.Sprintf("%d nouveauté(s) dans vos abonnements", len(notifications))
+		if err := mailer.Send(user.Email, subject, body.String()); err != nil {
+			log.Printf("Erreur envoi du résumé à l'utilisateur %d: %v", user.ID, err)
+			continue
+		}
+		repo.MarkDigestSent(user.ID)
+	}
+}
@@ -38,6 +38,15 @@ func RequireAuthWithRepo(cfg *config.Config, repo interface{}) func(http.Handler
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			user := GetUserFromRequestWithRepo(r, cfg, repo)
 			if user == nil {
+				if utils.GetBearerToken(r) != "" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusUnauthorized)
+					json.NewEncoder(w).Encode(map[string]string{
+						"status": "error",
+						"error":  "Token d'API invalide, expiré ou révoqué",
+					})
+					return
+				}
 				http.Redirect(w, r, "/login", http.StatusSeeOther)
 				return
 			}
@@ -131,6 +140,17 @@ func OptionalAuthWithRepo(cfg *config.Config, repo interface{}) func(http.Handle
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			user := GetUserFromRequestWithRepo(r, cfg, repo)
 
+			// Un token d'API invalide n'est pas traité comme une visite anonyme
+			if user == nil && utils.GetBearerToken(r) != "" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]string{
+					"status": "error",
+					"error":  "Token d'API invalide, expiré ou révoqué",
+				})
+				return
+			}
+
 			var ctx context.Context
 			if user != nil {
 				ctx = context.WithValue(r.Context(), UserContextKey, user)
@@ -174,8 +194,13 @@ func GetUserFromRequest(r *http.Request, cfg *config.Config) *models.User {
 	return user
 }
 
-// GetUserFromRequestWithRepo extrait l'utilisateur du token JWT et le récupère depuis la DB
+// GetUserFromRequestWithRepo extrait l'utilisateur du token JWT et le récupère depuis la DB.
+// Un en-tête "Authorization: Bearer" est prioritaire sur le cookie de session.
 func GetUserFromRequestWithRepo(r *http.Request, cfg *config.Config, repo interface{}) *models.User {
+	if bearer := utils.GetBearerToken(r); bearer != "" {
+		return getUserFromAPIToken(r, bearer, repo)
+	}
+
 	cookie, err := r.Cookie("token")
 	if err != nil {
 		return nil
@@ -206,6 +231,79 @@ func GetUserFromRequestWithRepo(r *http.Request, cfg *config.Config, repo interf
 	return user
 }
 
+// getUserFromAPIToken authentifie une requête par token d'API personnel
+func getUserFromAPIToken(r *http.Request, token string, repo interface{}) *models.User {
+	type APITokenRepository interface {
+		GetAPITokenByHash(hash string) (*models.APIToken, error)
+		TouchAPIToken(tokenID int, ip string) error
+		GetUserByIDComplete(id int) (*models.User, error)
+	}
+
+	tokenRepo, ok := repo.(APITokenRepository)
+	if !ok {
+		return nil
+	}
+
+	apiToken, err := tokenRepo.GetAPITokenByHash(utils.HashAPIToken(token))
+	if err != nil || !apiToken.IsActive() {
+		return nil
+	}
+
+	user, err := tokenRepo.GetUserByIDComplete(apiToken.UserID)
+	if err != nil || user.IsBanned {
+		return nil
+	}
+
+	// Les portées du token limitent ce que la requête peut faire
+	user.APIScopes = append([]string{}, apiToken.Scopes...)
+
+	tokenRepo.TouchAPIToken(apiToken.ID, utils.GetClientIP(r))
+
+	return user
+}
+
+// RequireScope middleware qui vérifie que le token d'API utilisé dispose d'une portée.
+// Doit être placé après un middleware d'authentification ; les sessions navigateur passent toujours.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUserFromContext(r.Context())
+			if user != nil && !user.HasScope(scope) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]string{
+					"status": "error",
+					"error":  "Portée du token insuffisante (requis: " + scope + ")",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession middleware qui refuse les requêtes authentifiées par token d'API
+// (gestion des tokens, paramètres du compte)
+func RequireSession() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUserFromContext(r.Context())
+			if user != nil && user.IsAPIClient() {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]string{
+					"status": "error",
+					"error":  "Action impossible avec un token d'API",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // GetUserFromContext récupère l'utilisateur du contexte de la requête
 func GetUserFromContext(ctx context.Context) *models.User {
 	user, ok := ctx.Value(UserContextKey).(*models.User)
@@ -231,10 +329,11 @@ func RequireRoleWithRepo(cfg *config.Config, repo interface{}, minRole int) func
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			user := GetUserFromRequestWithRepo(r, cfg, repo)
 
-			// Vérifier si c'est une requête AJAX
+			// Vérifier si c'est une requête AJAX ou un client d'API
 			isAjax := strings.Contains(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") ||
 				strings.Contains(r.Header.Get("Accept"), "application/json") ||
-				r.Header.Get("X-Requested-With") == "XMLHttpRequest"
+				r.Header.Get("X-Requested-With") == "XMLHttpRequest" ||
+				utils.GetBearerToken(r) != ""
 
 			if user == nil {
 				if isAjax {
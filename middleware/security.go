@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"aide-devoir-forum/config"
+)
+
+// nonceResponseWriter transporte le nonce CSP de la requête jusqu'au rendu des templates
+type nonceResponseWriter struct {
+	http.ResponseWriter
+	nonce string
+}
+
+// CSPNonce retourne le nonce CSP de la requête (voir utils.RenderTemplate)
+func (w *nonceResponseWriter) CSPNonce() string {
+	return w.nonce
+}
+
+// SecurityHeaders middleware qui ajoute les en-têtes de sécurité HTTP
+// (CSP avec nonce, nosniff, Referrer-Policy, frame-ancestors et HSTS en HTTPS)
+func SecurityHeaders(cfg *config.Config) func(http.Handler) http.Handler {
+	headers := cfg.Security.Headers
+
+	return func(next http.Handler) http.Handler {
+		if !headers.Enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce, err := generateNonce()
+			if err != nil {
+				http.Error(w, "Erreur interne", http.StatusInternalServerError)
+				return
+			}
+
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "DENY")
+			if headers.ReferrerPolicy != "" {
+				h.Set("Referrer-Policy", headers.ReferrerPolicy)
+			}
+
+			// Politique CSP : le préfixe de route le plus long remplace la politique par défaut
+			policy := headers.CSP
+			reportOnly := headers.CSPReportOnly
+			matched := ""
+			for prefix, routePolicy := range headers.CSPRoutes {
+				if strings.HasPrefix(r.URL.Path, prefix) && len(prefix) > len(matched) {
+					matched = prefix
+					policy = routePolicy
+					reportOnly = ""
+				}
+			}
+			if matched != "" {
+				h.Set("X-Frame-Options", "SAMEORIGIN")
+			}
+			if policy != "" {
+				h.Set("Content-Security-Policy", withNonce(policy, nonce))
+			}
+			if reportOnly != "" {
+				h.Set("Content-Security-Policy-Report-Only", withNonce(reportOnly, nonce))
+			}
+
+			// HSTS uniquement si la connexion est chiffrée
+			if headers.HSTSMaxAge > 0 && (r.TLS != nil || cfg.TLSEnabled()) {
+				hsts := "max-age=" + strconv.Itoa(headers.HSTSMaxAge)
+				if headers.HSTSSubdomains {
+					hsts += "; includeSubDomains"
+				}
+				h.Set("Strict-Transport-Security", hsts)
+			}
+
+			next.ServeHTTP(&nonceResponseWriter{ResponseWriter: w, nonce: nonce}, r)
+		})
+	}
+}
+
+// AllowFormAction autorise une origine supplémentaire dans la directive form-action
+// de la CSP de la réponse. Les navigateurs appliquent form-action aux redirections
+// qui suivent l'envoi d'un formulaire (ex: consentement OAuth vers l'application cliente).
+func AllowFormAction(w http.ResponseWriter, origin string) {
+	policy := w.Header().Get("Content-Security-Policy")
+	if policy == "" {
+		return
+	}
+
+	directives := strings.Split(policy, ";")
+	for i, directive := range directives {
+		if strings.HasPrefix(strings.TrimSpace(directive), "form-action ") {
+			directives[i] = strings.TrimRight(directive, " ") + " " + origin
+			w.Header().Set("Content-Security-Policy", strings.Join(directives, ";"))
+			return
+		}
+	}
+}
+
+// generateNonce génère un nonce aléatoire encodé en base64
+func generateNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// withNonce remplace le marqueur {nonce} d'une politique CSP
+func withNonce(policy, nonce string) string {
+	return strings.ReplaceAll(policy, "{nonce}", "'nonce-"+nonce+"'")
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -25,8 +27,16 @@ type User struct {
 	DateInscription   *time.Time `json:"date_inscription" db:"date_inscription"`
 	Location          *string    `json:"location" db:"location"`
 	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
-	AvatarURL         string     `json:"avatar_url"` // URL calculée côté serveur
+	AuthProvider      string     `json:"auth_provider" db:"auth_provider"` // "local" ou fournisseur externe (ex: "ldap")
+	AvatarURL         string     `json:"avatar_url"`                       // URL calculée côté serveur
+	Reputation        int        `json:"reputation" db:"reputation"`
 	Stats             *UserStats `json:"stats,omitempty"`
+	APIScopes         []string   `json:"-"` // Portées du token API utilisé, nil pour une session navigateur
+
+	UnreadNotifications int    `json:"unread_notifications"` // Notifications non lues, pour l'en-tête
+	EmailDigest         bool   `json:"email_digest"`         // Résumé des abonnements par email
+	MessagePrivacy      string `json:"message_privacy"`      // Qui peut démarrer une conversation privée
+	UnreadMessages      int    `json:"unread_messages"`      // Messages privés non lus, pour l'en-tête
 }
 
 // UserStats représente les statistiques d'un utilisateur
@@ -41,6 +51,11 @@ type UserStats struct {
 	TotalViewsPosts       int       `json:"total_views_posts" db:"total_views_posts"`
 	CreatedAt             time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt             time.Time `json:"updated_at" db:"updated_at"`
+
+	// Série de jours consécutifs avec au moins une publication
+	CurrentStreak  int        `json:"current_streak" db:"current_streak"`
+	LongestStreak  int        `json:"longest_streak" db:"longest_streak"`
+	LastActiveDate *time.Time `json:"last_active_date,omitempty" db:"last_active_date"`
 }
 
 // Category représente une catégorie de matière
@@ -50,10 +65,55 @@ type Category struct {
 	Description string    `json:"description" db:"description"`
 	Color       string    `json:"color" db:"color"`
 	Icon        string    `json:"icon" db:"icon"`
-	PostCount   int       `json:"post_count" db:"post_count"`
+	PostCount   int       `json:"post_count" db:"post_count"` // Inclut les posts des sous-catégories visibles
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+
+	// Arborescence : parent (nil = catégorie racine) et ordre parmi les catégories sœurs
+	ParentID *int       `json:"parent_id,omitempty" db:"parent_id"`
+	Position int        `json:"position" db:"position"`
+	Depth    int        `json:"depth"`
+	Children []Category `json:"children,omitempty"`
+
+	// Catégorie privée d'une classe (nil = catégorie publique)
+	ClassGroupID   *int   `json:"class_group_id,omitempty" db:"class_group_id"`
+	ClassGroupName string `json:"class_group_name,omitempty"`
+
+	CategoryRules
+	Moderators []CategoryModerator `json:"moderators,omitempty"` // Chargés pour l'administration
+}
+
+// CategoryRules définit qui peut lire, publier et commenter dans une catégorie
+type CategoryRules struct {
+	ReadMinRole    int  `json:"read_min_role" db:"read_min_role"`       // RoleGuest = visiteurs compris
+	PostMinRole    int  `json:"post_min_role" db:"post_min_role"`       // Rôle minimum pour créer un post
+	CommentMinRole int  `json:"comment_min_role" db:"comment_min_role"` // Rôle minimum pour répondre
+	IsAnnouncement bool `json:"is_announcement" db:"is_announcement"`   // Seuls les modérateurs publient
+
+	// Plusieurs réponses peuvent être acceptées comme solution d'un même post
+	AllowMultipleSolutions bool `json:"allow_multiple_solutions" db:"allow_multiple_solutions"`
+
+	// Les élèves peuvent publier questions et réponses sans afficher leur nom
+	AllowAnonymous bool `json:"allow_anonymous" db:"allow_anonymous"`
+}
+
+// CategoryModerator représente un modérateur nommé pour une catégorie
+type CategoryModerator struct {
+	CategoryID  int       `json:"category_id" db:"category_id"`
+	UserID      int       `json:"user_id" db:"user_id"`
+	Username    string    `json:"username"`
+	AppointedBy int       `json:"appointed_by" db:"appointed_by"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
+// CategoryPermissions résume les droits d'un utilisateur dans une catégorie
+type CategoryPermissions struct {
+	CanRead     bool `json:"can_read"`
+	CanPost     bool `json:"can_post"`
+	CanComment  bool `json:"can_comment"`
+	CanModerate bool `json:"can_moderate"` // Supprimer, fermer, épingler, marquer une solution
+	CanVerify   bool `json:"can_verify"`   // Valider une réponse en tant que professeur (verify_answers)
+}
+
 // Tag représente un tag pour organiser les posts
 type Tag struct {
 	ID        int       `json:"id" db:"id"`
@@ -86,6 +146,26 @@ type Post struct {
 	Tags          []Tag     `json:"tags"`
 	Images        []Image   `json:"images"`
 	UserVote      string    `json:"user_vote"`
+	MergedIntoID  *int      `json:"merged_into_id,omitempty" db:"merged_into_id"` // Doublon fusionné dans ce post
+
+	UserReputation int `json:"user_reputation"` // Réputation de l'auteur
+
+	// Marquage comme doublon d'une question canonique
+	DuplicateOfID      *int   `json:"duplicate_of_id,omitempty" db:"duplicate_of_id"`
+	DuplicateOfTitle   string `json:"duplicate_of_title,omitempty"`
+	DuplicateContested bool   `json:"duplicate_contested" db:"duplicate_contested"` // Contestation de l'auteur en attente d'examen
+
+	Bounty *Bounty `json:"bounty,omitempty"` // Prime en cours, chargée pour les listes mises en avant et la page du post
+
+	// Contexte du devoir (facultatif) : niveau scolaire et date de rendu
+	Level string     `json:"level,omitempty" db:"level"`
+	DueAt *time.Time `json:"due_at,omitempty" db:"due_at"`
+
+	PublishAt *time.Time `json:"publish_at,omitempty" db:"publish_at"` // Date de publication d'un post programmé
+
+	Poll *Poll `json:"poll,omitempty"` // Sondage attaché, chargé sur la page du post
+
+	IsAnonymous bool `json:"is_anonymous" db:"is_anonymous"` // Auteur affiché comme AnonymousName
 }
 
 // Comment représente un commentaire sur un post
@@ -107,6 +187,15 @@ type Comment struct {
 	UserVote      string    `json:"user_vote"`
 	Replies       []Comment `json:"replies"`
 	Images        []Image   `json:"images"`
+
+	UserReputation int `json:"user_reputation"` // Réputation de l'auteur
+
+	// Validation par un professeur, indépendante de la solution choisie par l'auteur
+	VerifiedByID   *int       `json:"verified_by,omitempty" db:"verified_by"`
+	VerifiedByName string     `json:"verified_by_name,omitempty"`
+	VerifiedAt     *time.Time `json:"verified_at,omitempty" db:"verified_at"`
+
+	IsAnonymous bool `json:"is_anonymous" db:"is_anonymous"` // Auteur affiché comme AnonymousName
 }
 
 // Vote représente un vote (like/dislike) sur un post ou commentaire
@@ -151,6 +240,89 @@ type Report struct {
 	ResolvedAt   time.Time `json:"resolved_at" db:"resolved_at"`
 }
 
+// APIToken représente un token d'API personnel (bots, scripts)
+type APIToken struct {
+	ID          int        `json:"id" db:"id"`
+	UserID      int        `json:"user_id" db:"user_id"`
+	Name        string     `json:"name" db:"name"`
+	TokenPrefix string     `json:"token_prefix" db:"token_prefix"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	Scopes      []string   `json:"scopes" db:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at" db:"last_used_at"`
+	LastUsedIP  string     `json:"last_used_ip" db:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// OAuthClient représente une application autorisée à utiliser "Se connecter avec le forum"
+type OAuthClient struct {
+	ID             int        `json:"id" db:"id"`
+	ClientID       string     `json:"client_id" db:"client_id"`
+	SecretHash     string     `json:"-" db:"client_secret_hash"`
+	Name           string     `json:"name" db:"name"`
+	RedirectURIs   []string   `json:"redirect_uris" db:"redirect_uris"`
+	IsConfidential bool       `json:"is_confidential" db:"is_confidential"`
+	CreatedBy      int        `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt      *time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+// OAuthAuthorizationCode représente un code d'autorisation OAuth2 (usage unique)
+type OAuthAuthorizationCode struct {
+	CodeHash            string     `json:"-" db:"code_hash"`
+	ClientID            string     `json:"client_id" db:"client_id"`
+	UserID              int        `json:"user_id" db:"user_id"`
+	RedirectURI         string     `json:"redirect_uri" db:"redirect_uri"`
+	Scopes              []string   `json:"scopes" db:"scopes"`
+	CodeChallenge       string     `json:"-" db:"code_challenge"`
+	CodeChallengeMethod string     `json:"-" db:"code_challenge_method"`
+	Nonce               string     `json:"-" db:"nonce"`
+	ExpiresAt           time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt              *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+}
+
+// InviteCode représente un code d'invitation généré par un professeur ou un administrateur
+type InviteCode struct {
+	ID             int        `json:"id" db:"id"`
+	Code           string     `json:"code" db:"code"`
+	CreatedBy      int        `json:"created_by" db:"created_by"`
+	CreatorName    string     `json:"creator_name"`
+	RoleID         int        `json:"role_id" db:"role_id"`
+	RoleName       string     `json:"role_name"`
+	ClassGroupID   *int       `json:"class_group_id" db:"class_group_id"`
+	ClassGroupName string     `json:"class_group_name"`
+	MaxUses        int        `json:"max_uses" db:"max_uses"`
+	Uses           int        `json:"uses" db:"uses"`
+	ExpiresAt      *time.Time `json:"expires_at" db:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UsedBy         []string   `json:"used_by"` // Comptes créés avec ce code
+}
+
+// ClassGroup représente une classe animée par un professeur
+type ClassGroup struct {
+	ID          int       `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	OwnerID     int       `json:"owner_id" db:"owner_id"`
+	OwnerName   string    `json:"owner_name"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+
+	// Chargés pour la page de gestion des classes
+	Members    []ClassGroupMember `json:"members,omitempty"`
+	Categories []Category         `json:"categories,omitempty"`
+}
+
+// ClassGroupMember représente un élève inscrit dans une classe
+type ClassGroupMember struct {
+	UserID   int       `json:"user_id" db:"user_id"`
+	Username string    `json:"username"`
+	RoleName string    `json:"role_name"`
+	JoinedAt time.Time `json:"joined_at" db:"joined_at"`
+}
+
 // JWT Claims pour l'authentification
 type Claims struct {
 	UserID   int    `json:"user_id"`
@@ -159,20 +331,472 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// OAuthAccessClaims représente le contenu d'un access token OAuth2 (RFC 9068)
+type OAuthAccessClaims struct {
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+	jwt.RegisteredClaims
+}
+
+// IDTokenClaims représente le contenu d'un id_token OpenID Connect
+type IDTokenClaims struct {
+	Nonce             string `json:"nonce,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Picture           string `json:"picture,omitempty"`
+	Role              string `json:"role,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+	jwt.RegisteredClaims
+}
+
 // Constantes pour les rôles
 const (
+	RoleGuest         = 0 // Visiteur non connecté (règles des catégories)
 	RoleUser          = 1
 	RoleProfessor     = 2
 	RoleModerator     = 3
 	RoleAdministrator = 4
 )
 
+// Permissions des rôles (colonne JSON roles.permissions, "all" les accorde toutes)
+const (
+	PermissionAll           = "all"
+	PermissionVerifyAnswers = "verify_answers"
+)
+
+// ReputationRules définit les points attribués par événement (configurables)
+type ReputationRules struct {
+	PostLike          int // Like reçu sur un post
+	CommentLike       int // Like reçu sur une réponse
+	DislikeReceived   int // Dislike reçu (valeur négative)
+	SolutionAccepted  int // Réponse acceptée comme solution
+	AnswerVerified    int // Réponse validée par un professeur
+	ModerationPenalty int // Contenu supprimé par un modérateur (valeur négative)
+	DailyCap          int // Plafond quotidien des points gagnés par les likes (0 = illimité)
+}
+
+// ReputationEvent représente une ligne du journal de réputation. Une annulation
+// (vote retiré, contenu supprimé) est une nouvelle ligne de points opposés.
+type ReputationEvent struct {
+	ID         int       `json:"id" db:"id"`
+	UserID     int       `json:"user_id" db:"user_id"`
+	ActorID    *int      `json:"actor_id,omitempty" db:"actor_id"`
+	EventType  string    `json:"event_type" db:"event_type"`
+	SourceType string    `json:"source_type" db:"source_type"` // "post" ou "comment"
+	SourceID   int       `json:"source_id" db:"source_id"`
+	Points     int       `json:"points" db:"points"`
+	ReversesID *int      `json:"reverses_id,omitempty" db:"reverses_id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Types d'événements de réputation
+const (
+	ReputationPostLiked         = "post_liked"
+	ReputationCommentLiked      = "comment_liked"
+	ReputationPostDisliked      = "post_disliked"
+	ReputationCommentDisliked   = "comment_disliked"
+	ReputationSolutionAccepted  = "solution_accepted"
+	ReputationAnswerVerified    = "answer_verified"
+	ReputationModerationPenalty = "moderation_penalty"
+	ReputationBountyOffered     = "bounty_offered"
+	ReputationBountyAwarded     = "bounty_awarded"
+	ReputationBountyRefunded    = "bounty_refunded"
+)
+
+// BountyRules définit les limites des primes (configurables)
+type BountyRules struct {
+	MinAmount    int // Montant minimum d'une prime
+	MaxAmount    int // Montant maximum d'une prime
+	DurationDays int // Durée d'une prime avant expiration
+}
+
+// Bounty représente une prime de réputation offerte par l'auteur d'une question.
+// Les mouvements de points sont inscrits dans le journal de réputation.
+type Bounty struct {
+	ID               int        `json:"id" db:"id"`
+	PostID           int        `json:"post_id" db:"post_id"`
+	UserID           int        `json:"user_id" db:"user_id"`
+	Amount           int        `json:"amount" db:"amount"`
+	Status           string     `json:"status" db:"status"`
+	AwardedToID      *int       `json:"awarded_to,omitempty" db:"awarded_to"`
+	AwardedToName    string     `json:"awarded_to_name,omitempty"`
+	AwardedCommentID *int       `json:"awarded_comment_id,omitempty" db:"awarded_comment_id"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt        time.Time  `json:"expires_at" db:"expires_at"`
+	ResolvedAt       *time.Time `json:"resolved_at,omitempty" db:"resolved_at"`
+}
+
+// États d'une prime
+const (
+	BountyOpen     = "open"
+	BountyAwarded  = "awarded"  // Attribuée à la solution acceptée ou à la meilleure réponse
+	BountyRefunded = "refunded" // Aucune réponse à l'expiration : montant rendu
+	BountyExpired  = "expired"  // Réponses sans vote positif à l'expiration : montant perdu
+)
+
+// Poll représente un sondage attaché à un post. Un utilisateur vote une fois : voter
+// à nouveau remplace ses choix précédents.
+type Poll struct {
+	ID          int          `json:"id" db:"id"`
+	PostID      int          `json:"post_id" db:"post_id"`
+	Question    string       `json:"question" db:"question"`
+	IsMultiple  bool         `json:"is_multiple" db:"is_multiple"`   // Plusieurs choix possibles
+	IsAnonymous bool         `json:"is_anonymous" db:"is_anonymous"` // Les votants ne sont pas affichés
+	ClosesAt    *time.Time   `json:"closes_at,omitempty" db:"closes_at"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+	Options     []PollOption `json:"options"`
+	VotersCount int          `json:"voters_count"`
+	IsClosed    bool         `json:"is_closed"` // Date de clôture dépassée
+	HasVoted    bool         `json:"has_voted"` // L'utilisateur connecté a voté
+}
+
+// PollOption représente un choix d'un sondage avec ses résultats
+type PollOption struct {
+	ID         int      `json:"id" db:"id"`
+	Label      string   `json:"label" db:"label"`
+	VotesCount int      `json:"votes_count" db:"votes_count"`
+	Percent    int      `json:"percent"`          // Part des votants ayant choisi cette option
+	Voters     []string `json:"voters,omitempty"` // Votes visibles uniquement
+	Selected   bool     `json:"selected"`         // Choisie par l'utilisateur connecté
+}
+
+// Limites d'un sondage
+const (
+	MinPollOptions = 2
+	MaxPollOptions = 10
+)
+
+// BadgeDefinition décrit un badge : il est attribué dès que la métrique atteint le seuil
+type BadgeDefinition struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"` // Classe Font Awesome
+	Tier        string `json:"tier"`
+	Metric      string `json:"metric"`
+	Threshold   int    `json:"threshold"`
+}
+
+// Niveaux des badges
+const (
+	BadgeTierBronze = "bronze"
+	BadgeTierSilver = "silver"
+	BadgeTierGold   = "gold"
+)
+
+// Métriques évaluées par les règles de badges
+const (
+	BadgeMetricAnswers          = "answers"           // Réponses publiées
+	BadgeMetricSolutions        = "solutions"         // Réponses acceptées comme solution
+	BadgeMetricLikes            = "likes"             // Likes reçus (posts et réponses)
+	BadgeMetricCategoriesHelped = "categories_helped" // Catégories où une réponse a été acceptée ou validée
+	BadgeMetricStreak           = "streak"            // Plus longue série de jours d'activité consécutifs
+)
+
+// BadgeDefinitions liste les règles de badges, dans l'ordre d'affichage
+var BadgeDefinitions = []BadgeDefinition{
+	{Code: "first_answer", Name: "Première réponse", Description: "Publier sa première réponse", Icon: "fa-comment", Tier: BadgeTierBronze, Metric: BadgeMetricAnswers, Threshold: 1},
+	{Code: "answers_50", Name: "Pilier du forum", Description: "Publier 50 réponses", Icon: "fa-comments", Tier: BadgeTierSilver, Metric: BadgeMetricAnswers, Threshold: 50},
+	{Code: "first_solution", Name: "Premier coup de main", Description: "Voir une réponse acceptée comme solution", Icon: "fa-check", Tier: BadgeTierBronze, Metric: BadgeMetricSolutions, Threshold: 1},
+	{Code: "solutions_10", Name: "Solutionneur", Description: "Voir 10 réponses acceptées comme solution", Icon: "fa-check-double", Tier: BadgeTierSilver, Metric: BadgeMetricSolutions, Threshold: 10},
+	{Code: "solutions_50", Name: "Expert", Description: "Voir 50 réponses acceptées comme solution", Icon: "fa-graduation-cap", Tier: BadgeTierGold, Metric: BadgeMetricSolutions, Threshold: 50},
+	{Code: "likes_100", Name: "Apprécié", Description: "Recevoir 100 likes", Icon: "fa-thumbs-up", Tier: BadgeTierSilver, Metric: BadgeMetricLikes, Threshold: 100},
+	{Code: "likes_500", Name: "Incontournable", Description: "Recevoir 500 likes", Icon: "fa-heart", Tier: BadgeTierGold, Metric: BadgeMetricLikes, Threshold: 500},
+	{Code: "categories_5", Name: "Polyvalent", Description: "Aider dans 5 matières différentes", Icon: "fa-shapes", Tier: BadgeTierSilver, Metric: BadgeMetricCategoriesHelped, Threshold: 5},
+	{Code: "streak_7", Name: "Assidu", Description: "Participer 7 jours d'affilée", Icon: "fa-fire", Tier: BadgeTierBronze, Metric: BadgeMetricStreak, Threshold: 7},
+	{Code: "streak_30", Name: "Infatigable", Description: "Participer 30 jours d'affilée", Icon: "fa-fire-alt", Tier: BadgeTierGold, Metric: BadgeMetricStreak, Threshold: 30},
+}
+
+// GetBadgeDefinition retourne la définition d'un badge par son code
+func GetBadgeDefinition(code string) (BadgeDefinition, bool) {
+	for _, badge := range BadgeDefinitions {
+		if badge.Code == code {
+			return badge, true
+		}
+	}
+	return BadgeDefinition{}, false
+}
+
+// UserBadge représente un badge obtenu par un utilisateur
+type UserBadge struct {
+	BadgeDefinition
+	UserID    int       `json:"user_id" db:"user_id"`
+	AwardedAt time.Time `json:"awarded_at" db:"awarded_at"`
+}
+
+// BadgeCatalogEntry représente un badge sur la page des badges
+type BadgeCatalogEntry struct {
+	BadgeDefinition
+	HoldersCount int        `json:"holders_count"`
+	AwardedAt    *time.Time `json:"awarded_at,omitempty"` // Date d'obtention par l'utilisateur connecté
+}
+
+// Notification représente une notification adressée à un utilisateur
+type Notification struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Type      string    `json:"type" db:"type"`
+	Message   string    `json:"message" db:"message"`
+	Link      string    `json:"link,omitempty" db:"link"`
+	IsRead    bool      `json:"is_read" db:"is_read"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Types de notifications
+const (
+	NotificationBadgeAwarded       = "badge_awarded"
+	NotificationBountyAwarded      = "bounty_awarded"
+	NotificationBountyExpired      = "bounty_expired"
+	NotificationPostPublished      = "post_published"      // Post programmé publié (auteur)
+	NotificationClassPost          = "class_post"          // Nouvelle publication programmée dans une classe (élèves)
+	NotificationMention            = "mention"             // Mention @pseudo dans une question ou une réponse
+	NotificationNewAnswer          = "new_answer"          // Nouvelle réponse sur une question suivie
+	NotificationNewPost            = "new_post"            // Nouvelle question dans une catégorie ou un tag suivi
+	NotificationPrivateMessage     = "private_message"     // Nouveau message privé
+	NotificationMessageReport      = "message_report"      // Conversation signalée (modérateurs)
+	NotificationDuplicateContested = "duplicate_contested" // Marquage de doublon contesté par l'auteur (modérateurs)
+)
+
+// SubscriptionNotificationTypes liste les notifications des abonnements, reprises dans le résumé par email
+var SubscriptionNotificationTypes = []string{NotificationNewAnswer, NotificationNewPost}
+
+// Limites des mentions notifiées, contre les notifications abusives : les mentions
+// au-delà restent des liens vers les profils mais ne sont pas notifiées
+const (
+	MaxMentionsPerMessage = 5  // Utilisateurs notifiés par question ou réponse
+	MaxMentionsPerHour    = 20 // Utilisateurs notifiés par un même auteur sur une heure glissante
+)
+
+// Subscription représente le suivi d'une question, d'une catégorie ou d'un tag
+type Subscription struct {
+	ID         int       `json:"id" db:"id"`
+	UserID     int       `json:"user_id" db:"user_id"`
+	TargetType string    `json:"target_type" db:"target_type"`
+	TargetID   int       `json:"target_id" db:"target_id"`
+	TargetName string    `json:"target_name"` // Titre de la question, nom de la catégorie ou du tag
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Éléments pouvant être suivis
+const (
+	SubscriptionPost     = "post"
+	SubscriptionCategory = "category"
+	SubscriptionTag      = "tag"
+)
+
+// Link retourne la page de l'élément suivi
+func (s Subscription) Link() string {
+	switch s.TargetType {
+	case SubscriptionCategory:
+		return fmt.Sprintf("/category/%d", s.TargetID)
+	case SubscriptionTag:
+		return "/search?q=" + url.QueryEscape("#"+s.TargetName)
+	default:
+		return fmt.Sprintf("/post/%d", s.TargetID)
+	}
+}
+
+// MentionSuggestion représente un utilisateur proposé par l'autocomplétion des mentions
+type MentionSuggestion struct {
+	Username  string `json:"username"`
+	RoleName  string `json:"role_name"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+// Bookmark représente une question ou une réponse enregistrée par un utilisateur pour ses
+// révisions, avec une note personnelle et éventuellement une collection
+type Bookmark struct {
+	ID             int       `json:"id" db:"id"`
+	UserID         int       `json:"user_id" db:"user_id"`
+	PostID         int       `json:"post_id" db:"post_id"`
+	CommentID      *int      `json:"comment_id,omitempty" db:"comment_id"` // nil = la question elle-même
+	CollectionID   *int      `json:"collection_id,omitempty" db:"collection_id"`
+	CollectionName string    `json:"collection_name,omitempty"`
+	Note           string    `json:"note" db:"note"`
+	PostTitle      string    `json:"post_title"`
+	CategoryID     int       `json:"category_id"`
+	CategoryName   string    `json:"category_name"`
+	Content        string    `json:"content"`     // Contenu de la question ou de la réponse
+	AuthorName     string    `json:"author_name"` // AnonymousName pour un contenu anonyme
+	IsSolution     bool      `json:"is_solution"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// Link retourne l'URL de la question ou de la réponse enregistrée
+func (b Bookmark) Link() string {
+	if b.CommentID != nil {
+		return fmt.Sprintf("/post/%d#comment-%d", b.PostID, *b.CommentID)
+	}
+	return fmt.Sprintf("/post/%d", b.PostID)
+}
+
+// BookmarkCollection représente une collection nommée de favoris (ex : « Bac de maths »)
+type BookmarkCollection struct {
+	ID             int       `json:"id" db:"id"`
+	UserID         int       `json:"user_id" db:"user_id"`
+	Name           string    `json:"name" db:"name"`
+	BookmarksCount int       `json:"bookmarks_count"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// BookmarkFilter regroupe les filtres de la page des favoris
+type BookmarkFilter struct {
+	Query        string `json:"q,omitempty"`             // Recherche dans le titre, le contenu et la note
+	CollectionID int    `json:"collection_id,omitempty"` // 0 = toutes les collections
+	Unsorted     bool   `json:"unsorted,omitempty"`      // Favoris sans collection uniquement
+	Kind         string `json:"kind,omitempty"`          // BookmarkKindPost ou BookmarkKindComment ("" = tous)
+	CategoryID   int    `json:"category_id,omitempty"`
+}
+
+// Types de favoris
+const (
+	BookmarkKindPost    = "post"
+	BookmarkKindComment = "comment"
+)
+
+// Limites des favoris
+const (
+	MaxBookmarkCollections = 50   // Collections par utilisateur
+	MaxBookmarkNoteLength  = 1000 // Caractères d'une note
+	MaxBookmarksListed     = 200  // Favoris affichés ou exportés à la fois
+)
+
+// Conversation représente une conversation privée entre deux utilisateurs, vue par l'un d'eux
+type Conversation struct {
+	ID             int       `json:"id" db:"id"`
+	User1ID        int       `json:"user1_id" db:"user1_id"` // Plus petit identifiant des deux participants
+	User2ID        int       `json:"user2_id" db:"user2_id"`
+	StartedBy      int       `json:"started_by" db:"started_by"`
+	OtherUserID    int       `json:"other_user_id"`
+	OtherUsername  string    `json:"other_username"`
+	OtherAvatarURL string    `json:"other_avatar_url,omitempty"`
+	LastMessage    string    `json:"last_message"` // Extrait du dernier message
+	UnreadCount    int       `json:"unread_count"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	LastMessageAt  time.Time `json:"last_message_at" db:"last_message_at"`
+}
+
+// HasParticipant vérifie qu'un utilisateur participe à la conversation
+func (c *Conversation) HasParticipant(userID int) bool {
+	return c.User1ID == userID || c.User2ID == userID
+}
+
+// PrivateMessage représente un message d'une conversation privée
+type PrivateMessage struct {
+	ID             int        `json:"id" db:"id"`
+	ConversationID int        `json:"conversation_id" db:"conversation_id"`
+	SenderID       int        `json:"sender_id" db:"sender_id"`
+	SenderName     string     `json:"sender_name"`
+	Content        string     `json:"content" db:"content"`
+	ReadAt         *time.Time `json:"read_at,omitempty" db:"read_at"` // Accusé de lecture du destinataire
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// BlockedUser représente un utilisateur bloqué dans la messagerie privée
+type BlockedUser struct {
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ConversationReport représente le signalement d'une conversation privée aux modérateurs
+type ConversationReport struct {
+	ID             int        `json:"id" db:"id"`
+	ConversationID int        `json:"conversation_id" db:"reported_id"`
+	ReporterID     int        `json:"reporter_id" db:"reporter_id"`
+	ReporterName   string     `json:"reporter_name"`
+	ReportedName   string     `json:"reported_name"` // L'autre participant
+	Reason         string     `json:"reason" db:"reason"`
+	Description    string     `json:"description" db:"description"`
+	Status         string     `json:"status" db:"status"`
+	ModeratorName  string     `json:"moderator_name,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty" db:"resolved_at"`
+}
+
+// Confidentialité de la messagerie privée : qui peut démarrer une conversation.
+// Un profil privé n'accepte que ses contacts, même réglé sur MessagePrivacyEveryone.
+const (
+	MessagePrivacyEveryone = "everyone"
+	MessagePrivacyContacts = "contacts" // Utilisateurs déjà rencontrés sur le forum (réponses, classes)
+	MessagePrivacyNobody   = "nobody"
+)
+
+// Statuts des signalements
+const (
+	ReportPending   = "pending"
+	ReportReviewed  = "reviewed" // Conversation consultée par un modérateur
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+)
+
+// Limites de la messagerie privée
+const (
+	MaxMessageLength           = 5000 // Caractères d'un message
+	MaxNewConversationsPerHour = 10   // Conversations démarrées par un même utilisateur sur une heure glissante
+	MaxMessagesListed          = 500  // Derniers messages affichés d'une conversation
+)
+
+// LeaderboardEntry représente une ligne d'un classement
+type LeaderboardEntry struct {
+	Rank        int    `json:"rank"`
+	UserID      int    `json:"user_id,omitempty"`
+	Username    string `json:"username,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	IsAnonymous bool   `json:"is_anonymous"` // Profil privé : nom masqué
+	Total       int    `json:"total"`
+}
+
+// Critères de classement
+const (
+	LeaderboardMetricSolutions  = "solutions"
+	LeaderboardMetricLikes      = "likes"
+	LeaderboardMetricReputation = "reputation"
+)
+
+// Périodes de classement
+const (
+	LeaderboardPeriodWeek       = "week"
+	LeaderboardPeriodMonth      = "month"
+	LeaderboardPeriodSchoolYear = "year" // Depuis le 1er septembre
+	LeaderboardPeriodAllTime    = "all"
+)
+
 // Constantes pour les types de votes
 const (
 	VoteLike    = "like"
 	VoteDislike = "dislike"
 )
 
+// Constantes pour les portées des tokens API
+const (
+	ScopeRead     = "read"
+	ScopePost     = "post"
+	ScopeComment  = "comment"
+	ScopeVote     = "vote"
+	ScopeModerate = "moderate"
+)
+
+// APIScopes liste les portées qu'un token API peut recevoir
+var APIScopes = []string{ScopeRead, ScopePost, ScopeComment, ScopeVote, ScopeModerate}
+
+// Constantes pour les fournisseurs d'identité
+const (
+	AuthProviderLocal = "local"
+	AuthProviderLDAP  = "ldap"
+)
+
+// Constantes pour les portées OAuth2 / OpenID Connect
+const (
+	OAuthScopeOpenID  = "openid"
+	OAuthScopeProfile = "profile"
+	OAuthScopeEmail   = "email"
+)
+
+// OAuthScopes liste les portées qu'une application cliente peut demander
+var OAuthScopes = []string{OAuthScopeOpenID, OAuthScopeProfile, OAuthScopeEmail}
+
 // Constantes pour les statuts de signalement
 const (
 	ReportStatusPending   = "pending"
@@ -183,11 +807,97 @@ const (
 
 // Constantes pour les statuts de posts
 const (
-	PostStatusOpen     = "open"
-	PostStatusClosed   = "closed"
-	PostStatusArchived = "archived"
+	PostStatusOpen      = "open"
+	PostStatusClosed    = "closed"
+	PostStatusArchived  = "archived"
+	PostStatusScheduled = "scheduled" // Publication programmée, invisible jusqu'à publish_at
+)
+
+// SchoolLevel représente un niveau scolaire proposé sur les questions
+type SchoolLevel struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+}
+
+// SchoolLevels liste les niveaux scolaires, du collège à l'université
+var SchoolLevels = []SchoolLevel{
+	{Code: "6e", Label: "6e"},
+	{Code: "5e", Label: "5e"},
+	{Code: "4e", Label: "4e"},
+	{Code: "3e", Label: "3e"},
+	{Code: "seconde", Label: "Seconde"},
+	{Code: "premiere", Label: "Première"},
+	{Code: "terminale", Label: "Terminale"},
+	{Code: "universite", Label: "Université"},
+}
+
+// SchoolLevelLabel retourne le libellé d'un niveau scolaire ("" si inconnu)
+func SchoolLevelLabel(code string) string {
+	for _, level := range SchoolLevels {
+		if level.Code == code {
+			return level.Label
+		}
+	}
+	return ""
+}
+
+// PostFilter regroupe les filtres et le tri des listes de posts (catégorie, recherche)
+type PostFilter struct {
+	Level string `json:"level,omitempty"` // Code de niveau scolaire ("" = tous)
+	Due   string `json:"due,omitempty"`   // DueFilterUpcoming, DueFilterWeek ou DueFilterPast ("" = tous)
+	Sort  string `json:"sort,omitempty"`  // PostSortUrgent, sinon les plus récents
+}
+
+// IsEmpty indique qu'aucun filtre ni tri particulier n'est demandé
+func (f PostFilter) IsEmpty() bool {
+	return f.Level == "" && f.Due == "" && f.Sort != PostSortUrgent
+}
+
+// Filtres d'échéance et tris des listes de posts
+const (
+	DueFilterUpcoming = "upcoming" // Date de rendu à venir
+	DueFilterWeek     = "week"     // À rendre dans les 7 prochains jours
+	DueFilterPast     = "past"     // Date de rendu dépassée
+
+	PostSortRecent = "recent"
+	PostSortUrgent = "urgent" // Questions non résolues à rendre bientôt d'abord
+)
+
+// ArchiveSuggestionDelay est le délai après la date de rendu au-delà duquel l'archivage
+// d'une question est suggéré à son auteur et aux modérateurs
+const ArchiveSuggestionDelay = 30 * 24 * time.Hour
+
+// Draft représente un brouillon de question ou de réponse, enregistré automatiquement
+// pendant la saisie. Les images ajoutées sont conservées en attente jusqu'à la publication.
+type Draft struct {
+	ID           int       `json:"id" db:"id"`
+	UserID       int       `json:"user_id" db:"user_id"`
+	Kind         string    `json:"kind" db:"kind"`
+	PostID       *int      `json:"post_id,omitempty" db:"post_id"`     // Question visée par une réponse
+	ParentID     *int      `json:"parent_id,omitempty" db:"parent_id"` // Commentaire visé par une réponse imbriquée
+	PostTitle    string    `json:"post_title,omitempty"`
+	Title        string    `json:"title" db:"title"`
+	Content      string    `json:"content" db:"content"`
+	CategoryID   *int      `json:"category_id,omitempty" db:"category_id"`
+	CategoryName string    `json:"category_name,omitempty"`
+	Tags         string    `json:"tags" db:"tags"`
+	Level        string    `json:"level" db:"level"`
+	DueAt        string    `json:"due_at" db:"due_at"`         // Valeur brute du champ datetime-local
+	PublishAt    string    `json:"publish_at" db:"publish_at"` // Publication programmée, même format
+	Images       []Image   `json:"images"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Types de brouillons
+const (
+	DraftKindPost    = "post"
+	DraftKindComment = "comment"
 )
 
+// DraftRetention est la durée sans modification au-delà de laquelle un brouillon est supprimé
+const DraftRetention = 30 * 24 * time.Hour
+
 // Méthodes utilitaires pour User
 func (u *User) IsAdmin() bool {
 	return u.RoleID >= RoleAdministrator
@@ -209,6 +919,90 @@ func (u *User) CanPromoteUsers() bool {
 	return u.RoleID >= RoleAdministrator && !u.IsBanned
 }
 
+// IsAPIClient indique si l'utilisateur est authentifié par un token API
+func (u *User) IsAPIClient() bool {
+	return u.APIScopes != nil
+}
+
+// HasScope vérifie qu'une requête authentifiée par token API dispose d'une portée.
+// Une session navigateur dispose de toutes les portées.
+func (u *User) HasScope(scope string) bool {
+	if !u.IsAPIClient() {
+		return true
+	}
+	for _, s := range u.APIScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Méthodes utilitaires pour APIToken
+
+// IsActive vérifie qu'un token n'est ni révoqué ni expiré
+func (t *APIToken) IsActive() bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || t.ExpiresAt.After(time.Now())
+}
+
+// IsExpired vérifie si un token a dépassé sa date d'expiration
+func (t *APIToken) IsExpired() bool {
+	return t.ExpiresAt != nil && !t.ExpiresAt.After(time.Now())
+}
+
+// Méthodes utilitaires pour OAuthClient
+
+// IsActive vérifie qu'une application cliente n'a pas été révoquée
+func (c *OAuthClient) IsActive() bool {
+	return c.RevokedAt == nil
+}
+
+// HasRedirectURI vérifie qu'une URI de redirection est enregistrée (comparaison exacte)
+func (c *OAuthClient) HasRedirectURI(uri string) bool {
+	for _, registered := range c.RedirectURIs {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// Méthodes utilitaires pour Category
+
+// IsPrivate indique si la catégorie est réservée aux membres d'une classe
+func (c Category) IsPrivate() bool {
+	return c.ClassGroupID != nil
+}
+
+// FlattenCategories parcourt une arborescence de catégories en profondeur
+// (parent puis sous-catégories), pour les listes déroulantes
+func FlattenCategories(tree []Category) []Category {
+	var flat []Category
+	for _, category := range tree {
+		flat = append(flat, category)
+		flat = append(flat, FlattenCategories(category.Children)...)
+	}
+	return flat
+}
+
+// Méthodes utilitaires pour InviteCode
+
+// IsActive vérifie qu'un code d'invitation peut encore servir
+func (c *InviteCode) IsActive() bool {
+	if c.RevokedAt != nil || c.Uses >= c.MaxUses {
+		return false
+	}
+	return c.ExpiresAt == nil || c.ExpiresAt.After(time.Now())
+}
+
+// IsExpired vérifie si un code d'invitation a dépassé sa date d'expiration
+func (c *InviteCode) IsExpired() bool {
+	return c.ExpiresAt != nil && !c.ExpiresAt.After(time.Now())
+}
+
 // Méthodes utilitaires pour Post
 func (p *Post) CanBeEditedBy(userID int) bool {
 	return p.UserID == userID || !p.IsLocked
@@ -231,19 +1025,27 @@ func (p *Post) GetStatusBadges() []string {
 	if p.Status == PostStatusArchived {
 		badges = append(badges, "archived")
 	}
+	if p.Status == PostStatusScheduled {
+		badges = append(badges, "scheduled")
+	}
 	return badges
 }
 
 // Vérifie si un post peut être vu par un utilisateur
 func (p *Post) CanBeViewedBy(userID int, userRoleID int) bool {
-	// Les posts archivés ne peuvent être vus que par le propriétaire ou les admins/modérateurs
-	if p.Status == PostStatusArchived {
+	// Les posts archivés ou programmés ne peuvent être vus que par le propriétaire ou les admins/modérateurs
+	if p.Status == PostStatusArchived || p.Status == PostStatusScheduled {
 		return p.UserID == userID || userRoleID >= RoleModerator
 	}
 	// Les posts ouverts et fermés sont visibles par tous
 	return true
 }
 
+// ShouldSuggestArchive indique que la date de rendu est dépassée depuis longtemps
+func (p *Post) ShouldSuggestArchive(now time.Time) bool {
+	return p.DueAt != nil && p.Status != PostStatusArchived && now.Sub(*p.DueAt) > ArchiveSuggestionDelay
+}
+
 // Vérifie si un post peut recevoir de nouveaux commentaires
 func (p *Post) CanReceiveComments() bool {
 	return p.Status == PostStatusOpen && !p.IsLocked
@@ -259,17 +1061,46 @@ func (p *Post) CanChangeStatusBy(userID int, userRoleID int) bool {
 	return userRoleID >= RoleModerator
 }
 
+// AnonymousName est affiché à la place de l'auteur d'un contenu publié anonymement
+const AnonymousName = "Anonyme"
+
+// HideAnonymousAuthor masque l'auteur d'un post anonyme, sauf pour l'auteur lui-même
+// (viewerID). Les modérateurs passent par la levée d'anonymat, journalisée.
+func (p *Post) HideAnonymousAuthor(viewerID int) {
+	if !p.IsAnonymous || (viewerID > 0 && p.UserID == viewerID) {
+		return
+	}
+	p.UserID, p.Username, p.UserRole, p.UserBanned, p.UserAvatarURL, p.UserReputation = 0, AnonymousName, "", false, "", 0
+}
+
 // Méthodes utilitaires pour Comment
+
+// HideAnonymousAuthor masque l'auteur d'une réponse anonyme, sauf pour l'auteur lui-même (viewerID)
+func (c *Comment) HideAnonymousAuthor(viewerID int) {
+	if !c.IsAnonymous || (viewerID > 0 && c.UserID == viewerID) {
+		return
+	}
+	c.UserID, c.Username, c.UserRole, c.UserBanned, c.UserAvatarURL, c.UserReputation = 0, AnonymousName, "", false, "", 0
+}
+
 func (c *Comment) CanBeMarkedAsSolution(userID int, postAuthorID int) bool {
 	return postAuthorID == userID && !c.IsSolution
 }
 
+// CanBeUnmarkedAsSolution vérifie si l'auteur du post peut retirer cette solution
+func (c *Comment) CanBeUnmarkedAsSolution(userID int, postAuthorID int) bool {
+	return postAuthorID == userID && c.IsSolution
+}
+
 // Structures pour les requêtes
 type CreatePostRequest struct {
 	Title      string `json:"title" form:"title" validate:"required,min=5,max=255"`
 	Content    string `json:"content" form:"content" validate:"required,min=20"`
 	CategoryID int    `json:"category_id" form:"category_id" validate:"required,min=1"`
 	Tags       string `json:"tags" form:"tags"`
+	Level      string `json:"level" form:"level"`           // Code de SchoolLevels, facultatif
+	DueAt      string `json:"due_at" form:"due_at"`         // Date de rendu (AAAA-MM-JJTHH:MM), facultative
+	PublishAt  string `json:"publish_at" form:"publish_at"` // Publication programmée (même format), facultative
 }
 
 type CreateCommentRequest struct {
@@ -301,22 +1132,47 @@ type HomePageData struct {
 	RecentPosts []Post     `json:"recent_posts"`
 	User        *User      `json:"user"`
 	Title       string     `json:"title"`
+
+	BountyPosts []Post `json:"bounty_posts,omitempty"` // Questions avec une prime en cours
 }
 
 type CategoryPageData struct {
-	Category Category `json:"category"`
-	Posts    []Post   `json:"posts"`
-	User     *User    `json:"user"`
-	Title    string   `json:"title"`
+	Category      Category            `json:"category"`
+	Breadcrumbs   []Category          `json:"breadcrumbs"` // Catégories parentes, de la racine au parent direct
+	Subcategories []Category          `json:"subcategories"`
+	Posts         []Post              `json:"posts"`
+	User          *User               `json:"user"`
+	Title         string              `json:"title"`
+	Permissions   CategoryPermissions `json:"permissions"`
+
+	BountyPosts  []Post     `json:"bounty_posts,omitempty"` // Questions avec une prime en cours
+	Filter       PostFilter `json:"filter"`
+	IsSubscribed bool       `json:"is_subscribed"` // L'utilisateur connecté suit la catégorie
 }
 
 type PostPageData struct {
-	Post           Post         `json:"post"`
-	Comments       []Comment    `json:"comments"`
-	User           *User        `json:"user"`
-	Title          string       `json:"title"`
-	CurrentSort    string       `json:"current_sort"`
-	AvailableSorts []SortOption `json:"available_sorts"`
+	Post           Post                `json:"post"`
+	Breadcrumbs    []Category          `json:"breadcrumbs"` // Catégories parentes de la catégorie du post
+	MoveCategories []Category          `json:"move_categories,omitempty"`
+	Permissions    CategoryPermissions `json:"permissions"`
+	MultiSolution  bool                `json:"multi_solution"`  // La catégorie accepte plusieurs solutions
+	AllowAnonymous bool                `json:"allow_anonymous"` // La catégorie accepte les réponses anonymes
+	Comments       []Comment           `json:"comments"`
+	User           *User               `json:"user"`
+	Title          string              `json:"title"`
+	CurrentSort    string              `json:"current_sort"`
+	AvailableSorts []SortOption        `json:"available_sorts"`
+
+	BountyRules   BountyRules `json:"-"`                        // Limites affichées dans le formulaire de prime
+	AwardedBounty *Bounty     `json:"awarded_bounty,omitempty"` // Dernière prime attribuée
+
+	SuggestArchive bool `json:"suggest_archive"` // Date de rendu dépassée depuis longtemps
+	IsSubscribed   bool `json:"is_subscribed"`   // L'utilisateur connecté suit la question
+
+	CommentDrafts []Draft `json:"comment_drafts,omitempty"` // Brouillons de réponse de l'utilisateur
+
+	IsBookmarked       bool         `json:"is_bookmarked"`                 // Question enregistrée dans les favoris
+	BookmarkedComments map[int]bool `json:"bookmarked_comments,omitempty"` // Réponses enregistrées dans les favoris
 }
 
 type SortOption struct {
@@ -325,12 +1181,15 @@ type SortOption struct {
 }
 
 type AdminPageData struct {
-	Users      []User          `json:"users"`
-	Categories []Category      `json:"categories"`
-	Logs       []ModerationLog `json:"logs"`
-	Stats      AdminStats      `json:"stats"`
-	User       *User           `json:"user"`
-	Title      string          `json:"title"`
+	Users        []User          `json:"users"`
+	Categories   []Category      `json:"categories"`
+	Logs         []ModerationLog `json:"logs"`
+	Stats        AdminStats      `json:"stats"`
+	OAuthClients []OAuthClient   `json:"oauth_clients"`
+	User         *User           `json:"user"`
+	Title        string          `json:"title"`
+
+	ArchiveSuggestions []Post `json:"archive_suggestions"` // Questions dont la date de rendu est dépassée depuis longtemps
 }
 
 type AdminStats struct {
@@ -350,6 +1209,7 @@ type Image struct {
 	Height       int       `json:"height" db:"height"`
 	PostID       *int      `json:"post_id" db:"post_id"`
 	CommentID    *int      `json:"comment_id" db:"comment_id"`
+	DraftID      *int      `json:"draft_id,omitempty" db:"draft_id"` // Image en attente, rattachée à un brouillon
 	UserID       int       `json:"user_id" db:"user_id"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	URL          string    `json:"url"` // URL calculée côté serveur
@@ -374,12 +1234,145 @@ type ProfilePageData struct {
 	RecentActivity []UserActivity `json:"recent_activity"`
 	User           *User          `json:"user"` // Utilisateur connecté
 	Title          string         `json:"title"`
+
+	ReputationEvents []ReputationEvent `json:"reputation_events,omitempty"` // Historique, sur son propre profil
+	Badges           []UserBadge       `json:"badges,omitempty"`
+	CanMessage       bool              `json:"can_message"` // L'utilisateur connecté peut lui écrire en privé
 }
 
 // SettingsPageData représente les données pour la page de paramètres
 type SettingsPageData struct {
+	User            *User          `json:"user"`
+	Title           string         `json:"title"`
+	APITokens       []APIToken     `json:"api_tokens"`
+	AvailableScopes []string       `json:"available_scopes"`
+	Subscriptions   []Subscription `json:"subscriptions"`
+	MailEnabled     bool           `json:"mail_enabled"` // Envoi d'emails configuré (résumé des abonnements)
+	BlockedUsers    []BlockedUser  `json:"blocked_users"`
+}
+
+// LoginPageData représente les données pour la page de connexion
+type LoginPageData struct {
+	Next string `json:"next"` // Chemin local où revenir après connexion
+}
+
+// RegisterPageData représente les données pour la page d'inscription
+type RegisterPageData struct {
+	Mode       string `json:"mode"`        // open, invite-only ou closed
+	InviteCode string `json:"invite_code"` // Pré-rempli depuis un lien d'invitation
+}
+
+// InvitesPageData représente les données de la page de gestion des invitations
+type InvitesPageData struct {
+	User             *User        `json:"user"`
+	Title            string       `json:"title"`
+	Invites          []InviteCode `json:"invites"`
+	ClassGroups      []ClassGroup `json:"class_groups"`
+	AssignableRoles  []Role       `json:"assignable_roles"`
+	RegistrationMode string       `json:"registration_mode"`
+}
+
+// ReviewPageData représente les données de la file des questions à relire par un professeur
+type ReviewPageData struct {
 	User  *User  `json:"user"`
 	Title string `json:"title"`
+	Posts []Post `json:"posts"`
+}
+
+// BadgesPageData représente les données de la page des badges
+type BadgesPageData struct {
+	User   *User               `json:"user"`
+	Title  string              `json:"title"`
+	Badges []BadgeCatalogEntry `json:"badges"`
+}
+
+// LeaderboardPageData représente les données de la page des classements
+type LeaderboardPageData struct {
+	User       *User              `json:"user"`
+	Title      string             `json:"title"`
+	Metric     string             `json:"metric"`
+	Period     string             `json:"period"`
+	CategoryID int                `json:"category_id"`
+	Categories []Category         `json:"categories"`
+	Entries    []LeaderboardEntry `json:"entries"`
+}
+
+// NotificationsPageData représente les données de la page des notifications
+type NotificationsPageData struct {
+	User          *User          `json:"user"`
+	Title         string         `json:"title"`
+	Notifications []Notification `json:"notifications"`
+}
+
+// DraftsPageData représente les données de la page des brouillons
+type DraftsPageData struct {
+	User           *User   `json:"user"`
+	Title          string  `json:"title"`
+	Drafts         []Draft `json:"drafts"`
+	ScheduledPosts []Post  `json:"scheduled_posts"` // Posts programmés, pas encore publiés
+}
+
+// BookmarksPageData représente les données de la page des favoris
+type BookmarksPageData struct {
+	User        *User                `json:"user"`
+	Title       string               `json:"title"`
+	Bookmarks   []Bookmark           `json:"bookmarks"`
+	Collections []BookmarkCollection `json:"collections"`
+	Categories  []Category           `json:"categories"` // Catégories proposées dans les filtres
+	Filter      BookmarkFilter       `json:"filter"`
+}
+
+// RevisionSheetPageData représente les données de la fiche de révision imprimable d'une collection
+type RevisionSheetPageData struct {
+	User        *User              `json:"user"`
+	Title       string             `json:"title"`
+	Collection  BookmarkCollection `json:"collection"`
+	Bookmarks   []Bookmark         `json:"bookmarks"`
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
+// MessagesPageData représente les données de la messagerie privée (liste des conversations)
+type MessagesPageData struct {
+	User          *User          `json:"user"`
+	Title         string         `json:"title"`
+	Conversations []Conversation `json:"conversations"`
+	Recipient     string         `json:"recipient,omitempty"` // Destinataire pré-rempli (?to=)
+}
+
+// ConversationPageData représente les données d'une conversation privée
+type ConversationPageData struct {
+	User             *User                `json:"user"`
+	Title            string               `json:"title"`
+	Conversation     Conversation         `json:"conversation"`
+	Messages         []PrivateMessage     `json:"messages"`
+	Participants     []User               `json:"participants"`
+	HasBlocked       bool                 `json:"has_blocked"`       // L'utilisateur a bloqué son interlocuteur
+	ReplyRestriction string               `json:"reply_restriction"` // Raison pour laquelle il ne peut pas répondre
+	ModeratorView    bool                 `json:"moderator_view"`    // Consultation d'une conversation signalée
+	Reports          []ConversationReport `json:"reports,omitempty"` // Signalements, en consultation modérateur
+}
+
+// MessageReportsPageData représente les données de la page des conversations signalées
+type MessageReportsPageData struct {
+	User    *User                `json:"user"`
+	Title   string               `json:"title"`
+	Reports []ConversationReport `json:"reports"`
+}
+
+// ClassesPageData représente les données de la page de gestion des classes
+type ClassesPageData struct {
+	User        *User        `json:"user"`
+	Title       string       `json:"title"`
+	ClassGroups []ClassGroup `json:"class_groups"`
+}
+
+// OAuthConsentPageData représente les données de l'écran de consentement OAuth
+type OAuthConsentPageData struct {
+	User   *User             `json:"user"`
+	Title  string            `json:"title"`
+	Client OAuthClient       `json:"client"`
+	Scopes []string          `json:"scopes"`
+	Params map[string]string `json:"params"` // Paramètres de la demande renvoyés avec la décision
 }
 
 // UpdateProfileRequest représente une demande de mise à jour de profil
@@ -0,0 +1,50 @@
+// ===========================================
+// FAVORIS : QUESTIONS ET RÉPONSES ENREGISTRÉES
+// ===========================================
+
+// setBookmarkState met à jour un bouton d'enregistrement (icône seule ou avec libellé)
+function setBookmarkState(button, bookmarked) {
+    button.dataset.bookmarked = bookmarked ? 'true' : 'false';
+    const icon = `<i class="${bookmarked ? 'fas' : 'far'} fa-bookmark"></i>`;
+    if (button.dataset.label) {
+        button.innerHTML = icon + (bookmarked ? ' Enregistré' : ' Enregistrer');
+    } else {
+        button.innerHTML = icon;
+        button.title = bookmarked ? 'Retirer des favoris' : 'Enregistrer dans les favoris';
+    }
+}
+
+// Délégation : couvre tous les boutons .bookmark-toggle de la page
+document.addEventListener('click', (e) => {
+    const button = e.target.closest('.bookmark-toggle');
+    if (!button) return;
+
+    const bookmarked = button.dataset.bookmarked === 'true';
+    const params = new URLSearchParams({ action: bookmarked ? 'remove' : 'add' });
+    if (button.dataset.postId) params.append('post_id', button.dataset.postId);
+    if (button.dataset.commentId) params.append('comment_id', button.dataset.commentId);
+
+    button.disabled = true;
+    fetch('/bookmarks/toggle', { method: 'POST', body: params })
+        .then(response => response.json())
+        .then(data => {
+            if (data.status !== 'success') {
+                throw new Error(data.error || 'Erreur lors de la mise à jour des favoris');
+            }
+            // Sur la page des favoris, un retrait supprime la ligne
+            const row = button.closest('.bookmark-item');
+            if (row && !data.bookmarked) {
+                row.remove();
+            } else {
+                setBookmarkState(button, data.bookmarked);
+            }
+            if (window.showSuccess) showSuccess(data.message);
+        })
+        .catch(error => {
+            if (window.showError) showError(error.message);
+            else alert(error.message);
+        })
+        .finally(() => {
+            button.disabled = false;
+        });
+});
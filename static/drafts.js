@@ -0,0 +1,305 @@
+// ===========================================
+// BROUILLONS : ENREGISTREMENT AUTOMATIQUE
+// ===========================================
+
+// Délai d'inactivité avant l'enregistrement du brouillon (ms)
+const DRAFT_SAVE_DELAY = 2000;
+
+// Champs enregistrés dans un brouillon
+const DRAFT_FIELDS = ['title', 'content', 'category_id', 'tags', 'level', 'due_at', 'publish_at'];
+
+class DraftAutosave {
+    constructor(form, options = {}) {
+        this.form = form;
+        this.kind = options.kind || 'post';
+        this.postId = options.postId || '';
+        this.parentId = options.parentId || '';
+        this.maxImages = options.maxImages || 5;
+        this.updateUrl = options.updateUrl || false;
+        this.saveBeforeSubmit = options.saveBeforeSubmit || false;
+        this.images = options.images || [];
+        this.timer = null;
+        this.saving = Promise.resolve();
+        this.submitting = false;
+
+        // Copie locale, conservée si le serveur ne répond pas (session expirée, hors ligne)
+        this.storageKey = `draft:${this.kind}:${this.postId}:${this.parentId}`;
+
+        this.idInput = form.querySelector('input[name="draft_id"]');
+        if (!this.idInput) {
+            this.idInput = document.createElement('input');
+            this.idInput.type = 'hidden';
+            this.idInput.name = 'draft_id';
+            form.appendChild(this.idInput);
+        }
+
+        const actions = form.querySelector('.form-actions');
+        this.imagesContainer = document.createElement('div');
+        this.imagesContainer.className = 'image-previews draft-images';
+        form.insertBefore(this.imagesContainer, actions);
+        this.status = document.createElement('small');
+        this.status.className = 'draft-status';
+        (actions || form).appendChild(this.status);
+
+        form.addEventListener('input', (e) => {
+            if (e.target.type === 'file') return;
+            this.submitting = false;
+            this.schedule();
+        });
+        form.addEventListener('submit', (e) => {
+            // Formulaire envoyé par le navigateur : enregistrer d'abord les dernières
+            // modifications, pour les retrouver si la publication est refusée
+            if (this.timer && this.saveBeforeSubmit) {
+                e.preventDefault();
+                this.save().then(() => {
+                    this.submitting = true;
+                    this.clearBackup();
+                    form.submit();
+                });
+                return;
+            }
+            // La publication supprime le brouillon : ne plus l'enregistrer
+            this.submitting = true;
+            clearTimeout(this.timer);
+            this.clearBackup();
+        });
+        window.addEventListener('beforeunload', () => {
+            if (!this.submitting && this.timer) {
+                this.backup();
+            }
+        });
+
+        form.draftAutosave = this;
+        this.setDraftId(options.draftId || 0);
+        this.renderImages();
+        this.restoreBackup();
+    }
+
+    setDraftId(id) {
+        const changed = id && id !== this.draftId;
+        this.draftId = id;
+        this.idInput.value = id || '';
+        if (changed && this.updateUrl) {
+            window.history.replaceState({}, document.title, window.location.pathname + '?draft=' + id);
+        }
+    }
+
+    values() {
+        const values = {};
+        DRAFT_FIELDS.forEach(name => {
+            const field = this.form.elements[name];
+            if (field) values[name] = field.value;
+        });
+        return values;
+    }
+
+    isEmpty() {
+        const values = this.values();
+        return !(values.title || '').trim() && !(values.content || '').trim() && this.images.length === 0;
+    }
+
+    setStatus(message) {
+        this.status.textContent = message;
+    }
+
+    schedule() {
+        clearTimeout(this.timer);
+        this.setStatus('Modifications non enregistrées');
+        this.timer = setTimeout(() => this.save(), DRAFT_SAVE_DELAY);
+    }
+
+    // save enregistre le brouillon ; les enregistrements sont effectués l'un après l'autre
+    // pour ne pas créer deux brouillons pour le même formulaire
+    save(force = false) {
+        clearTimeout(this.timer);
+        this.timer = null;
+        this.saving = this.saving.then(() => this.doSave(force));
+        return this.saving;
+    }
+
+    async doSave(force) {
+        if (this.submitting || (!force && !this.draftId && this.isEmpty())) {
+            return;
+        }
+
+        const body = new URLSearchParams(this.values());
+        body.append('kind', this.kind);
+        body.append('draft_id', this.draftId || '');
+        body.append('post_id', this.postId);
+        body.append('parent_id', this.parentId);
+
+        try {
+            const response = await fetch('/drafts/save', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/x-www-form-urlencoded'},
+                body: body
+            });
+            if (response.status === 401 || response.redirected) {
+                this.backup();
+                this.setStatus('Session expirée : brouillon conservé sur cet appareil');
+                return;
+            }
+            const data = await response.json();
+            if (data.status !== 'success') {
+                this.backup();
+                this.setStatus('Brouillon non enregistré : ' + (data.error || 'erreur inconnue'));
+                return;
+            }
+
+            this.setDraftId(data.draft_id);
+            this.clearBackup();
+            const now = new Date();
+            this.setStatus(`Brouillon enregistré à ${now.toLocaleTimeString('fr-FR', {hour: '2-digit', minute: '2-digit'})}`);
+        } catch (error) {
+            this.backup();
+            this.setStatus('Hors ligne : brouillon conservé sur cet appareil');
+        }
+    }
+
+    // uploadImages ajoute des images au brouillon ; elles restent en attente jusqu'à la publication
+    async uploadImages(files) {
+        const valid = [];
+        for (const file of Array.from(files)) {
+            if (!file.type.startsWith('image/')) {
+                showNotification('Seules les images sont autorisées', 'error');
+                continue;
+            }
+            if (file.size > 10 * 1024 * 1024) { // 10MB
+                showNotification(`L'image ${file.name} est trop volumineuse (max 10MB)`, 'error');
+                continue;
+            }
+            valid.push(file);
+        }
+        if (valid.length === 0) return;
+
+        if (this.images.length + valid.length > this.maxImages) {
+            showNotification(`Maximum ${this.maxImages} images autorisées`, 'error');
+            return;
+        }
+
+        // Le brouillon doit exister pour y rattacher les images
+        await this.save(true);
+        if (!this.draftId) {
+            showNotification('Impossible d\'enregistrer le brouillon pour y ajouter les images', 'error');
+            return;
+        }
+
+        const formData = new FormData();
+        formData.append('draft_id', this.draftId);
+        valid.forEach(file => formData.append('images', file));
+
+        try {
+            const response = await fetch('/drafts/images', {method: 'POST', body: formData});
+            const data = await response.json();
+            if (data.status !== 'success') {
+                showNotification('Erreur: ' + (data.error || 'Erreur inconnue'), 'error');
+                return;
+            }
+            if (data.upload_warnings && data.upload_warnings.length > 0) {
+                showNotification('Certaines images n\'ont pas pu être ajoutées', 'warning');
+            }
+            this.images = data.images || [];
+            this.renderImages();
+        } catch (error) {
+            showNotification('Erreur: ' + error.message, 'error');
+        }
+    }
+
+    async removeImage(imageId) {
+        try {
+            const response = await fetch('/drafts/images/delete', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/x-www-form-urlencoded'},
+                body: `image_id=${imageId}`
+            });
+            const data = await response.json();
+            if (data.status !== 'success') {
+                showNotification('Erreur: ' + (data.error || 'Erreur inconnue'), 'error');
+                return;
+            }
+            this.images = this.images.filter(image => image.id !== imageId);
+            this.renderImages();
+        } catch (error) {
+            showNotification('Erreur: ' + error.message, 'error');
+        }
+    }
+
+    renderImages() {
+        this.imagesContainer.innerHTML = '';
+        this.imagesContainer.style.display = this.images.length > 0 ? '' : 'none';
+
+        this.images.forEach(image => {
+            const preview = document.createElement('div');
+            preview.className = 'image-preview';
+
+            const img = document.createElement('img');
+            img.src = image.url;
+            img.alt = image.original_name;
+            const info = document.createElement('div');
+            info.className = 'preview-info';
+            const name = document.createElement('span');
+            name.className = 'file-name';
+            name.textContent = image.original_name;
+            info.appendChild(name);
+
+            const remove = document.createElement('button');
+            remove.type = 'button';
+            remove.className = 'remove-image';
+            remove.title = 'Retirer l\'image';
+            remove.innerHTML = '<i class="fas fa-times"></i>';
+            remove.addEventListener('click', () => this.removeImage(image.id));
+
+            preview.append(img, info, remove);
+            this.imagesContainer.appendChild(preview);
+        });
+    }
+
+    backup() {
+        try {
+            localStorage.setItem(this.storageKey, JSON.stringify(this.values()));
+        } catch (error) {
+            // Stockage local indisponible : seul le brouillon serveur est conservé
+        }
+    }
+
+    clearBackup() {
+        try {
+            localStorage.removeItem(this.storageKey);
+        } catch (error) {
+            // Stockage local indisponible
+        }
+    }
+
+    // restoreBackup restaure la copie locale dans un formulaire vide (brouillon jamais
+    // parvenu au serveur), puis l'enregistre
+    restoreBackup() {
+        let saved = null;
+        try {
+            saved = JSON.parse(localStorage.getItem(this.storageKey));
+        } catch (error) {
+            return;
+        }
+        if (!saved || !this.isEmpty()) return;
+
+        Object.entries(saved).forEach(([name, value]) => {
+            const field = this.form.elements[name];
+            if (field && value) field.value = value;
+        });
+        if (!this.isEmpty()) {
+            showNotification('Votre saisie précédente a été restaurée', 'info');
+            this.schedule();
+        }
+    }
+}
+
+// Restaure les champs d'un brouillon enregistré dans un formulaire
+function fillDraftForm(form, draft) {
+    DRAFT_FIELDS.forEach(name => {
+        const field = form.elements[name];
+        if (!field || !draft[name]) return;
+        field.value = name === 'category_id' ? String(draft[name]) : draft[name];
+    });
+}
+
+window.DraftAutosave = DraftAutosave;
+window.fillDraftForm = fillDraftForm;
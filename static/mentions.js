@@ -0,0 +1,164 @@
+// ===========================================
+// MENTIONS @PSEUDO : AUTOCOMPLÉTION
+// ===========================================
+
+// Délai de saisie avant de chercher les pseudos (ms)
+const MENTION_SEARCH_DELAY = 200;
+
+// Mention en cours de saisie juste avant le curseur
+const MENTION_BEFORE_CARET = /(^|[^A-Za-z0-9_@.-])@([A-Za-z0-9_-]{1,50})$/;
+
+class MentionAutocomplete {
+    constructor() {
+        this.textarea = null;
+        this.query = '';
+        this.items = [];
+        this.active = 0;
+        this.timer = null;
+
+        this.list = document.createElement('div');
+        this.list.className = 'mention-suggestions';
+        this.list.hidden = true;
+        document.body.appendChild(this.list);
+
+        // Délégation : couvre aussi les formulaires de réponse affichés plus tard
+        document.addEventListener('input', (e) => {
+            if (e.target.matches('textarea[data-mentions]')) this.onInput(e.target);
+        });
+        document.addEventListener('keydown', (e) => this.onKeydown(e), true);
+        document.addEventListener('click', (e) => {
+            if (!this.list.contains(e.target)) this.hide();
+        });
+        window.addEventListener('resize', () => this.hide());
+    }
+
+    onInput(textarea) {
+        clearTimeout(this.timer);
+        const before = textarea.value.slice(0, textarea.selectionStart);
+        const match = before.match(MENTION_BEFORE_CARET);
+        if (!match) {
+            this.hide();
+            return;
+        }
+
+        this.textarea = textarea;
+        this.query = match[2];
+        this.timer = setTimeout(() => this.search(this.query), MENTION_SEARCH_DELAY);
+    }
+
+    search(query) {
+        fetch(`/users/autocomplete?q=${encodeURIComponent(query)}`)
+            .then(response => response.ok ? response.json() : [])
+            .then(users => {
+                // Résultat d'une saisie dépassée
+                if (query !== this.query) return;
+                this.show(users || []);
+            })
+            .catch(() => this.hide());
+    }
+
+    show(users) {
+        this.items = users;
+        this.active = 0;
+        this.list.innerHTML = '';
+        if (users.length === 0) {
+            this.hide();
+            return;
+        }
+
+        users.forEach((user, index) => {
+            const item = document.createElement('div');
+            item.className = 'mention-suggestion';
+
+            const avatar = document.createElement('span');
+            avatar.className = 'mention-avatar';
+            if (user.avatar_url) {
+                const img = document.createElement('img');
+                img.src = user.avatar_url;
+                img.alt = '';
+                avatar.appendChild(img);
+            } else {
+                avatar.textContent = user.username.charAt(0).toUpperCase();
+            }
+
+            const name = document.createElement('span');
+            name.className = 'mention-username';
+            name.textContent = '@' + user.username;
+
+            const role = document.createElement('small');
+            role.className = 'mention-role';
+            role.textContent = user.role_name;
+
+            item.append(avatar, name, role);
+            item.addEventListener('mousedown', (e) => {
+                e.preventDefault();
+                this.select(index);
+            });
+            this.list.appendChild(item);
+        });
+
+        const rect = this.textarea.getBoundingClientRect();
+        this.list.style.top = `${rect.bottom + window.scrollY + 4}px`;
+        this.list.style.left = `${rect.left + window.scrollX}px`;
+        this.list.style.minWidth = `${Math.min(rect.width, 280)}px`;
+        this.list.hidden = false;
+        this.highlight();
+    }
+
+    hide() {
+        this.list.hidden = true;
+        this.items = [];
+    }
+
+    highlight() {
+        Array.from(this.list.children).forEach((item, index) => {
+            item.classList.toggle('active', index === this.active);
+        });
+    }
+
+    onKeydown(e) {
+        if (this.list.hidden || e.target !== this.textarea) return;
+
+        switch (e.key) {
+            case 'ArrowDown':
+                this.active = (this.active + 1) % this.items.length;
+                break;
+            case 'ArrowUp':
+                this.active = (this.active - 1 + this.items.length) % this.items.length;
+                break;
+            case 'Enter':
+            case 'Tab':
+                this.select(this.active);
+                break;
+            case 'Escape':
+                this.hide();
+                break;
+            default:
+                return;
+        }
+        e.preventDefault();
+        this.highlight();
+    }
+
+    // select remplace le pseudo en cours de saisie par le pseudo choisi
+    select(index) {
+        const user = this.items[index];
+        if (!user) return;
+
+        const textarea = this.textarea;
+        const caret = textarea.selectionStart;
+        const start = caret - this.query.length;
+        const inserted = user.username + ' ';
+        textarea.value = textarea.value.slice(0, start) + inserted + textarea.value.slice(caret);
+        textarea.selectionStart = textarea.selectionEnd = start + inserted.length;
+        textarea.focus();
+        this.hide();
+
+        // Prévenir les autres écouteurs (brouillons) de la modification
+        textarea.dispatchEvent(new Event('input', { bubbles: true }));
+    }
+}
+
+document.addEventListener('DOMContentLoaded', () => {
+    window.mentionAutocomplete = new MentionAutocomplete();
+});
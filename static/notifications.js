@@ -64,10 +64,11 @@ class NotificationSystem {
                 <div class="notification-title">${titles[type]}</div>
                 <div class="notification-message">${message}</div>
             </div>
-            <button class="notification-close" onclick="notifications.remove(this.parentElement)">
+            <button class="notification-close">
                 <i class="fas fa-times"></i>
             </button>
         `;
+        notification.querySelector('.notification-close').addEventListener('click', () => this.remove(notification));
 
         return notification;
     }
@@ -203,11 +203,16 @@ function showRolePromotionModal(userId, username, currentRole) {
                 ${roleOptions}
             </select>
             <div class="modal-buttons">
-                <button onclick="promoteUser(${userId}, document.getElementById('newRole').value); this.closest('.modal-overlay').remove()">Confirmer</button>
-                <button onclick="this.closest('.modal-overlay').remove()">Annuler</button>
+                <button class="modal-confirm">Confirmer</button>
+                <button class="modal-cancel">Annuler</button>
             </div>
         </div>
     `;
+    modal.querySelector('.modal-confirm').addEventListener('click', () => {
+        promoteUser(userId, modal.querySelector('#newRole').value);
+        modal.remove();
+    });
+    modal.querySelector('.modal-cancel').addEventListener('click', () => modal.remove());
     
     document.body.appendChild(modal);
 }
@@ -313,9 +318,10 @@ function showNotification(message, type = 'info') {
         <div class="notification-content">
             <i class="fas fa-${getNotificationIcon(type)}"></i>
             <span>${message}</span>
-            <button class="notification-close" onclick="this.parentElement.parentElement.remove()">×</button>
+            <button class="notification-close">×</button>
         </div>
     `;
+    notification.querySelector('.notification-close').addEventListener('click', () => notification.remove());
     
     container.appendChild(notification);
     
@@ -0,0 +1,49 @@
+// ===========================================
+// ABONNEMENTS : SUIVRE QUESTIONS, CATÉGORIES ET TAGS
+// ===========================================
+
+// setSubscriptionState met à jour le libellé d'un bouton Suivre / Ne plus suivre
+function setSubscriptionState(button, subscribed) {
+    button.dataset.subscribed = subscribed ? 'true' : 'false';
+    button.innerHTML = subscribed
+        ? '<i class="fas fa-bell-slash"></i> Ne plus suivre'
+        : '<i class="fas fa-bell"></i> Suivre';
+}
+
+// Délégation : couvre tous les boutons .subscription-toggle de la page
+document.addEventListener('click', (e) => {
+    const button = e.target.closest('.subscription-toggle');
+    if (!button) return;
+
+    const subscribed = button.dataset.subscribed === 'true';
+    const params = new URLSearchParams({
+        target_type: button.dataset.targetType,
+        action: subscribed ? 'unsubscribe' : 'subscribe'
+    });
+    if (button.dataset.targetId) params.append('target_id', button.dataset.targetId);
+    if (button.dataset.tag) params.append('tag', button.dataset.tag);
+
+    button.disabled = true;
+    fetch('/subscriptions', { method: 'POST', body: params })
+        .then(response => response.json())
+        .then(data => {
+            if (data.status !== 'success') {
+                throw new Error(data.error || 'Erreur lors de la mise à jour de l\'abonnement');
+            }
+            // Sur la page Paramètres, un désabonnement retire la ligne
+            const row = button.closest('.subscription-item');
+            if (row && !data.subscribed) {
+                row.remove();
+            } else {
+                setSubscriptionState(button, data.subscribed);
+            }
+            if (window.showSuccess) showSuccess(data.message);
+        })
+        .catch(error => {
+            if (window.showError) showError(error.message);
+            else alert(error.message);
+        })
+        .finally(() => {
+            button.disabled = false;
+        });
+});
@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"html/template"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
@@ -67,14 +71,57 @@ func DeleteCookie(w http.ResponseWriter, name string) {
 	http.SetCookie(w, cookie)
 }
 
+// CSPNonceWriter est implémenté par les ResponseWriter qui portent un nonce CSP
+// (voir middleware.SecurityHeaders)
+type CSPNonceWriter interface {
+	CSPNonce() string
+}
+
 // RenderTemplate rend un template avec gestion d'erreur
 func RenderTemplate(w http.ResponseWriter, tmpl *template.Template, name string, data interface{}) {
-	if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+	if err := ExecuteTemplate(w, tmpl, name, data); err != nil {
 		http.Error(w, "Erreur de rendu du template", http.StatusInternalServerError)
 	}
 }
 
-// RenderSimplePage rend une page HTML simple en cas d'erreur de template
+// cspNonceMarker est le texte rendu par la fonction de template {{cspNonce}},
+// remplacé par le nonce de la requête dans ExecuteTemplate. Il est tiré au
+// démarrage pour qu'un contenu utilisateur ne puisse pas le reproduire.
+var cspNonceMarker = func() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("génération du marqueur de nonce CSP impossible: " + err.Error())
+	}
+	return "cspnonce" + hex.EncodeToString(b)
+}()
+
+// CSPNonceMarker est la fonction de template {{cspNonce}}
+func CSPNonceMarker() string {
+	return cspNonceMarker
+}
+
+// ExecuteTemplate exécute un template et remplace le marqueur de {{cspNonce}}
+// par le nonce CSP de la requête
+func ExecuteTemplate(w http.ResponseWriter, tmpl *template.Template, name string, data interface{}) error {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	_, err := w.Write(withRequestNonce(w, buf.Bytes()))
+	return err
+}
+
+// withRequestNonce remplace le marqueur de {{cspNonce}} par le nonce CSP de la requête
+func withRequestNonce(w http.ResponseWriter, page []byte) []byte {
+	nonce := ""
+	if nw, ok := w.(CSPNonceWriter); ok {
+		nonce = nw.CSPNonce()
+	}
+	return bytes.ReplaceAll(page, []byte(cspNonceMarker), []byte(nonce))
+}
+
+// RenderSimplePage rend une page HTML simple en cas d'erreur de template.
+// Les scripts inline du contenu portent le nonce via CSPNonceMarker().
 func RenderSimplePage(w http.ResponseWriter, title, content string) {
 	html := `<!DOCTYPE html>
 <html lang="fr">
@@ -101,7 +148,7 @@ func RenderSimplePage(w http.ResponseWriter, title, content string) {
 </html>`
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(html))
+	w.Write(withRequestNonce(w, []byte(html)))
 }
 
 // SanitizeInput nettoie une entrée utilisateur basique
@@ -239,9 +286,11 @@ func GetClientIP(r *http.Request) string {
 }
 
 // FormatContent convertit les retours à la ligne en balises HTML <br>
+// et les mentions @pseudo en liens vers les profils
 func FormatContent(content string) template.HTML {
 	// Échapper le HTML pour éviter les injections XSS
 	content = template.HTMLEscapeString(content)
+	content = linkMentions(content)
 
 	// Convertir les retours à la ligne en <br>
 	content = strings.ReplaceAll(content, "\n", "<br>")
@@ -268,7 +317,7 @@ func RenderErrorPage(w http.ResponseWriter, tmpl *template.Template, code int, t
 	}
 
 	if tmpl != nil {
-		if err := tmpl.ExecuteTemplate(w, "error.html", data); err != nil {
+		if err := ExecuteTemplate(w, tmpl, "error.html", data); err != nil {
 			// Fallback en cas d'erreur de template
 			RenderSimpleErrorPage(w, code, title, message)
 		}
@@ -305,14 +354,20 @@ func RenderSimpleErrorPage(w http.ResponseWriter, code int, title, message strin
         <p class="error-message">` + message + `</p>
         <div>
             <a href="/" class="btn"><i class="fas fa-home"></i> Retour à l'accueil</a>
-            <a href="javascript:history.back()" class="btn btn-secondary"><i class="fas fa-arrow-left"></i> Page précédente</a>
+            <a href="#" id="history-back" class="btn btn-secondary"><i class="fas fa-arrow-left"></i> Page précédente</a>
         </div>
     </div>
+    <script nonce="` + cspNonceMarker + `">
+        document.getElementById('history-back').addEventListener('click', function(e) {
+            e.preventDefault();
+            history.back();
+        });
+    </script>
 </body>
 </html>`
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(html))
+	w.Write(withRequestNonce(w, []byte(html)))
 }
 
 // RenderJSONError rend une erreur au format JSON
@@ -322,6 +377,22 @@ func RenderJSONError(w http.ResponseWriter, code int, message string) {
 	w.Write([]byte(`{"error": "` + message + `", "status": "error"}`))
 }
 
+// SetUploadHeaders durcit la réponse d'un fichier uploadé : type de contenu
+// imposé, pas de détection MIME par le navigateur et affichage en ligne
+// uniquement pour les images autorisées.
+func SetUploadHeaders(w http.ResponseWriter, filename, contentType string) {
+	disposition := "attachment"
+	if _, ok := AllowedMimeTypes[contentType]; ok {
+		disposition = "inline"
+	} else {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filename}))
+}
+
 // IntPtr retourne un pointeur vers un int
 func IntPtr(i int) *int {
 	return &i
@@ -16,10 +16,11 @@ import (
 
 // Configuration pour les images
 const (
-	MaxFileSize    = 10 << 20 // 10MB
-	MaxImagesCount = 5        // Maximum 5 images par post
-	UploadPath     = "./uploads/posts"
-	ThumbnailPath  = "./uploads/thumbnails"
+	MaxFileSize          = 10 << 20 // 10MB
+	MaxImagesCount       = 5        // Maximum 5 images par post
+	MaxCommentImageCount = 3        // Maximum 3 images par commentaire
+	UploadPath           = "./uploads/posts"
+	ThumbnailPath        = "./uploads/thumbnails"
 )
 
 // Types MIME autorisés
@@ -197,6 +198,22 @@ func CreateThumbnail(srcPath string, thumbPath string, maxWidth, maxHeight int)
 	return err
 }
 
+// ContentTypeFromExtension retourne le type MIME autorisé correspondant à
+// l'extension d'un fichier, ou une chaîne vide si l'extension n'est pas autorisée
+func ContentTypeFromExtension(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	}
+	return ""
+}
+
 // GetImageURL retourne l'URL publique d'une image
 func GetImageURL(filename string) string {
 	return "/uploads/posts/" + filename
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mailer envoie des emails en texte brut par SMTP
+type Mailer struct {
+	Host     string
+	Port     string
+	Username string // Vide = pas d'authentification
+	Password string
+	From     string
+}
+
+// Send envoie un email en texte brut (UTF-8) à un destinataire
+func (m *Mailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{to}, []byte(msg.String()))
+}
+
+// UnsubscribeToken signe un lien de désabonnement envoyé par email, utilisable sans
+// connexion : il n'autorise que la suppression de cet abonnement.
+func UnsubscribeToken(secret []byte, userID int, targetType string, targetID int) string {
+	payload := strconv.Itoa(userID) + ":" + targetType + ":" + strconv.Itoa(targetID)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ParseUnsubscribeToken vérifie un lien de désabonnement et retourne l'abonnement visé
+func ParseUnsubscribeToken(secret []byte, token string) (userID int, targetType string, targetID int, ok bool) {
+	encodedPayload, encodedMAC, found := strings.Cut(token, ".")
+	if !found {
+		return 0, "", 0, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return 0, "", 0, false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil {
+		return 0, "", 0, false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return 0, "", 0, false
+	}
+
+	parts := strings.Split(string(payload), ":")
+	if len(parts) != 3 {
+		return 0, "", 0, false
+	}
+	userID, err1 := strconv.Atoi(parts[0])
+	targetID, err2 := strconv.Atoi(parts[2])
+	if err1 != nil || err2 != nil {
+		return 0, "", 0, false
+	}
+	return userID, parts[1], targetID, true
+}
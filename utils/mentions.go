@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionRegex reconnaît une mention @pseudo (caractères autorisés par IsValidUsername).
+// Le caractère qui précède ne doit pas faire partie d'un mot, pour ignorer les adresses email.
+var mentionRegex = regexp.MustCompile(`(^|[^A-Za-z0-9_@.-])@([A-Za-z0-9_-]{3,50})`)
+
+// mentionPrefixRegex reconnaît un début de pseudo saisi après @ (autocomplétion)
+var mentionPrefixRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,50}$`)
+
+// IsMentionPrefix vérifie qu'un préfixe ne contient que des caractères autorisés dans un pseudo
+func IsMentionPrefix(prefix string) bool {
+	return mentionPrefixRegex.MatchString(prefix)
+}
+
+// ParseMentions retourne les pseudos mentionnés dans un contenu, sans doublons
+// (comparaison insensible à la casse), dans l'ordre d'apparition
+func ParseMentions(content string) []string {
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, match := range mentionRegex.FindAllStringSubmatch(content, -1) {
+		key := strings.ToLower(match[2])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		usernames = append(usernames, match[2])
+	}
+	return usernames
+}
+
+// linkMentions remplace les mentions d'un contenu déjà échappé par des liens vers les profils
+func linkMentions(escaped string) string {
+	return mentionRegex.ReplaceAllString(escaped, `$1<a href="/profile/$2" class="mention">@$2</a>`)
+}
@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+
+	"aide-devoir-forum/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Préfixes des identifiants et secrets des applications OAuth
+const (
+	OAuthClientIDPrefix     = "adf-client-"
+	OAuthClientSecretPrefix = "adf_secret_"
+)
+
+// OAuthSigningKey regroupe la clé RSA des tokens OAuth et son identifiant publié dans le JWKS
+type OAuthSigningKey struct {
+	Key   *rsa.PrivateKey
+	KeyID string
+}
+
+// LoadOrCreateOAuthSigningKey charge la clé privée RSA (PEM) des tokens OAuth.
+// Si le fichier n'existe pas, une nouvelle clé est générée et enregistrée.
+// Sans chemin, la clé est éphémère : les tokens émis ne survivent pas à un redémarrage.
+func LoadOrCreateOAuthSigningKey(path string) (*OAuthSigningKey, error) {
+	var key *rsa.PrivateKey
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("clé de signature OAuth invalide: PEM attendu")
+		}
+		if parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+			key = parsed
+		} else {
+			parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			rsaKey, ok := parsed.(*rsa.PrivateKey)
+			if !ok {
+				return nil, errors.New("clé de signature OAuth invalide: clé RSA attendue")
+			}
+			key = rsaKey
+		}
+	case path == "" || errors.Is(err, os.ErrNotExist):
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			pemData := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+			if err := os.WriteFile(path, pemData, 0600); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, err
+	}
+
+	// L'identifiant de clé dérive de la clé publique : il change si la clé est remplacée
+	sum := sha256.Sum256(x509.MarshalPKCS1PublicKey(&key.PublicKey))
+	return &OAuthSigningKey{Key: key, KeyID: base64.RawURLEncoding.EncodeToString(sum[:12])}, nil
+}
+
+// JWK retourne la clé publique au format JSON Web Key (RFC 7517)
+func (k *OAuthSigningKey) JWK() map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"use": "sig",
+		"alg": "RS256",
+		"kid": k.KeyID,
+		"n":   base64.RawURLEncoding.EncodeToString(k.Key.PublicKey.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.Key.PublicKey.E)).Bytes()),
+	}
+}
+
+// Sign signe des claims en RS256. tokenType renseigne l'en-tête "typ" (ex: "at+jwt").
+func (k *OAuthSigningKey) Sign(claims jwt.Claims, tokenType string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = k.KeyID
+	if tokenType != "" {
+		token.Header["typ"] = tokenType
+	}
+	return token.SignedString(k.Key)
+}
+
+// ParseAccessToken valide un access token OAuth émis par le forum
+func (k *OAuthSigningKey) ParseAccessToken(tokenString, issuer string) (*models.OAuthAccessClaims, error) {
+	claims := &models.OAuthAccessClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// Un id_token (même clé, sans typ "at+jwt") ne doit pas servir d'access token
+		if typ, _ := token.Header["typ"].(string); typ != "at+jwt" {
+			return nil, errors.New("type de token inattendu")
+		}
+		return &k.Key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithIssuer(issuer), jwt.WithExpirationRequired())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, jwt.ErrSignatureInvalid
+	}
+
+	return claims, nil
+}
+
+// GenerateOAuthClientCredentials génère l'identifiant public et le secret d'une application.
+// Comme pour les tokens d'API, seul le hash du secret est stocké.
+func GenerateOAuthClientCredentials() (clientID, secret, secretHash string, err error) {
+	idBytes := make([]byte, 12)
+	if _, err = rand.Read(idBytes); err != nil {
+		return "", "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err = rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	clientID = OAuthClientIDPrefix + base64.RawURLEncoding.EncodeToString(idBytes)
+	secret = OAuthClientSecretPrefix + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return clientID, secret, HashAPIToken(secret), nil
+}
+
+// GenerateOAuthCode génère un code d'autorisation et son hash
+func GenerateOAuthCode() (code, hash string, err error) {
+	randomBytes := make([]byte, 32)
+	if _, err = rand.Read(randomBytes); err != nil {
+		return "", "", err
+	}
+	code = base64.RawURLEncoding.EncodeToString(randomBytes)
+	return code, HashAPIToken(code), nil
+}
+
+// VerifyPKCE vérifie le code_verifier d'une demande de token (RFC 7636, méthode S256 uniquement)
+func VerifyPKCE(verifier, challenge string) bool {
+	// RFC 7636 §4.1 : 43 à 128 caractères
+	if len(verifier) < 43 || len(verifier) > 128 || challenge == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// SafeRedirectPath n'accepte que des chemins locaux (évite les redirections ouvertes)
+func SafeRedirectPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return ""
+	}
+	return path
+}
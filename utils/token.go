@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// APITokenPrefix préfixe les tokens d'API personnels pour les reconnaître facilement
+const APITokenPrefix = "adf_"
+
+// GenerateAPIToken génère un token d'API. Le token en clair n'est montré qu'une
+// fois à l'utilisateur : seuls son préfixe d'affichage et son hash sont stockés.
+func GenerateAPIToken() (token, displayPrefix, hash string, err error) {
+	randomBytes := make([]byte, 32)
+	if _, err = rand.Read(randomBytes); err != nil {
+		return "", "", "", err
+	}
+
+	token = APITokenPrefix + base64.RawURLEncoding.EncodeToString(randomBytes)
+	displayPrefix = token[:len(APITokenPrefix)+6]
+	return token, displayPrefix, HashAPIToken(token), nil
+}
+
+// HashAPIToken calcule le hash SHA-256 d'un token d'API.
+// Les tokens étant aléatoires et longs, un hash rapide suffit (contrairement aux mots de passe).
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetBearerToken extrait le token de l'en-tête "Authorization: Bearer ..."
+func GetBearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
+
+// inviteCodeAlphabet exclut les caractères ambigus (0/O, 1/I/L) : les codes d'invitation
+// sont souvent recopiés depuis un tableau ou une feuille distribuée en classe
+const inviteCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// GenerateInviteCode génère un code d'invitation lisible au format XXXX-XXXX
+func GenerateInviteCode() (string, error) {
+	randomBytes := make([]byte, 8)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i, value := range randomBytes {
+		if i == 4 {
+			b.WriteByte('-')
+		}
+		b.WriteByte(inviteCodeAlphabet[int(value)%len(inviteCodeAlphabet)])
+	}
+	return b.String(), nil
+}